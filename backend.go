@@ -0,0 +1,64 @@
+package sqlrisk
+
+import (
+	"github.com/sunkaimr/sql-risk/comm"
+)
+
+// BackendType 数据源的数据库类型，不同类型的元数据、事务、磁盘信息的查询方式不同
+type BackendType string
+
+const (
+	MySQLBackend    BackendType = "mysql"
+	TiDBBackend     BackendType = "tidb"
+	PostgresBackend BackendType = "postgres"
+)
+
+// Backend 与数据库类型相关的元数据查询
+type Backend interface {
+	// TableSize 表大小，单位MB
+	TableSize(conn *Connector, database, table string) (int, error)
+	// TableRows 表行数
+	TableRows(conn *Connector, database, table string) (int, error)
+	// Transactions 正在运行的事务
+	Transactions(conn *Connector) ([]TrxResult, error)
+}
+
+func GetBackend(t BackendType) Backend {
+	switch t {
+	case TiDBBackend:
+		return tidbBackend{}
+	default:
+		return mysqlBackend{}
+	}
+}
+
+type mysqlBackend struct{}
+
+func (mysqlBackend) TableSize(conn *Connector, database, table string) (int, error) {
+	return conn.TableSize(database, table)
+}
+
+func (mysqlBackend) TableRows(conn *Connector, database, table string) (int, error) {
+	conn.Database = database
+	return conn.TableRows(table)
+}
+
+func (mysqlBackend) Transactions(conn *Connector) ([]TrxResult, error) {
+	return conn.TableTransaction()
+}
+
+// backendType 数据源未指定数据库类型时根据数据库分支识别，识别失败按MySQL处理
+func (c *SQLRisk) backendType() BackendType {
+	if c.Backend != "" {
+		return c.Backend
+	}
+	if c.Config != nil && c.Config.RiskConfig.Offline {
+		return MySQLBackend
+	}
+
+	info, err := c.CollectServerInfo()
+	if err == nil && info.Flavor == comm.FlavorTiDB {
+		return TiDBBackend
+	}
+	return MySQLBackend
+}
@@ -0,0 +1,302 @@
+package sqlrisk
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sunkaimr/sql-risk/comm"
+	"github.com/sunkaimr/sql-risk/policy"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NoExpiration 缓存永不过期
+const NoExpiration time.Duration = -1
+
+// DefaultVerdictTTL 评估项跨工单缓存的时长，未列出的评估项（影响行数、磁盘、从库延迟等实时值）不跨工单缓存。
+// 表是否存在变化较快，缓存时间较短；操作类型、关键字只取决于SQL文本，永不过期
+var DefaultVerdictTTL = map[string]time.Duration{
+	policy.TabExist.ID:          time.Minute,
+	policy.TabSize.ID:           10 * time.Minute,
+	policy.TabRows.ID:           10 * time.Minute,
+	policy.PrimaryKeyExist.ID:   10 * time.Minute,
+	policy.ForeignKeyExist.ID:   10 * time.Minute,
+	policy.TriggerExist.ID:      10 * time.Minute,
+	policy.IndexExistInWhere.ID: 10 * time.Minute,
+	policy.Operate.ID:           NoExpiration,
+	policy.Action.ID:            NoExpiration,
+	policy.KeyWord.ID:           NoExpiration,
+	policy.KeyWords.ID:          NoExpiration,
+}
+
+// MemoryCache 内存中的LRU缓存，并发安全；nil时不缓存
+type MemoryCache struct {
+	// 最多缓存的条数，为0时不限制
+	Capacity int
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type memoryEntry struct {
+	key   string
+	value any
+	// 为零值时永不过期
+	expireAt time.Time
+}
+
+func NewMemoryCache(capacity int) *MemoryCache {
+	return &MemoryCache{
+		Capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element, 1),
+	}
+}
+
+func (c *MemoryCache) Get(key string) (any, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := e.Value.(*memoryEntry)
+	if !entry.expireAt.IsZero() && time.Now().After(entry.expireAt) {
+		c.ll.Remove(e)
+		delete(c.items, key)
+		return nil, false
+	}
+	c.ll.MoveToFront(e)
+	return entry.value, true
+}
+
+// Set ttl小于等于0时永不过期
+func (c *MemoryCache) Set(key string, value any, ttl time.Duration) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &memoryEntry{key: key, value: value}
+	if ttl > 0 {
+		entry.expireAt = time.Now().Add(ttl)
+	}
+	if e, ok := c.items[key]; ok {
+		e.Value = entry
+		c.ll.MoveToFront(e)
+		return
+	}
+	c.items[key] = c.ll.PushFront(entry)
+	if c.Capacity > 0 && c.ll.Len() > c.Capacity {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*memoryEntry).key)
+	}
+}
+
+// Len 缓存的条数，包含已过期未清理的
+func (c *MemoryCache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// VerdictCacheEntry MySQL中缓存的评估项，值以JSON保存
+type VerdictCacheEntry struct {
+	Key      string     `gorm:"type:varchar(64);primary_key;column:cache_key;comment:缓存键的MD5"`
+	ItemID   string     `gorm:"type:varchar(64);column:item_id;comment:评估项ID"`
+	Value    string     `gorm:"type:text;column:value;comment:评估项的值"`
+	ExpireAt *time.Time `gorm:"index:expire_at_idx;column:expire_at;comment:过期时间，为空时永不过期"`
+}
+
+// MySQLCache 以MySQL保存的评估项缓存，多个实例间共享
+type MySQLCache struct {
+	*gorm.DB
+}
+
+func NewMySQLCache(db *gorm.DB) *MySQLCache {
+	return &MySQLCache{db}
+}
+
+// Init 创建或更新缓存表
+func (c *MySQLCache) Init() error {
+	err := c.AutoMigrate(&VerdictCacheEntry{})
+	if err != nil {
+		return fmt.Errorf("AutoMigrate verdict cache failed, %s", err)
+	}
+	return nil
+}
+
+// Get 查询未过期的评估项，返回值的过期时间，永不过期时为零值
+func (c *MySQLCache) Get(key, id string) (any, time.Time, bool, error) {
+	entry := VerdictCacheEntry{}
+	res := c.Where("cache_key = ? AND (expire_at IS NULL OR expire_at > ?)", comm.Hash(key), time.Now()).Limit(1).Find(&entry)
+	if res.Error != nil {
+		return nil, time.Time{}, false, fmt.Errorf("query verdict cache failed, %s", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return nil, time.Time{}, false, nil
+	}
+
+	var v any
+	err := json.Unmarshal([]byte(entry.Value), &v)
+	if err != nil {
+		return nil, time.Time{}, false, fmt.Errorf("unmarshal verdict cache %s failed, %s", id, err)
+	}
+	expireAt := time.Time{}
+	if entry.ExpireAt != nil {
+		expireAt = *entry.ExpireAt
+	}
+	return restoreItemValue(id, v), expireAt, true, nil
+}
+
+// Set ttl小于等于0时永不过期
+func (c *MySQLCache) Set(key, id string, value any, ttl time.Duration) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("marshal verdict cache %s failed, %s", id, err)
+	}
+	entry := VerdictCacheEntry{Key: comm.Hash(key), ItemID: id, Value: string(b)}
+	if ttl > 0 {
+		expireAt := time.Now().Add(ttl)
+		entry.ExpireAt = &expireAt
+	}
+	err = c.Clauses(clause.OnConflict{UpdateAll: true}).Create(&entry).Error
+	if err != nil {
+		return fmt.Errorf("save verdict cache %s failed, %s", id, err)
+	}
+	return nil
+}
+
+// Cleanup 清理已过期的评估项，返回清理的条数
+func (c *MySQLCache) Cleanup() (int64, error) {
+	res := c.Where("expire_at <= ?", time.Now()).Delete(&VerdictCacheEntry{})
+	if res.Error != nil {
+		return 0, fmt.Errorf("delete expired verdict cache failed, %s", res.Error)
+	}
+	return res.RowsAffected, nil
+}
+
+// VerdictCache 跨工单的评估项缓存，按数据源+SQL指纹+表缓存评估项的值。
+// 先查询内存，内存中没有时查询MySQL并回填内存；MySQL不可用时只使用内存，不影响风险识别
+type VerdictCache struct {
+	Local *MemoryCache
+	// 可选的MySQL缓存，为nil时只缓存在内存中
+	Remote *MySQLCache
+	// 各评估项的缓存时长，为nil时使用DefaultVerdictTTL
+	TTL map[string]time.Duration
+}
+
+// NewVerdictCache capacity为内存中最多缓存的条数，remote为nil时只缓存在内存中
+func NewVerdictCache(capacity int, remote *MySQLCache) *VerdictCache {
+	return &VerdictCache{Local: NewMemoryCache(capacity), Remote: remote}
+}
+
+// VerdictKey 评估项的缓存键，SQL中的表可能省略库名，需要区分SQL执行时所在的库
+func VerdictKey(datasource, database, fingerID, id string, keys []string) string {
+	return strings.Join([]string{datasource, database, fingerID, id, strings.Join(keys, "|")}, "|")
+}
+
+// Cacheable 评估项是否跨工单缓存
+func (c *VerdictCache) Cacheable(id string) bool {
+	return c != nil && c.ttl(id) != 0
+}
+
+func (c *VerdictCache) Get(key, id string) (any, bool) {
+	if !c.Cacheable(id) {
+		return nil, false
+	}
+	if v, ok := c.Local.Get(key); ok {
+		return v, true
+	}
+	if c.Remote == nil {
+		return nil, false
+	}
+
+	v, expireAt, ok, err := c.Remote.Get(key, id)
+	if err != nil || !ok {
+		return nil, false
+	}
+	ttl := NoExpiration
+	if !expireAt.IsZero() {
+		ttl = time.Until(expireAt)
+		if ttl <= 0 {
+			return v, true
+		}
+	}
+	c.Local.Set(key, v, ttl)
+	return v, true
+}
+
+func (c *VerdictCache) Set(key, id string, value any) {
+	if !c.Cacheable(id) {
+		return
+	}
+	ttl := c.ttl(id)
+	c.Local.Set(key, value, ttl)
+	if c.Remote != nil {
+		// 写入失败时下次重新采集
+		_ = c.Remote.Set(key, id, value, ttl)
+	}
+}
+
+func (c *VerdictCache) ttl(id string) time.Duration {
+	if c.TTL != nil {
+		return c.TTL[id]
+	}
+	return DefaultVerdictTTL[id]
+}
+
+func (c *SQLRisk) verdicts() *VerdictCache {
+	if c.Config == nil {
+		return nil
+	}
+	return c.Config.VerdictCache
+}
+
+// verdictKey 注册的数据源按数据源ID区分，否则按地址区分
+func (c *SQLRisk) verdictKey(id string, keys []string) string {
+	datasource := c.DataSourceID
+	if datasource == "" {
+		datasource = c.Addr + ":" + c.Port
+	}
+	return VerdictKey(datasource, c.DataBase, c.FingerID, id, keys)
+}
+
+// cachedAction 操作类型、动作和关键字可能取决于SQL中的值（如SET sql_log_bin = 0），按SQL文本缓存，相同的SQL不再重复解析
+func (c *SQLRisk) cachedAction() (policy.OperateType, policy.ActionType, policy.KeyWordType, []policy.KeyWordType, bool) {
+	verdicts := c.verdicts()
+	ope, ok1 := verdicts.Get(c.verdictKey(policy.Operate.ID, []string{c.SQLID}), policy.Operate.ID)
+	act, ok2 := verdicts.Get(c.verdictKey(policy.Action.ID, []string{c.SQLID}), policy.Action.ID)
+	keyword, ok3 := verdicts.Get(c.verdictKey(policy.KeyWord.ID, []string{c.SQLID}), policy.KeyWord.ID)
+	keywords, ok4 := verdicts.Get(c.verdictKey(policy.KeyWords.ID, []string{c.SQLID}), policy.KeyWords.ID)
+	if !ok1 || !ok2 || !ok3 || !ok4 {
+		return "", "", "", nil, false
+	}
+	o, _ := ope.(policy.OperateType)
+	a, _ := act.(policy.ActionType)
+	k, _ := keyword.(policy.KeyWordType)
+	ks, _ := keywords.([]policy.KeyWordType)
+	return o, a, k, ks, true
+}
+
+func (c *SQLRisk) cacheAction(ope policy.OperateType, act policy.ActionType, keyword policy.KeyWordType, keywords []policy.KeyWordType) {
+	verdicts := c.verdicts()
+	verdicts.Set(c.verdictKey(policy.Operate.ID, []string{c.SQLID}), policy.Operate.ID, ope)
+	verdicts.Set(c.verdictKey(policy.Action.ID, []string{c.SQLID}), policy.Action.ID, act)
+	verdicts.Set(c.verdictKey(policy.KeyWord.ID, []string{c.SQLID}), policy.KeyWord.ID, keyword)
+	verdicts.Set(c.verdictKey(policy.KeyWords.ID, []string{c.SQLID}), policy.KeyWords.ID, keywords)
+}
@@ -0,0 +1,137 @@
+package sqlrisk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sunkaimr/sql-risk/policy"
+)
+
+func TestMemoryCache(t *testing.T) {
+	c := NewMemoryCache(2)
+	c.Set("a", 1, 0)
+	c.Set("b", 2, 0)
+	c.Get("a")
+	// 超过容量时淘汰最久未使用的b
+	c.Set("c", 3, 0)
+	if _, ok := c.Get("b"); ok || c.Len() != 2 {
+		t.Fatalf("MemoryCache got b, len %d, want evicted", c.Len())
+	}
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("MemoryCache got a %v, %v, want 1", v, ok)
+	}
+
+	c.Set("d", 4, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := c.Get("d"); ok {
+		t.Fatalf("MemoryCache got expired d")
+	}
+
+	var empty *MemoryCache
+	empty.Set("a", 1, 0)
+	if _, ok := empty.Get("a"); ok {
+		t.Fatalf("nil MemoryCache got a")
+	}
+}
+
+func TestVerdictCache(t *testing.T) {
+	verdicts := NewVerdictCache(100, nil)
+	newRisk := func(finger string) *SQLRisk {
+		r := NewSqlRisk("", "127.0.0.1", "", "3306", "", "", "test", "update t1 set a = 1", &Config{VerdictCache: verdicts})
+		r.FingerID = finger
+		return r
+	}
+
+	// 跨工单命中缓存时不再连接数据库
+	tables := []string{"test.t1"}
+	verdicts.Set(newRisk("f1").verdictKey(policy.TabExist.ID, tables), policy.TabExist.ID, true)
+	r := newRisk("f1")
+	if err := r.CollectValueWithCache(policy.TabExist.Name, policy.TabExist.ID, tables, "CollectTableExist", true); err != nil {
+		t.Fatalf("CollectValueWithCache failed, got error: %s", err)
+	}
+	if v, err := r.GetItemValueWithBool(policy.TabExist.ID); err != nil || !v {
+		t.Fatalf("TabExist got %v, %v, want true", v, err)
+	}
+
+	// 实时的评估项不跨工单缓存
+	key := r.verdictKey(policy.AffectRows.ID, []string{r.SQLID})
+	verdicts.Set(key, policy.AffectRows.ID, 100)
+	if _, ok := verdicts.Get(key, policy.AffectRows.ID); ok {
+		t.Fatalf("AffectRows should not be cached across tickets")
+	}
+
+	// 相同的SQL使用缓存的关键字
+	r = newRisk("f2")
+	r.SQLID = "s1"
+	r.cacheAction(policy.Operate.V.DML, policy.Action.V.Update, policy.KeyWord.V.Update, nil)
+	r = newRisk("f2")
+	r.SQLID = "s1"
+	r.SQLText = "delete from t1"
+	if err := r.SetSQLBasicInfo(); err != nil {
+		t.Fatalf("SetSQLBasicInfo failed, got error: %s", err)
+	}
+	if v, _ := r.GetItemValueWithKeyWordType(policy.KeyWord.ID); v != policy.KeyWord.V.Update {
+		t.Fatalf("KeyWord got %v, want cached %v", v, policy.KeyWord.V.Update)
+	}
+	r = newRisk("")
+	r.SQLText = "delete from t1"
+	if err := r.SetSQLBasicInfo(); err != nil {
+		t.Fatalf("SetSQLBasicInfo failed, got error: %s", err)
+	}
+	if _, ok := verdicts.Get(r.verdictKey(policy.KeyWord.ID, []string{r.SQLID}), policy.KeyWord.ID); !ok {
+		t.Fatalf("KeyWord of %s not cached", r.SQLID)
+	}
+
+	// 指纹相同但值不同的SQL关键字可能不同，不能共用缓存
+	tests := []struct {
+		sql  string
+		want policy.KeyWordType
+	}{
+		{"SET sql_log_bin=1", policy.KeyWord.V.SetVariable},
+		{"SET sql_log_bin=0", policy.KeyWord.V.DisableBinlog},
+	}
+	for _, test := range tests {
+		r = NewSqlRisk("", "127.0.0.1", "", "3306", "", "", "test", test.sql, &Config{VerdictCache: verdicts})
+		if err := r.SetSQLBasicInfo(); err != nil {
+			t.Fatalf("SetSQLBasicInfo(%s) failed, got error: %s", test.sql, err)
+		}
+		if v, _ := r.GetItemValueWithKeyWordType(policy.KeyWord.ID); v != test.want {
+			t.Fatalf("KeyWord of %s got %v, want %v", test.sql, v, test.want)
+		}
+	}
+}
+
+func TestMySQLVerdictCache(t *testing.T) {
+	store, mock, err := mockResultStore()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	verdicts := NewVerdictCache(100, NewMySQLCache(store.DB))
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `verdict_cache_entries` .* ON DUPLICATE KEY UPDATE").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	verdicts.Set("k1", policy.TabRows.ID, 2048)
+
+	// 内存中没有时查询MySQL，并还原评估项的类型
+	mock.ExpectQuery("SELECT \\* FROM `verdict_cache_entries` WHERE cache_key = \\?").
+		WillReturnRows(mock.NewRows([]string{"cache_key", "item_id", "value", "expire_at"}).
+			AddRow("k2", policy.KeyWords.ID, `["alter add column","alter drop column"]`, nil))
+	v, ok := verdicts.Get("k2", policy.KeyWords.ID)
+	keywords, _ := v.([]policy.KeyWordType)
+	if !ok || len(keywords) != 2 || keywords[0] != policy.KeyWord.V.AlertAddCol {
+		t.Fatalf("Get got %#v, %v", v, ok)
+	}
+	// 回填内存后不再查询MySQL
+	if v, ok = verdicts.Get("k2", policy.KeyWords.ID); !ok {
+		t.Fatalf("Get got %#v, %v after backfill", v, ok)
+	}
+
+	if v, ok = verdicts.Get("k1", policy.TabRows.ID); !ok || v != 2048 {
+		t.Fatalf("Get got %v, %v, want 2048", v, ok)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
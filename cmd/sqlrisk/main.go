@@ -0,0 +1,249 @@
+// sqlrisk 命令行工具：对SQL文件进行风险识别，可在本地或CI中执行与工单系统相同的检查
+//
+//	sqlrisk -dsn 'user:passwd@tcp(127.0.0.1:3306)/db' a.sql b.sql
+//	cat a.sql | sqlrisk -db db -format ci -fail-level high
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/sunkaimr/sql-risk"
+	"github.com/sunkaimr/sql-risk/comm"
+	"github.com/sunkaimr/sql-risk/policy"
+)
+
+const (
+	exitOK      = 0
+	exitRisk    = 1
+	exitFailure = 2
+
+	stdinName = "<stdin>"
+)
+
+type options struct {
+	dsn       string
+	rwAddr    string
+	database  string
+	policy    string
+	promURL   string
+	promToken string
+	promTLS   bool
+	metrics   string
+	diskMB    int
+	cpuCores  int
+	format    string
+	failLevel string
+	passwdEnv string
+	replicas  string
+	backend   string
+}
+
+// verdicts 同一次运行中识别的多个文件共享评估项缓存，相同指纹的SQL不再重复采集
+var verdicts = sqlrisk.NewVerdictCache(10000, nil)
+
+// input 待识别的SQL文件
+type input struct {
+	name string
+	sql  string
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	opt := options{}
+	fs := flag.NewFlagSet("sqlrisk", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&opt.dsn, "dsn", "", "数据源, 格式: user:passwd@tcp(host:port)/database, 不指定时以离线模式运行")
+	fs.StringVar(&opt.rwAddr, "rw-addr", "", "读写库的地址, 用于查询监控信息")
+	fs.StringVar(&opt.database, "db", "", "SQL默认操作的库, 不指定时使用dsn中的库名")
+	fs.StringVar(&opt.policy, "policy", "", "策略文件, 不指定时使用默认策略")
+	fs.StringVar(&opt.promURL, "prom", "", "监控查询地址(Prometheus/Thanos)")
+	fs.StringVar(&opt.promToken, "prom-token-env", "", "从该环境变量读取监控查询的Bearer Token")
+	fs.BoolVar(&opt.promTLS, "prom-insecure", false, "查询监控时跳过证书校验")
+	fs.StringVar(&opt.metrics, "metrics-class", "", "数据源的监控类别: tencent-cdb, node, windows, 不指定时依次尝试")
+	fs.IntVar(&opt.diskMB, "disk-capacity", 0, "没有监控时根据磁盘容量(MB)和MySQL数据目录大小估算剩余空间")
+	fs.IntVar(&opt.cpuCores, "cpu-cores", 0, "没有监控时根据CPU核数和Threads_running估算CPU使用率")
+	fs.StringVar(&opt.replicas, "replica", "", "只读库地址, 格式: host:port, 多个以逗号分隔, 表元数据和COUNT(*)查询优先发往只读库")
+	fs.StringVar(&opt.backend, "backend", "", "数据库类型: mysql, tidb, postgres, 不指定时根据数据库版本识别(postgres需要显式指定)")
+	fs.StringVar(&opt.passwdEnv, "passwd-env", "", "从该环境变量读取数据源密码, 避免在dsn中写明文密码")
+	fs.StringVar(&opt.format, "format", formatText, "输出格式: text, json, ci, sarif, junit")
+	fs.StringVar(&opt.failLevel, "fail-level", string(comm.High), "风险等级达到该级别时返回非0: info, low, high, fatal")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: sqlrisk [flags] [file.sql ...]\n\n未指定文件或文件为'-'时从标准输入读取SQL\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitFailure
+	}
+
+	failLevel := comm.Level(strings.ToLower(opt.failLevel))
+	if _, ok := comm.LevelMap[failLevel]; !ok {
+		fmt.Fprintf(stderr, "unsupported fail level: %s\n", opt.failLevel)
+		return exitFailure
+	}
+
+	reporter, err := newReporter(opt.format, failLevel, stdout)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+
+	inputs, err := readInputs(fs.Args(), stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+
+	err = initPolicy(opt.policy)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+
+	works := make([]*sqlrisk.WorkRisk, 0, len(inputs))
+	for _, in := range inputs {
+		w, err := newWorkRisk(opt, in)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitFailure
+		}
+		// 识别失败时工单会被标记为fatal，错误信息记录在工单中一并输出
+		_ = w.IdentifyWorkRiskPreRisk()
+		works = append(works, w)
+	}
+
+	names := make([]string, 0, len(inputs))
+	for _, in := range inputs {
+		names = append(names, in.name)
+	}
+	err = reporter.Report(names, works)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+
+	for _, w := range works {
+		if comm.LevelMap[w.PreResult.Level] >= comm.LevelMap[failLevel] {
+			return exitRisk
+		}
+	}
+	return exitOK
+}
+
+// readInputs 读取SQL文件，未指定文件或文件为'-'时从标准输入读取
+func readInputs(files []string, stdin io.Reader) ([]input, error) {
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	inputs := make([]input, 0, len(files))
+	for _, file := range files {
+		var b []byte
+		var err error
+		name := file
+		if file == "-" {
+			name = stdinName
+			b, err = io.ReadAll(stdin)
+		} else {
+			b, err = os.ReadFile(file)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read %s failed, %s", name, err)
+		}
+		inputs = append(inputs, input{name: name, sql: string(b)})
+	}
+	return inputs, nil
+}
+
+// initPolicy 加载策略，未指定策略文件时使用默认策略
+func initPolicy(file string) error {
+	store := policy.GetStore(policy.MemoryStoreType, nil)
+	if file != "" {
+		store = policy.GetStore(policy.FileStoreType, file)
+	}
+
+	err := store.Init()
+	if err != nil {
+		return fmt.Errorf("init policy failed, %s", err)
+	}
+	return nil
+}
+
+// newWorkRisk 根据命令行参数创建工单，未指定dsn时以离线模式识别
+func newWorkRisk(opt options, in input) (*sqlrisk.WorkRisk, error) {
+	if opt.dsn == "" {
+		if opt.database == "" {
+			return nil, fmt.Errorf("database is required in offline mode, please specify it with -db")
+		}
+		w, err := sqlrisk.NewWorkRisk(in.name, "", opt.database, in.sql, nil)
+		if err != nil {
+			return nil, err
+		}
+		w.Config.RiskConfig.Offline = true
+		w.Config.VerdictCache = verdicts
+		return w, nil
+	}
+
+	dsn, err := mysql.ParseDSN(opt.dsn)
+	if err != nil {
+		return nil, fmt.Errorf("parse dsn failed, %s", err)
+	}
+
+	var credential sqlrisk.CredentialProvider
+	if opt.passwdEnv != "" {
+		credential = sqlrisk.EnvCredential{PasswdEnv: opt.passwdEnv}
+	}
+
+	host, port := splitHostPort(dsn.Addr)
+	ds := &sqlrisk.DataSource{
+		ID:            dsn.Addr,
+		Primary:       sqlrisk.Endpoint{Addr: host, Port: port, User: dsn.User, Passwd: dsn.Passwd, Credential: credential},
+		ReadWriteAddr: opt.rwAddr,
+		Backend:       sqlrisk.BackendType(strings.ToLower(opt.backend)),
+		MetricsClass:  opt.metrics,
+	}
+	for _, r := range strings.Split(opt.replicas, ",") {
+		if r = strings.TrimSpace(r); r == "" {
+			continue
+		}
+		h, p := splitHostPort(r)
+		ds.Replicas = append(ds.Replicas, sqlrisk.Endpoint{Addr: h, Port: p, User: dsn.User, Passwd: dsn.Passwd, Credential: credential})
+	}
+	if err = sqlrisk.RegisterDataSource(ds); err != nil {
+		return nil, err
+	}
+
+	database := opt.database
+	if database == "" {
+		database = dsn.DBName
+	}
+
+	w, err := sqlrisk.NewWorkRisk(in.name, ds.ID, database, in.sql, nil)
+	if err != nil {
+		return nil, err
+	}
+	w.Config.Runtime.Url = opt.promURL
+	w.Config.Runtime.InsecureSkipVerify = opt.promTLS
+	if opt.promToken != "" {
+		w.Config.Runtime.BearerToken = os.Getenv(opt.promToken)
+	}
+	w.Config.Runtime.DiskCapacity = opt.diskMB
+	w.Config.Runtime.CpuCores = opt.cpuCores
+	w.Config.VerdictCache = verdicts
+	return w, nil
+}
+
+// splitHostPort 拆分host:port，未指定端口时使用3306
+func splitHostPort(addr string) (string, string) {
+	if i := strings.LastIndex(addr, ":"); i != -1 {
+		return addr[:i], addr[i+1:]
+	}
+	return addr, "3306"
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRunOffline(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		sql      string
+		exitCode int
+		contains []string
+	}{
+		{
+			name:     "test001",
+			args:     []string{"-db", "test", "-fail-level", "fatal"},
+			sql:      "select * from student where id=1;",
+			exitCode: exitOK,
+			contains: []string{"[1] low", "OPE.SELECT.001"},
+		},
+		{
+			name:     "test002",
+			args:     []string{"-db", "test", "-format", "ci"},
+			sql:      "select * from student where id=1;\ndelete from student;",
+			exitCode: exitRisk,
+			contains: []string{"<stdin>:#1: low: OPE.SELECT.001", "<stdin>:#2: fatal: AGG.RULEMATCH.101"},
+		},
+		{
+			name:     "test003",
+			args:     []string{"-format", "ci"},
+			sql:      "select * from student where id=1;",
+			exitCode: exitFailure,
+		},
+		{
+			name:     "test005",
+			args:     []string{"-db", "test", "-format", "sarif"},
+			sql:      "select * from student where id=1;\ndelete from student;",
+			exitCode: exitRisk,
+			contains: []string{`"version": "2.1.0"`, `"ruleId": "AGG.RULEMATCH.101"`, `"startLine": 2`},
+		},
+		{
+			name:     "test006",
+			args:     []string{"-db", "test", "-format", "junit", "-fail-level", "fatal"},
+			sql:      "select * from student where id=1;\ndelete from student;",
+			exitCode: exitRisk,
+			contains: []string{`<testsuites tests="2" failures="1" errors="0">`, `<failure message="AGG.RULEMATCH.101`},
+		},
+		{
+			name:     "test007",
+			args:     []string{"-db", "test", "-format", "ci"},
+			sql:      "alter table student add column a int, drop column b, drop primary key;",
+			exitCode: exitRisk,
+			contains: []string{"<stdin>:#1: fatal: AGG.RULEMATCH.055"},
+		},
+		{
+			name:     "test008",
+			args:     []string{"-db", "test", "-fail-level", "fatal"},
+			sql:      "insert into student values (1);\ninsert into student values (2);",
+			exitCode: exitOK,
+			contains: []string{"sql: 2", "[2] ", "represented by #1"},
+		},
+		{
+			name:     "test009",
+			args:     []string{"-db", "test", "-format", "ci"},
+			sql:      "create table t_new (id int primary key);\ninsert into t_new select * from t;\nrename table t to t_bak, t_new to t;",
+			exitCode: exitRisk,
+			contains: []string{"<stdin>:#3: info: SEQ.SWAP.001"},
+		},
+		{
+			name:     "test010",
+			args:     []string{"-db", "test", "-format", "ci"},
+			sql:      "create table t_new (id int primary key);\nrename table t to t_bak, t_new to t;",
+			exitCode: exitRisk,
+			contains: []string{"<stdin>:#2: fatal: SEQ.SWAP.002"},
+		},
+		{
+			name:     "test011",
+			args:     []string{"-db", "test", "-format", "ci"},
+			sql:      "alter table t add column c int;\nupdate t set c=1;",
+			exitCode: exitRisk,
+			contains: []string{"<stdin>:#2: high: SEQ.ALTER.001"},
+		},
+		{
+			name:     "test004",
+			args:     []string{"-db", "test", "-fail-level", "unknown"},
+			sql:      "select * from student where id=1;",
+			exitCode: exitFailure,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			code := run(test.args, strings.NewReader(test.sql), stdout, stderr)
+			if code != test.exitCode {
+				t.Fatalf("run(%v) failed, got exit code:%d, want:%d, stderr:%s", test.args, code, test.exitCode, stderr.String())
+			}
+
+			for _, s := range test.contains {
+				if !strings.Contains(stdout.String(), s) {
+					t.Fatalf("run(%v) output not contains %q, got:\n%s", test.args, s, stdout.String())
+				}
+			}
+		})
+	}
+}
+
+func TestRunJSON(t *testing.T) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run([]string{"-db", "test", "-format", "json"}, strings.NewReader("update student set age=1 where id=1;"), stdout, stderr)
+	if code != exitRisk {
+		t.Fatalf("run failed, got exit code:%d, want:%d, stderr:%s", code, exitRisk, stderr.String())
+	}
+
+	var works []map[string]any
+	err := json.Unmarshal(stdout.Bytes(), &works)
+	if err != nil {
+		t.Fatalf("unmarshal output failed, %s", err)
+	}
+	if len(works) != 1 {
+		t.Fatalf("got %d works, want 1", len(works))
+	}
+}
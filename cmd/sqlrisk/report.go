@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/sunkaimr/sql-risk"
+	"github.com/sunkaimr/sql-risk/comm"
+	"github.com/sunkaimr/sql-risk/report"
+)
+
+const (
+	formatText  = "text"
+	formatJSON  = "json"
+	formatCI    = "ci"
+	formatSARIF = "sarif"
+	formatJUnit = "junit"
+)
+
+// Reporter 输出工单的风险识别结果
+type Reporter interface {
+	Report(names []string, works []*sqlrisk.WorkRisk) error
+}
+
+func newReporter(format string, failLevel comm.Level, w io.Writer) (Reporter, error) {
+	switch strings.ToLower(format) {
+	case formatText:
+		return &TextReporter{w}, nil
+	case formatJSON:
+		return &JSONReporter{w}, nil
+	case formatCI:
+		return &CIReporter{w}, nil
+	case formatSARIF:
+		return &SARIFReporter{w}, nil
+	case formatJUnit:
+		return &JUnitReporter{w: w, failLevel: failLevel}, nil
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+// TextReporter 以便于阅读的文本格式输出
+type TextReporter struct {
+	w io.Writer
+}
+
+func (c *TextReporter) Report(names []string, works []*sqlrisk.WorkRisk) error {
+	for i, work := range works {
+		fmt.Fprintf(c.w, "==> %s  level: %s  special: %v  sql: %d  cost: %dms\n",
+			names[i], work.PreResult.Level, work.PreResult.Special, work.Summary.SQLCount, work.Cost)
+		for _, e := range work.Errors {
+			fmt.Fprintf(c.w, "    error: [%s] %s\n", e.Type, e.Error)
+		}
+
+		for j, r := range work.SQLRisks {
+			fmt.Fprintf(c.w, "[%d] %-5s %s\n", j+1, r.PreResult.Level, r.SQLText)
+			// 抽样未检测的SQL继承代表SQL的策略，不再重复输出
+			if r.RepresentedBy > 0 {
+				fmt.Fprintf(c.w, "    represented by #%d\n", r.RepresentedBy)
+				continue
+			}
+			for _, p := range report.EffectivePolicies(r) {
+				fmt.Fprintf(c.w, "    %-5s %s %s", p.Level, p.PolicyID, p.Name)
+				if p.Suggestion != "" {
+					fmt.Fprintf(c.w, ": %s", p.Suggestion)
+				}
+				fmt.Fprintln(c.w)
+			}
+			for _, e := range r.Errors {
+				fmt.Fprintf(c.w, "    error: [%s] %s\n", e.Type, e.Error)
+			}
+		}
+		fmt.Fprintln(c.w)
+	}
+	return nil
+}
+
+// JSONReporter 以json格式输出完整的识别结果
+type JSONReporter struct {
+	w io.Writer
+}
+
+func (c *JSONReporter) Report(names []string, works []*sqlrisk.WorkRisk) error {
+	encoder := json.NewEncoder(c.w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(works)
+}
+
+// CIReporter 每个风险一行，格式为 file:#n: level: policy_id name: suggestion，便于CI中grep和解析
+type CIReporter struct {
+	w io.Writer
+}
+
+func (c *CIReporter) Report(names []string, works []*sqlrisk.WorkRisk) error {
+	for i, work := range works {
+		for _, e := range work.Errors {
+			fmt.Fprintf(c.w, "%s: error: %s\n", names[i], oneLine(e.Error))
+		}
+
+		for j, r := range work.SQLRisks {
+			for _, p := range report.EffectivePolicies(r) {
+				msg := p.Description
+				if p.Suggestion != "" {
+					msg = p.Suggestion
+				}
+				fmt.Fprintf(c.w, "%s:#%d: %s: %s %s: %s\n", names[i], j+1, p.Level, p.PolicyID, p.Name, oneLine(msg))
+			}
+			for _, e := range r.Errors {
+				fmt.Fprintf(c.w, "%s:#%d: error: %s\n", names[i], j+1, oneLine(e.Error))
+			}
+		}
+	}
+	return nil
+}
+
+// SARIFReporter 以SARIF格式输出，可直接上传到GitHub code scanning等平台
+type SARIFReporter struct {
+	w io.Writer
+}
+
+func (c *SARIFReporter) Report(_ []string, works []*sqlrisk.WorkRisk) error {
+	encoder := json.NewEncoder(c.w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report.SARIF(works...))
+}
+
+// JUnitReporter 以JUnit XML格式输出，风险等级达到failLevel的SQL记为失败
+type JUnitReporter struct {
+	w         io.Writer
+	failLevel comm.Level
+}
+
+func (c *JUnitReporter) Report(_ []string, works []*sqlrisk.WorkRisk) error {
+	_, err := io.WriteString(c.w, xml.Header)
+	if err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(c.w)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(report.JUnit(c.failLevel, works...))
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(c.w, "\n")
+	return err
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,89 @@
+package comm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/tidb/parser/ast"
+	"github.com/pingcap/tidb/parser/format"
+)
+
+// ParseOptimizeTable 识别 OPTIMIZE [NO_WRITE_TO_BINLOG | LOCAL] TABLE tbl_name [, tbl_name] ...
+// tidb/parser不支持该语句，通过分词识别，返回语句操作的表
+func ParseOptimizeTable(sql string, defaultDB string) ([]string, bool) {
+	tokens := tokenizeRoutine(RemoveSQLComments(sql), 1024)
+	if len(tokens) < 3 || !tokens[0].is("optimize") {
+		return nil, false
+	}
+
+	i := 1
+	if tokens[i].is("no_write_to_binlog", "local") {
+		i++
+	}
+	if i >= len(tokens) || !tokens[i].is("table", "tables") {
+		return nil, false
+	}
+	i++
+
+	var tables []string
+	for {
+		var schema, name string
+		schema, name, i = parseQualifiedName(tokens, i)
+		if name == "" {
+			break
+		}
+		if schema == "" {
+			schema = defaultDB
+		}
+		tables = append(tables, fmt.Sprintf("%s.%s", schema, name))
+
+		if i >= len(tokens) || tokens[i].text != "," {
+			break
+		}
+		i++
+	}
+	return tables, len(tables) != 0
+}
+
+// ParseUseDataBase 识别 USE db_name 语句，返回切换后的库
+func ParseUseDataBase(sql string) (string, bool) {
+	sql = RemoveSQLComments(sql)
+	if len(sql) < 4 || !strings.EqualFold(sql[:3], "use") {
+		return "", false
+	}
+
+	node, err := TiParse(sql, "", "")
+	if err != nil {
+		return "", false
+	}
+	if n, ok := node.(*ast.UseStmt); ok {
+		return n.DBName, true
+	}
+	return "", false
+}
+
+// RestoreExpr 将表达式还原为SQL文本
+func RestoreExpr(expr ast.ExprNode) string {
+	if expr == nil {
+		return ""
+	}
+	var sb strings.Builder
+	err := expr.Restore(format.NewRestoreCtx(format.DefaultRestoreFlags, &sb))
+	if err != nil {
+		return ""
+	}
+	return sb.String()
+}
+
+// tableNames 将表名转换为 库.表 的格式
+func tableNames(defaultDB string, names ...*ast.TableName) []string {
+	tables := make([]string, 0, len(names))
+	for _, t := range names {
+		db := defaultDB
+		if t.Schema.O != "" {
+			db = t.Schema.O
+		}
+		tables = append(tables, fmt.Sprintf("%s.%s", db, t.Name.O))
+	}
+	return tables
+}
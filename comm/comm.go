@@ -1,12 +1,15 @@
 package comm
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/parser/ast"
@@ -36,16 +39,70 @@ type TableConstraints struct {
 	Column []string
 }
 
+// Position SQL语句在原始文本中的位置
+type Position struct {
+	// 字节偏移，从0开始
+	Offset int `json:"offset"`
+	// 行号，从1开始
+	Line int `json:"line"`
+	// 列号（按字符计算），从1开始
+	Column int `json:"column"`
+}
+
+// Statement 拆分后的SQL语句
+type Statement struct {
+	// 去除注释和空格后的SQL
+	SQL string `json:"sql"`
+	// 原始文本中的语句，保留语句内的注释，不含语句前的空白、注释和结尾的分隔符
+	Text string `json:"text"`
+	// 语句在原始文本中的起始位置（跳过语句前的空白和注释）
+	Start Position `json:"start"`
+	// 语句在原始文本中的结束位置（不含分隔符），指向语句最后一个字符的下一个字符
+	End Position `json:"end"`
+}
+
 // SplitStatement 将多个SQL语句进行拆分
 func SplitStatement(sqls string) []string {
-	sqlList := make([]string, 0, 100)
+	stmts := SplitStatementWithPosition(sqls)
+	sqlList := make([]string, 0, len(stmts))
+	for _, stmt := range stmts {
+		sqlList = append(sqlList, stmt.SQL)
+	}
+	return sqlList
+}
+
+// SplitStatementWithPosition 将多个SQL语句进行拆分，并记录每个语句在原始文本中的位置
+// 支持DELIMITER指令切换分隔符，DELIMITER指令本身不作为语句返回
+func SplitStatementWithPosition(sqls string) []Statement {
+	return splitStatementWithPosition(sqls, false)
+}
+
+// SplitPostgresStatementWithPosition 按PostgreSQL的语法拆分SQL语句，$tag$...$tag$包裹的函数体中的分号不作为分隔符
+func SplitPostgresStatementWithPosition(sqls string) []Statement {
+	return splitStatementWithPosition(sqls, true)
+}
+
+func splitStatementWithPosition(sqls string, dollarQuote bool) []Statement {
+	text := sqls
+	stmts := make([]Statement, 0, 100)
+	offset := 0
+	delimiter := ";"
 	for {
 		if sqls == "" {
 			break
 		}
 
+		// 和mysql客户端一样处理DELIMITER指令，存储过程、函数、触发器的定义体中可以包含分号
+		skip := skipSpaceAndComments(sqls)
+		if d, n, ok := parseDelimiter(sqls[skip:]); ok {
+			delimiter = d
+			offset += skip + n
+			sqls = sqls[skip+n:]
+			continue
+		}
+
 		// 查询请求切分
-		orgSQL, sql, bufBytes := SplitOneStatement([]byte(sqls), []byte{';'})
+		orgSQL, sql, bufBytes := splitOneStatement([]byte(sqls), []byte(delimiter), dollarQuote)
 		if len(sqls) == len(bufBytes) {
 			// 防止切分死循环，当剩余的内容和原 SQL 相同时直接清空 sqls
 			sqls = ""
@@ -54,6 +111,9 @@ func SplitStatement(sqls string) []string {
 		} else {
 			sqls = string(bufBytes)
 		}
+		start := offset + skipSpaceAndComments(orgSQL)
+		end := offset + len(trimDelimiter(orgSQL, delimiter))
+		offset += len(orgSQL)
 
 		// 去除无用的备注和空格
 		sql = RemoveSQLComments(sql)
@@ -61,15 +121,124 @@ func SplitStatement(sqls string) []string {
 			continue
 		}
 
-		sqlList = append(sqlList, sql)
+		if end < start {
+			end = start
+		}
+		stmts = append(stmts, Statement{
+			SQL:   sql,
+			Text:  text[start:end],
+			Start: OffsetToPosition(text, start),
+			End:   OffsetToPosition(text, end),
+		})
+	}
+
+	return stmts
+}
+
+// OffsetToPosition 将字节偏移转换为行号和列号
+func OffsetToPosition(text string, offset int) Position {
+	if offset > len(text) {
+		offset = len(text)
+	}
+	if offset < 0 {
+		offset = 0
 	}
 
-	return sqlList
+	prefix := text[:offset]
+	lineStart := strings.LastIndex(prefix, "\n") + 1
+	return Position{
+		Offset: offset,
+		Line:   strings.Count(prefix, "\n") + 1,
+		Column: utf8.RuneCountInString(prefix[lineStart:]) + 1,
+	}
+}
+
+// parseDelimiter 解析DELIMITER指令，返回新的分隔符和指令占用的字节数（不含行尾换行符）
+func parseDelimiter(sql string) (string, int, bool) {
+	const directive = "delimiter"
+	if len(sql) <= len(directive) || !strings.EqualFold(sql[:len(directive)], directive) ||
+		(sql[len(directive)] != ' ' && sql[len(directive)] != '\t') {
+		return "", 0, false
+	}
+
+	end := strings.IndexAny(sql, "\r\n")
+	if end == -1 {
+		end = len(sql)
+	}
+	fields := strings.Fields(sql[len(directive):end])
+	if len(fields) == 0 {
+		return "", 0, false
+	}
+	return fields[0], end, true
+}
+
+// trimDelimiter 去除语句结尾的分隔符和空白
+func trimDelimiter(sql, delimiter string) string {
+	sql = strings.TrimRightFunc(sql, unicode.IsSpace)
+	sql = strings.TrimSuffix(sql, delimiter)
+	return strings.TrimRightFunc(sql, unicode.IsSpace)
+}
+
+// skipSpaceAndComments 返回跳过开头的空白（包括中文空格等Unicode空白）和注释后第一个有效字符的偏移
+func skipSpaceAndComments(sql string) int {
+	i := 0
+	for i < len(sql) {
+		r, size := utf8.DecodeRuneInString(sql[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case strings.HasPrefix(sql[i:], "-- ") || strings.HasPrefix(sql[i:], "--\n") ||
+			strings.HasPrefix(sql[i:], "--\r") || sql[i] == '#':
+			end := strings.IndexAny(sql[i:], "\r\n")
+			if end == -1 {
+				return len(sql)
+			}
+			i += end
+		case strings.HasPrefix(sql[i:], "/*") && !strings.HasPrefix(sql[i:], "/*!") && !strings.HasPrefix(sql[i:], "/*+"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end == -1 {
+				return len(sql)
+			}
+			i += end + 4
+		default:
+			return i
+		}
+	}
+	return i
 }
 
 // SplitOneStatement SQL切分
 // return 1. original sql, 2. remove comment sql, 3. left over buf
 func SplitOneStatement(buf []byte, delimiter []byte) (string, string, []byte) {
+	return splitOneStatement(buf, delimiter, false)
+}
+
+// dollarQuoteRegex PostgreSQL的美元符引用标签：$$或$tag$
+var dollarQuoteRegex = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_]*)?\$`)
+
+// dollarQuoteEnd 返回从buf[i]开始的美元符引用结束标签之后的偏移，不是美元符引用时返回-1，
+// 没有结束标签时返回len(buf)
+func dollarQuoteEnd(buf []byte, i int) int {
+	// 标识符中可以包含$，如a$b$
+	if i > 0 && (buf[i-1] == '_' || buf[i-1] == '$' || isAlnum(buf[i-1])) {
+		return -1
+	}
+	tag := dollarQuoteRegex.Find(buf[i:])
+	if tag == nil {
+		return -1
+	}
+	end := bytes.Index(buf[i+len(tag):], tag)
+	if end == -1 {
+		return len(buf)
+	}
+	return i + len(tag) + end + len(tag)
+}
+
+func isAlnum(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+}
+
+func splitOneStatement(buf []byte, delimiter []byte, dollarQuote bool) (string, string, []byte) {
 	var singleLineComment bool
 	var multiLineComment bool
 	var quoted bool
@@ -142,6 +311,18 @@ func SplitOneStatement(buf []byte, delimiter []byte) (string, string, []byte) {
 			}
 		}
 
+		// PostgreSQL dollar quoted string
+		if dollarQuote && b == '$' && !quoted && !singleLineComment && !multiLineComment {
+			if end := dollarQuoteEnd(buf, i); end != -1 {
+				if end >= len(buf) {
+					sql = string(buf)
+					break
+				}
+				i = end - 1
+				continue
+			}
+		}
+
 		// quoted string
 		switch b {
 		case '`', '\'', '"':
@@ -163,8 +344,9 @@ func SplitOneStatement(buf []byte, delimiter []byte) (string, string, []byte) {
 		if !quoted && !singleLineComment && !multiLineComment {
 			eof := true
 			for k, c := range delimiter {
-				if len(buf) > i+k && buf[i+k] != c {
+				if len(buf) <= i+k || buf[i+k] != c {
 					eof = false
+					break
 				}
 			}
 			if eof {
@@ -209,6 +391,13 @@ func ExtractingRelatedTableName(sql string, defaultDB string) ([]string, error)
 	var tables []string
 	node, err := TiParse(sql, "", "")
 	if err != nil {
+		// tidb/parser不支持的存储过程、函数、触发器、事件
+		if routine, ok := ParseRoutine(sql); ok {
+			return routine.Objects(defaultDB), nil
+		}
+		if tables, ok := ParseOptimizeTable(sql, defaultDB); ok {
+			return tables, nil
+		}
 		return tables, err
 	}
 
@@ -229,6 +418,14 @@ func ExtractingRelatedTableName(sql string, defaultDB string) ([]string, error)
 		}
 	case *ast.UseStmt:
 		tables = append(tables, fmt.Sprintf("%s.", n.DBName))
+	case *ast.LockTablesStmt:
+		for _, l := range n.TableLocks {
+			tables = append(tables, tableNames(defaultDB, l.Table)...)
+		}
+	case *ast.AnalyzeTableStmt:
+		tables = append(tables, tableNames(defaultDB, n.TableNames...)...)
+	case *ast.LoadDataStmt:
+		tables = append(tables, tableNames(defaultDB, n.Table)...)
 	// SetOprStmt represents "union/except/intersect statement"
 	case *ast.InsertStmt, *ast.SelectStmt, *ast.SetOprStmt, *ast.UpdateStmt, *ast.DeleteStmt, *ast.CreateViewStmt:
 		// DML/DQL: INSERT, SELECT, UPDATE, DELETE
@@ -301,6 +498,13 @@ func ExtractingTableName(sql string, defaultDB string) ([]string, error) {
 	var tables []string
 	node, err := TiParse(sql, "", "")
 	if err != nil {
+		// tidb/parser不支持的存储过程、函数、触发器、事件
+		if routine, ok := ParseRoutine(sql); ok {
+			return routine.Objects(defaultDB), nil
+		}
+		if tables, ok := ParseOptimizeTable(sql, defaultDB); ok {
+			return tables, nil
+		}
 		return tables, err
 	}
 
@@ -397,6 +601,16 @@ func ExtractingTableName(sql string, defaultDB string) ([]string, error) {
 		// 多表删除
 		s := extractingDeleteStmtList(defaultDB, alias, n)
 		tables = append(tables, s...)
+
+	// 管理语句
+	case *ast.LockTablesStmt:
+		for _, l := range n.TableLocks {
+			tables = append(tables, tableNames(defaultDB, l.Table)...)
+		}
+	case *ast.AnalyzeTableStmt:
+		tables = append(tables, tableNames(defaultDB, n.TableNames...)...)
+	case *ast.LoadDataStmt:
+		tables = append(tables, tableNames(defaultDB, n.Table)...)
 	}
 
 	return RemoveDuplicatesItem(tables), nil
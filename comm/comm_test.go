@@ -364,3 +364,504 @@ CREATE TABLE my_table (
 		})
 	}
 }
+
+func TestSplitStatementWithPosition(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want []Statement
+	}{
+		{
+			name: "test001",
+			sql:  "select 1;select 2;",
+			want: []Statement{
+				{SQL: "select 1", Text: "select 1", Start: Position{Offset: 0, Line: 1, Column: 1}, End: Position{Offset: 8, Line: 1, Column: 9}},
+				{SQL: "select 2", Text: "select 2", Start: Position{Offset: 9, Line: 1, Column: 10}, End: Position{Offset: 17, Line: 1, Column: 18}},
+			},
+		},
+		{
+			name: "test002",
+			sql:  "-- 注释\nupdate t set a=1 where id=1;\n\n  /* comment */ delete from t where id=2;",
+			want: []Statement{
+				{SQL: "update t set a=1 where id=1", Text: "update t set a=1 where id=1", Start: Position{Offset: 10, Line: 2, Column: 1}, End: Position{Offset: 37, Line: 2, Column: 28}},
+				{SQL: "delete from t where id=2", Text: "delete from t where id=2", Start: Position{Offset: 56, Line: 4, Column: 17}, End: Position{Offset: 80, Line: 4, Column: 41}},
+			},
+		},
+		{
+			name: "test003",
+			sql:  "select 1;\nselect /* c */ 1 ;",
+			want: []Statement{
+				{SQL: "select 1", Text: "select 1", Start: Position{Offset: 0, Line: 1, Column: 1}, End: Position{Offset: 8, Line: 1, Column: 9}},
+				{SQL: "select  1", Text: "select /* c */ 1", Start: Position{Offset: 10, Line: 2, Column: 1}, End: Position{Offset: 26, Line: 2, Column: 17}},
+			},
+		},
+		{
+			name: "test004",
+			sql:  "select 1;\u3000select 2\u3000;",
+			want: []Statement{
+				{SQL: "select 1", Text: "select 1", Start: Position{Offset: 0, Line: 1, Column: 1}, End: Position{Offset: 8, Line: 1, Column: 9}},
+				{SQL: "select 2", Text: "select 2", Start: Position{Offset: 12, Line: 1, Column: 11}, End: Position{Offset: 20, Line: 1, Column: 19}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := SplitStatementWithPosition(test.sql)
+			if len(got) != len(test.want) {
+				t.Fatalf("SplitStatementWithPosition(%q) failed, got:%+v, want:%+v", test.sql, got, test.want)
+			}
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Fatalf("SplitStatementWithPosition(%q) failed, got:%+v, want:%+v", test.sql, got[i], test.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSplitPostgresStatementWithPosition(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{
+			name: "test001",
+			sql:  "CREATE FUNCTION f() RETURNS trigger AS $$ BEGIN NEW.a := 1; RETURN NEW; END; $$ LANGUAGE plpgsql;select 1;",
+			want: []string{
+				"CREATE FUNCTION f() RETURNS trigger AS $$ BEGIN NEW.a := 1; RETURN NEW; END; $$ LANGUAGE plpgsql",
+				"select 1",
+			},
+		},
+		{
+			name: "test002",
+			sql:  "create function g() returns int as $body$ select 1; $$ ; $body$ language sql;\nselect $1 from t;",
+			want: []string{
+				"create function g() returns int as $body$ select 1; $$ ; $body$ language sql",
+				"select $1 from t",
+			},
+		},
+		{
+			name: "test003",
+			sql:  "select a$b$ from t;select 2;",
+			want: []string{"select a$b$ from t", "select 2"},
+		},
+		{
+			name: "test004",
+			sql:  "do $$ begin perform 1; end",
+			want: []string{"do $$ begin perform 1; end"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := SplitPostgresStatementWithPosition(test.sql)
+			if len(got) != len(test.want) {
+				t.Fatalf("SplitPostgresStatementWithPosition(%q) failed, got:%+v, want:%v", test.sql, got, test.want)
+			}
+			for i := range got {
+				if got[i].SQL != test.want[i] {
+					t.Fatalf("SplitPostgresStatementWithPosition(%q) failed, got:%q, want:%q", test.sql, got[i].SQL, test.want[i])
+				}
+			}
+		})
+	}
+
+	// MySQL不支持美元符引用，仍按分号拆分
+	if got := SplitStatementWithPosition(tests[0].sql); len(got) != 5 {
+		t.Fatalf("SplitStatementWithPosition(%q) got %d statements, want 5", tests[0].sql, len(got))
+	}
+}
+
+func TestSplitStatement(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{
+			name: "test001",
+			sql:  "select 1;select 2;",
+			want: []string{"select 1", "select 2"},
+		},
+		{
+			name: "test002",
+			sql: `DELIMITER $$
+CREATE PROCEDURE p1()
+BEGIN
+  update t set a=1 where id=1;
+  delete from t where id=2;
+END$$
+DELIMITER ;
+select 1;`,
+			want: []string{
+				"CREATE PROCEDURE p1()\nBEGIN\n  update t set a=1 where id=1;\n  delete from t where id=2;\nEND",
+				"select 1",
+			},
+		},
+		{
+			name: "test003",
+			sql: `delimiter //
+CREATE TRIGGER tr1 BEFORE INSERT ON t FOR EACH ROW BEGIN SET NEW.a = 1; END //
+CREATE TRIGGER tr2 BEFORE UPDATE ON t FOR EACH ROW BEGIN SET NEW.a = 2; END //`,
+			want: []string{
+				"CREATE TRIGGER tr1 BEFORE INSERT ON t FOR EACH ROW BEGIN SET NEW.a = 1; END",
+				"CREATE TRIGGER tr2 BEFORE UPDATE ON t FOR EACH ROW BEGIN SET NEW.a = 2; END",
+			},
+		},
+		{
+			name: "test004",
+			sql:  "select 'delimiter $$';select 2;",
+			want: []string{"select 'delimiter $$'", "select 2"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := SplitStatement(test.sql)
+			if !SlicesEqual(got, test.want) {
+				t.Fatalf("SplitStatement(%q) failed, got:%q, want:%q", test.sql, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseRoutine(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		ok      bool
+		want    RoutineStmt
+		objects []string
+	}{
+		{
+			name:    "test001",
+			sql:     "CREATE DEFINER=`root`@`%` PROCEDURE `d1`.`p1`(IN id INT) BEGIN DELETE FROM t WHERE t.id = id; END",
+			ok:      true,
+			want:    RoutineStmt{Action: RoutineCreate, Type: RoutineProcedure, Schema: "d1", Name: "p1"},
+			objects: []string{"d1."},
+		},
+		{
+			name:    "test002",
+			sql:     "/* 触发器 */ create trigger if not exists tr1 after update on d2.student for each row set @a = 1",
+			ok:      true,
+			want:    RoutineStmt{Action: RoutineCreate, Type: RoutineTrigger, Name: "tr1", IfNotExists: true, TableSchema: "d2", Table: "student"},
+			objects: []string{"test.", "d2.student"},
+		},
+		{
+			name:    "test003",
+			sql:     "DROP FUNCTION IF EXISTS f1",
+			ok:      true,
+			want:    RoutineStmt{Action: RoutineDrop, Type: RoutineFunction, Name: "f1", IfExists: true},
+			objects: []string{"test."},
+		},
+		{
+			name:    "test004",
+			sql:     "ALTER DEFINER = 'admin'@'localhost' EVENT e1 ON COMPLETION PRESERVE",
+			ok:      true,
+			want:    RoutineStmt{Action: RoutineAlter, Type: RoutineEvent, Name: "e1"},
+			objects: []string{"test."},
+		},
+		{
+			name: "test005",
+			sql:  "ALTER TABLE procedure ADD COLUMN a INT",
+		},
+		{
+			name: "test006",
+			sql:  "CREATE TABLE `event` (id INT)",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := ParseRoutine(test.sql)
+			if ok != test.ok {
+				t.Fatalf("ParseRoutine(%q) failed, got ok:%v, want:%v", test.sql, ok, test.ok)
+			}
+			if !ok {
+				return
+			}
+			if *got != test.want {
+				t.Fatalf("ParseRoutine(%q) failed, got:%+v, want:%+v", test.sql, *got, test.want)
+			}
+			if objects := got.Objects("test"); !SlicesEqual(objects, test.objects) {
+				t.Fatalf("Objects() failed, got:%v, want:%v", objects, test.objects)
+			}
+		})
+	}
+}
+
+func TestExtractingDCLInfo(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want DCLInfo
+	}{
+		{
+			name: "test001",
+			sql:  "GRANT ALL PRIVILEGES ON *.* TO 'u1'@'%' WITH GRANT OPTION",
+			want: DCLInfo{Users: []string{"'u1'@'%'"}, Grant: true, Scope: PrivilegeScopeGlobal, Object: "*.*", Privileges: []string{"ALL PRIVILEGES"},
+				AllPrivileges: true, WithGrantOption: true, AnyHost: true},
+		},
+		{
+			name: "test002",
+			sql:  "GRANT SELECT, INSERT ON * TO 'u1'@'10.%', 'u2'@'10.1.1.1'",
+			want: DCLInfo{Users: []string{"'u1'@'10.%'", "'u2'@'10.1.1.1'"}, Grant: true, Scope: PrivilegeScopeDatabase, Object: "test.*", Privileges: []string{"SELECT", "INSERT"}},
+		},
+		{
+			name: "test003",
+			sql:  "REVOKE UPDATE ON d1.t1 FROM 'u1'@'%'",
+			want: DCLInfo{Users: []string{"'u1'@'%'"}, Scope: PrivilegeScopeTable, Object: "d1.t1", Privileges: []string{"UPDATE"}},
+		},
+		{
+			name: "test004",
+			sql:  "CREATE USER 'u1' IDENTIFIED BY 'password'",
+			want: DCLInfo{Users: []string{"'u1'@'%'"}, AnyHost: true},
+		},
+		{
+			name: "test005",
+			sql:  "SET PASSWORD FOR 'u1'@'10.%' = 'password'",
+			want: DCLInfo{Users: []string{"'u1'@'10.%'"}},
+		},
+		{
+			name: "test006",
+			sql:  "REVOKE ALL PRIVILEGES ON test.* FROM 'u1'@'10.%'",
+			want: DCLInfo{Users: []string{"'u1'@'10.%'"}, Scope: PrivilegeScopeDatabase, Object: "test.*", Privileges: []string{"ALL PRIVILEGES"},
+				AllPrivileges: true},
+		},
+		{
+			name: "test007",
+			sql:  "REVOKE SELECT ON *.* FROM 'u1'@'%'",
+			want: DCLInfo{Users: []string{"'u1'@'%'"}, Scope: PrivilegeScopeGlobal, Object: "*.*", Privileges: []string{"SELECT"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ExtractingDCLInfo(test.sql, "test")
+			if err != nil {
+				t.Fatalf("ExtractingDCLInfo(%q) failed, %s", test.sql, err)
+			}
+			if fmt.Sprintf("%+v", *got) != fmt.Sprintf("%+v", test.want) {
+				t.Fatalf("ExtractingDCLInfo(%q) failed, got:%+v, want:%+v", test.sql, *got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseOptimizeTable(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		ok   bool
+		want []string
+	}{
+		{"test001", "OPTIMIZE TABLE t1", true, []string{"test.t1"}},
+		{"test002", "optimize no_write_to_binlog table `d1`.`t1`, t2", true, []string{"d1.t1", "test.t2"}},
+		{"test003", "OPTIMIZE PARTITION p1", false, nil},
+		{"test004", "SELECT * FROM t1", false, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := ParseOptimizeTable(test.sql, "test")
+			if ok != test.ok || !SlicesEqual(got, test.want) && test.ok {
+				t.Fatalf("ParseOptimizeTable(%q) failed, got:%v %v, want:%v %v", test.sql, got, ok, test.want, test.ok)
+			}
+		})
+	}
+}
+
+func TestParseUseDataBase(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		ok   bool
+		want string
+	}{
+		{"test001", "USE d1", true, "d1"},
+		{"test002", "/* 切换库 */ use `d-2`", true, "d-2"},
+		{"test003", "update user set a=1", false, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := ParseUseDataBase(test.sql)
+			if ok != test.ok || got != test.want {
+				t.Fatalf("ParseUseDataBase(%q) failed, got:%q %v, want:%q %v", test.sql, got, ok, test.want, test.ok)
+			}
+		})
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		want    Version
+		wantErr bool
+	}{
+		{"test001", "8.0.32", Version{8, 0, 32}, false},
+		{"test002", "5.7.40-log", Version{5, 7, 40}, false},
+		{"test003", "5.7.25-TiDB-v6.5.0", Version{5, 7, 25}, false},
+		{"test004", "10.6", Version{10, 6, 0}, false},
+		{"test005", "unknown", Version{}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseVersion(test.version)
+			if (err != nil) != test.wantErr || got != test.want {
+				t.Fatalf("ParseVersion(%q) failed, got:%v %v, want:%v", test.version, got, err, test.want)
+			}
+		})
+	}
+}
+
+func TestDetectFlavor(t *testing.T) {
+	tests := []struct {
+		name      string
+		version   string
+		variables map[string]string
+		want      string
+	}{
+		{"test001", "8.0.32", map[string]string{"version_comment": "MySQL Community Server - GPL"}, FlavorMySQL},
+		{"test002", "5.7.25-TiDB-v6.5.0", nil, FlavorTiDB},
+		{"test003", "10.6.12-MariaDB-log", nil, FlavorMariaDB},
+		{"test004", "8.0.28", map[string]string{"aurora_version": "3.04.0"}, FlavorAurora},
+		{"test005", "8.0.22-txsql", map[string]string{"version_comment": "Tencent Cloud TXSQL"}, FlavorTencentCDB},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := DetectFlavor(test.version, test.variables); got != test.want {
+				t.Fatalf("DetectFlavor(%q) got %s, want %s", test.version, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParsePostgres(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		verb     string
+		object   string
+		tables   []string
+		relevant []string
+		check    func(*PGStmt) bool
+	}{
+		{
+			name:     "test001",
+			sql:      `select a.id from "Order" a join public.item i on i.order_id = a.id, generate_series(1, 3) where a.id in (select id from s1.t1)`,
+			verb:     "select",
+			tables:   []string{"public.Order", "public.item", "s1.t1"},
+			relevant: []string{"public.Order", "public.item", "s1.t1"},
+		},
+		{
+			name:     "test002",
+			sql:      "WITH old AS (SELECT id FROM orders WHERE created < now() - interval '1 year') DELETE FROM ONLY orders USING old WHERE orders.id = old.id",
+			verb:     "delete",
+			tables:   []string{"public.orders"},
+			relevant: []string{"public.orders"},
+			check:    func(s *PGStmt) bool { return s.Where },
+		},
+		{
+			name:     "test003",
+			sql:      "UPDATE Sales.Orders SET status = 'done' FROM (SELECT 1) x",
+			verb:     "update",
+			tables:   []string{"sales.orders"},
+			relevant: []string{"sales.orders"},
+			check:    func(s *PGStmt) bool { return !s.Where },
+		},
+		{
+			name:     "test004",
+			sql:      "INSERT INTO t1 (a, b) SELECT a, b FROM t2 ON CONFLICT (a) DO NOTHING",
+			verb:     "insert",
+			tables:   []string{"public.t1"},
+			relevant: []string{"public.t2", "public.t1"},
+			check:    func(s *PGStmt) bool { return s.Select },
+		},
+		{
+			name:     "test005",
+			sql:      "CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS idx_a ON ONLY t1 USING btree (a)",
+			verb:     "create",
+			object:   "index",
+			tables:   []string{"public.t1"},
+			relevant: []string{"public.t1"},
+			check:    func(s *PGStmt) bool { return s.Unique && s.Concurrently && s.IfNotExists },
+		},
+		{
+			name:     "test006",
+			sql:      "ALTER TABLE IF EXISTS t1 ADD COLUMN c int DEFAULT 0, ALTER COLUMN b TYPE bigint USING b::bigint, ADD CONSTRAINT pk PRIMARY KEY (id), RENAME TO t2",
+			verb:     "alter",
+			object:   "table",
+			tables:   []string{"public.t1"},
+			relevant: []string{"public.t1"},
+			check: func(s *PGStmt) bool {
+				return SlicesEqual(s.AlterActions, []string{PGAlterAddColumn, PGAlterColumnType, PGAlterAddPrimaryKey, PGAlterRenameTable})
+			},
+		},
+		{
+			name:     "test007",
+			sql:      "DROP TABLE IF EXISTS t1, s1.t2 CASCADE",
+			verb:     "drop",
+			object:   "table",
+			tables:   []string{"public.t1", "s1.t2"},
+			relevant: []string{"public.t1", "s1.t2"},
+			check:    func(s *PGStmt) bool { return s.IfExists },
+		},
+		{
+			name:     "test008",
+			sql:      "COPY t1 (a, b) FROM '/tmp/t1.csv' WITH (FORMAT csv)",
+			verb:     "copy",
+			tables:   []string{"public.t1"},
+			relevant: []string{"public.t1"},
+			check:    func(s *PGStmt) bool { return s.CopyFrom },
+		},
+		{
+			name:     "test009",
+			sql:      "EXPLAIN (ANALYZE, BUFFERS) DELETE FROM t1",
+			verb:     "delete",
+			tables:   []string{"public.t1"},
+			relevant: []string{"public.t1"},
+		},
+		{
+			name:     "test010",
+			sql:      "VACUUM (FULL, VERBOSE) t1",
+			verb:     "vacuum",
+			tables:   []string{"public.t1"},
+			relevant: []string{"public.t1"},
+			check:    func(s *PGStmt) bool { return s.Full },
+		},
+		{
+			name:     "test011",
+			sql:      "ALTER SYSTEM SET max_connections = 500",
+			verb:     "alter",
+			object:   "system",
+			tables:   nil,
+			relevant: []string{},
+			check:    func(s *PGStmt) bool { return s.System && s.SetName == "max_connections" && s.SetValue == "500" },
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParsePostgres(test.sql, "")
+			if err != nil {
+				t.Fatalf("ParsePostgres(%q) failed, %s", test.sql, err)
+			}
+			if got.Verb != test.verb || got.Object != test.object {
+				t.Fatalf("ParsePostgres(%q) got verb:%s object:%s, want verb:%s object:%s", test.sql, got.Verb, got.Object, test.verb, test.object)
+			}
+			if !SlicesEqual(got.Tables, test.tables) || !SlicesEqual(got.RelevantTables, test.relevant) {
+				t.Fatalf("ParsePostgres(%q) got tables:%v relevant:%v, want tables:%v relevant:%v",
+					test.sql, got.Tables, got.RelevantTables, test.tables, test.relevant)
+			}
+			if test.check != nil && !test.check(got) {
+				t.Fatalf("ParsePostgres(%q) got:%+v", test.sql, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package comm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/tidb/parser/ast"
+	"github.com/pingcap/tidb/parser/auth"
+	"github.com/pingcap/tidb/parser/mysql"
+)
+
+// 权限范围
+const (
+	PrivilegeScopeGlobal   = "global"
+	PrivilegeScopeDatabase = "database"
+	PrivilegeScopeTable    = "table"
+)
+
+// DCLInfo 授权和用户管理语句的信息
+type DCLInfo struct {
+	// 涉及的用户，格式: 'user'@'host'
+	Users []string
+	// 是否为GRANT语句，REVOKE语句回收权限，不需要评估授权的风险
+	Grant bool
+	// 权限范围：global, database, table，非GRANT/REVOKE语句为空
+	Scope string
+	// 权限作用的对象，格式: *.*, db.*, db.table
+	Object string
+	// 授予或回收的权限
+	Privileges []string
+	// 是否授予或回收所有权限
+	AllPrivileges bool
+	// 是否带有 WITH GRANT OPTION
+	WithGrantOption bool
+	// 授权或创建用户时主机是否为'%'
+	AnyHost bool
+}
+
+// ExtractingDCLInfo 提取GRANT、REVOKE、CREATE/DROP/ALTER USER、SET PASSWORD语句中的用户和权限范围
+func ExtractingDCLInfo(sql string, defaultDB string) (*DCLInfo, error) {
+	node, err := TiParse(sql, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	info := &DCLInfo{}
+	switch n := node.(type) {
+	case *ast.GrantStmt:
+		info.Users = userSpecNames(n.Users)
+		info.Grant = true
+		info.AnyHost = anyHost(n.Users)
+		info.WithGrantOption = n.WithGrant
+		info.setPrivileges(n.Privs, n.Level, defaultDB)
+	case *ast.RevokeStmt:
+		info.Users = userSpecNames(n.Users)
+		info.setPrivileges(n.Privs, n.Level, defaultDB)
+	case *ast.CreateUserStmt:
+		info.Users = userSpecNames(n.Specs)
+		info.AnyHost = anyHost(n.Specs)
+	case *ast.AlterUserStmt:
+		info.Users = userSpecNames(n.Specs)
+	case *ast.DropUserStmt:
+		for _, u := range n.UserList {
+			info.Users = append(info.Users, userName(u))
+		}
+	case *ast.SetPwdStmt:
+		info.Users = append(info.Users, userName(n.User))
+	case *ast.FlushStmt:
+	default:
+		return nil, fmt.Errorf("not a dcl statement: %T", node)
+	}
+	return info, nil
+}
+
+func (c *DCLInfo) setPrivileges(privs []*ast.PrivElem, level *ast.GrantLevel, defaultDB string) {
+	for _, p := range privs {
+		if p.Priv == mysql.AllPriv {
+			c.AllPrivileges = true
+		}
+		if p.Priv == mysql.ExtendedPriv {
+			c.Privileges = append(c.Privileges, strings.ToUpper(p.Name))
+			continue
+		}
+		c.Privileges = append(c.Privileges, strings.ToUpper(p.Priv.String()))
+	}
+
+	if level == nil {
+		return
+	}
+	switch level.Level {
+	case ast.GrantLevelGlobal:
+		c.Scope = PrivilegeScopeGlobal
+		c.Object = "*.*"
+	case ast.GrantLevelDB:
+		// GRANT ... ON * 表示当前库
+		db := level.DBName
+		if db == "" {
+			db = defaultDB
+		}
+		c.Scope = PrivilegeScopeDatabase
+		c.Object = fmt.Sprintf("%s.*", db)
+	case ast.GrantLevelTable:
+		db := level.DBName
+		if db == "" {
+			db = defaultDB
+		}
+		c.Scope = PrivilegeScopeTable
+		c.Object = fmt.Sprintf("%s.%s", db, level.TableName)
+	}
+}
+
+func userSpecNames(specs []*ast.UserSpec) []string {
+	users := make([]string, 0, len(specs))
+	for _, s := range specs {
+		users = append(users, userName(s.User))
+	}
+	return users
+}
+
+func userName(u *auth.UserIdentity) string {
+	if u == nil || u.CurrentUser {
+		return "CURRENT_USER"
+	}
+	return fmt.Sprintf("'%s'@'%s'", u.Username, u.Hostname)
+}
+
+// anyHost 是否存在主机为'%'的用户，未指定主机时mysql默认为'%'
+func anyHost(specs []*ast.UserSpec) bool {
+	for _, s := range specs {
+		if s.User != nil && !s.User.CurrentUser && (s.User.Hostname == "%" || s.User.Hostname == "") {
+			return true
+		}
+	}
+	return false
+}
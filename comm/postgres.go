@@ -0,0 +1,578 @@
+package comm
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// tidb/parser只能解析MySQL语法，PostgreSQL的语句通过分词识别语句类型和操作的表
+
+// PostgresDefaultSchema 未指定schema时表所在的schema
+const PostgresDefaultSchema = "public"
+
+// ALTER TABLE的子句类型
+const (
+	PGAlterAddColumn      = "add column"
+	PGAlterDropColumn     = "drop column"
+	PGAlterColumnType     = "alter column type"
+	PGAlterColumn         = "alter column"
+	PGAlterRenameColumn   = "rename column"
+	PGAlterRenameTable    = "rename table"
+	PGAlterAddPrimaryKey  = "add primary key"
+	PGAlterAddUnique      = "add unique"
+	PGAlterAddConstraint  = "add constraint"
+	PGAlterDropConstraint = "drop constraint"
+	PGAlterAttachPart     = "attach partition"
+	PGAlterDetachPart     = "detach partition"
+	PGAlterOther          = "other"
+)
+
+// PGStmt PostgreSQL语句的分词识别结果
+type PGStmt struct {
+	// 语句的第一个关键字，小写，WITH语句为CTE之后的主语句，如 select、insert、alter
+	Verb string
+	// 操作的对象类型，小写，如 table、index、view、materialized view、function、user
+	Object string
+	// 语句操作（增、删、改、查）的表，格式为 schema.table
+	Tables []string
+	// 语句涉及的所有表，格式为 schema.table
+	RelevantTables []string
+	IfExists       bool
+	IfNotExists    bool
+	// CREATE TEMPORARY TABLE
+	Temporary bool
+	// CREATE UNIQUE INDEX
+	Unique bool
+	// CREATE/DROP INDEX CONCURRENTLY
+	Concurrently bool
+	// CREATE TABLE ... AS、INSERT ... SELECT
+	Select bool
+	// UPDATE、DELETE是否带WHERE条件
+	Where bool
+	// COPY ... FROM
+	CopyFrom bool
+	// VACUUM FULL
+	Full bool
+	// ALTER SYSTEM SET
+	System bool
+	// SET语句的参数名（小写）和值
+	SetName  string
+	SetValue string
+	// 是否调用了 pg_terminate_backend、pg_cancel_backend
+	TerminateBackend bool
+	// ALTER TABLE的子句类型
+	AlterActions []string
+}
+
+// pgClauseKeywords 表名之后不能作为别名的关键字
+var pgClauseKeywords = []string{
+	"where", "join", "inner", "left", "right", "full", "cross", "natural", "on", "using", "group", "order", "having",
+	"limit", "offset", "union", "intersect", "except", "window", "for", "returning", "set", "values", "select",
+	"from", "as", "default", "do", "to", "with", "tablesample", "fetch", "lateral",
+}
+
+type pgParser struct {
+	tokens        []routineToken
+	i             int
+	defaultSchema string
+}
+
+// ParsePostgres 识别PostgreSQL语句的类型和操作的表，defaultSchema为空时使用public
+func ParsePostgres(sql, defaultSchema string) (*PGStmt, error) {
+	if defaultSchema == "" {
+		defaultSchema = PostgresDefaultSchema
+	}
+	p := &pgParser{tokens: tokenizeRoutine(RemoveSQLComments(sql), math.MaxInt), defaultSchema: defaultSchema}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("empty statement")
+	}
+
+	stmt := &PGStmt{RelevantTables: p.fromTables()}
+	for _, t := range p.tokens {
+		if t.is("pg_terminate_backend", "pg_cancel_backend") {
+			stmt.TerminateBackend = true
+		}
+	}
+
+	// WITH ... 取CTE之后的主语句，CTE的名称不是表
+	var ctes []string
+	if p.peek("with") {
+		p.i, ctes = p.mainStatement()
+	}
+	if p.i >= len(p.tokens) || p.tokens[p.i].quoted {
+		return nil, fmt.Errorf("unrecognized statement")
+	}
+	stmt.Verb = strings.ToLower(p.tokens[p.i].text)
+	p.i++
+
+	// EXPLAIN ANALYZE会真正执行语句，按被执行的语句识别
+	if stmt.Verb == "explain" && p.explainAnalyze() && p.i < len(p.tokens) {
+		stmt.Verb = strings.ToLower(p.tokens[p.i].text)
+		p.i++
+	}
+
+	switch stmt.Verb {
+	case "select", "table", "values", "show", "explain":
+		stmt.Verb = "select"
+		stmt.Tables = stmt.RelevantTables
+	case "insert":
+		p.accept("into")
+		stmt.Tables = p.names(false)
+		stmt.Select = p.contains("select")
+	case "update":
+		p.accept("only")
+		stmt.Tables = p.names(false)
+		stmt.Where = p.topLevel("where")
+	case "delete":
+		p.accept("from")
+		p.accept("only")
+		stmt.Tables = p.names(false)
+		stmt.Where = p.topLevel("where")
+		if p.seek("using") {
+			stmt.RelevantTables = append(stmt.RelevantTables, p.names(true)...)
+		}
+	case "truncate":
+		p.accept("table")
+		p.accept("only")
+		stmt.Object = "table"
+		stmt.Tables = p.names(true)
+	case "copy":
+		stmt.Tables = p.names(false)
+		if len(stmt.Tables) == 0 {
+			// COPY (query) TO ...
+			stmt.Tables = stmt.RelevantTables
+		}
+		stmt.CopyFrom = p.topLevel("from")
+	case "lock":
+		p.accept("table")
+		p.accept("only")
+		stmt.Object = "table"
+		stmt.Tables = p.names(true)
+	case "vacuum", "analyze", "analyse", "cluster", "reindex":
+		p.parseMaintenance(stmt)
+	case "create":
+		p.parseCreate(stmt)
+	case "drop":
+		p.parseDrop(stmt)
+	case "alter":
+		p.parseAlter(stmt)
+	case "set":
+		p.parseSet(stmt)
+	}
+	relevant := make([]string, 0, len(stmt.RelevantTables)+len(stmt.Tables))
+	for _, t := range append(stmt.RelevantTables, stmt.Tables...) {
+		if !EleExist(t, ctes) {
+			relevant = append(relevant, t)
+		}
+	}
+	stmt.RelevantTables = RemoveDuplicatesItem(relevant)
+	return stmt, nil
+}
+
+// explainAnalyze 跳过EXPLAIN的选项，返回是否包含ANALYZE
+func (p *pgParser) explainAnalyze() bool {
+	analyze := false
+	if p.peek("(") {
+		for ; p.i < len(p.tokens) && p.tokens[p.i].text != ")"; p.i++ {
+			if p.tokens[p.i].is("analyze", "analyse") && !(p.i+1 < len(p.tokens) && p.tokens[p.i+1].is("false", "off", "0")) {
+				analyze = true
+			}
+		}
+		p.i++
+	}
+	for p.peek("analyze", "analyse", "verbose") {
+		if p.peek("analyze", "analyse") {
+			analyze = true
+		}
+		p.i++
+	}
+	return analyze
+}
+
+func (p *pgParser) parseMaintenance(stmt *PGStmt) {
+	if stmt.Verb == "analyse" {
+		stmt.Verb = "analyze"
+	}
+	// VACUUM (FULL, ANALYZE) t
+	if p.peek("(") {
+		for ; p.i < len(p.tokens) && p.tokens[p.i].text != ")"; p.i++ {
+			if p.tokens[p.i].is("full") {
+				stmt.Full = true
+			}
+		}
+		p.i++
+	}
+	for p.peek("full", "freeze", "verbose", "analyze", "analyse", "concurrently") {
+		if p.peek("full") {
+			stmt.Full = true
+		}
+		p.i++
+	}
+	// REINDEX {INDEX | TABLE | SCHEMA | DATABASE | SYSTEM} [CONCURRENTLY] name
+	if stmt.Verb == "reindex" {
+		if p.peek("table", "index", "schema", "database", "system") {
+			stmt.Object = strings.ToLower(p.tokens[p.i].text)
+			p.i++
+		}
+		p.accept("concurrently")
+		if stmt.Object != "table" {
+			return
+		}
+	}
+	stmt.Tables = p.names(true)
+}
+
+func (p *pgParser) parseCreate(stmt *PGStmt) {
+	if p.accept("or") {
+		p.accept("replace")
+	}
+	for p.peek("temporary", "temp", "unlogged", "global", "local", "unique", "materialized", "recursive", "constraint") {
+		switch {
+		case p.peek("temporary", "temp"):
+			stmt.Temporary = true
+		case p.peek("unique"):
+			stmt.Unique = true
+		case p.peek("materialized"):
+			stmt.Object = "materialized "
+		}
+		p.i++
+	}
+	if p.i >= len(p.tokens) {
+		return
+	}
+	stmt.Object += strings.ToLower(p.tokens[p.i].text)
+	p.i++
+
+	switch stmt.Object {
+	case "table":
+		stmt.IfNotExists = p.ifNotExists()
+		stmt.Tables = p.names(false)
+		stmt.Select = p.contains("as") && p.contains("select")
+	case "index":
+		stmt.Concurrently = p.accept("concurrently")
+		stmt.IfNotExists = p.ifNotExists()
+		if p.seek("on") {
+			p.accept("only")
+			stmt.Tables = p.names(false)
+		}
+	case "view", "materialized view":
+		stmt.IfNotExists = p.ifNotExists()
+		stmt.Tables = p.names(false)
+	case "trigger":
+		if p.seek("on") {
+			stmt.Tables = p.names(false)
+		}
+	case "role", "group":
+		stmt.Object = "user"
+	}
+}
+
+func (p *pgParser) parseDrop(stmt *PGStmt) {
+	if p.accept("materialized") {
+		stmt.Object = "materialized "
+	}
+	if p.i >= len(p.tokens) {
+		return
+	}
+	stmt.Object += strings.ToLower(p.tokens[p.i].text)
+	p.i++
+
+	switch stmt.Object {
+	case "table", "view", "materialized view":
+		stmt.IfExists = p.ifExists()
+		stmt.Tables = p.names(true)
+	case "index":
+		stmt.Concurrently = p.accept("concurrently")
+		stmt.IfExists = p.ifExists()
+	case "trigger":
+		stmt.IfExists = p.ifExists()
+		if p.seek("on") {
+			stmt.Tables = p.names(false)
+		}
+	case "schema", "database":
+		stmt.IfExists = p.ifExists()
+	case "role", "group":
+		stmt.Object = "user"
+	}
+}
+
+func (p *pgParser) parseAlter(stmt *PGStmt) {
+	if p.accept("materialized") {
+		stmt.Object = "materialized "
+	}
+	if p.i >= len(p.tokens) {
+		return
+	}
+	stmt.Object += strings.ToLower(p.tokens[p.i].text)
+	p.i++
+
+	switch stmt.Object {
+	case "system":
+		// ALTER SYSTEM SET name = value
+		stmt.System = true
+		if p.accept("set", "reset") {
+			p.parseSet(stmt)
+		}
+	case "role", "group":
+		stmt.Object = "user"
+	case "table":
+		stmt.IfExists = p.ifExists()
+		p.accept("only")
+		stmt.Tables = p.names(false)
+		stmt.AlterActions = p.alterActions()
+	}
+}
+
+// alterActions 按顶层逗号切分ALTER TABLE的子句
+func (p *pgParser) alterActions() []string {
+	actions := make([]string, 0, 1)
+	depth, start := 0, p.i
+	for j := p.i; j <= len(p.tokens); j++ {
+		if j < len(p.tokens) {
+			switch p.tokens[j].text {
+			case "(":
+				depth++
+				continue
+			case ")":
+				depth--
+				continue
+			}
+			if depth != 0 || p.tokens[j].text != "," || p.tokens[j].quoted {
+				continue
+			}
+		}
+		if j > start {
+			actions = append(actions, pgAlterAction(p.tokens[start:j]))
+		}
+		start = j + 1
+	}
+	return actions
+}
+
+func pgAlterAction(tokens []routineToken) string {
+	at := func(i int, keywords ...string) bool {
+		return i < len(tokens) && tokens[i].is(keywords...)
+	}
+
+	switch {
+	case at(0, "add"):
+		i := 1
+		if at(i, "constraint") {
+			i += 2
+		}
+		switch {
+		case at(i, "primary"):
+			return PGAlterAddPrimaryKey
+		case at(i, "unique"):
+			return PGAlterAddUnique
+		case at(i, "foreign", "check", "exclude"):
+			return PGAlterAddConstraint
+		}
+		return PGAlterAddColumn
+	case at(0, "drop"):
+		if at(1, "constraint") {
+			return PGAlterDropConstraint
+		}
+		return PGAlterDropColumn
+	case at(0, "alter"):
+		for _, t := range tokens {
+			if t.is("type") {
+				return PGAlterColumnType
+			}
+		}
+		return PGAlterColumn
+	case at(0, "rename"):
+		switch {
+		case at(1, "to"):
+			return PGAlterRenameTable
+		case at(1, "constraint"):
+			return PGAlterOther
+		}
+		return PGAlterRenameColumn
+	case at(0, "attach") && at(1, "partition"):
+		return PGAlterAttachPart
+	case at(0, "detach") && at(1, "partition"):
+		return PGAlterDetachPart
+	}
+	return PGAlterOther
+}
+
+// parseSet SET [SESSION | LOCAL] name {TO | =} value
+func (p *pgParser) parseSet(stmt *PGStmt) {
+	p.accept("session", "local")
+	if p.i >= len(p.tokens) {
+		return
+	}
+	stmt.SetName = strings.ToLower(p.tokens[p.i].text)
+	p.i++
+	p.accept("to", "=")
+	if p.i < len(p.tokens) {
+		stmt.SetValue = strings.ToLower(p.tokens[p.i].text)
+	}
+}
+
+// mainStatement WITH语句中CTE之后第一个顶层的 SELECT、INSERT、UPDATE、DELETE 的位置，以及CTE的名称
+func (p *pgParser) mainStatement() (int, []string) {
+	depth, ctes := 0, make([]string, 0, 1)
+	for j := p.i + 1; j < len(p.tokens); j++ {
+		switch {
+		case p.tokens[j].text == "(" && !p.tokens[j].quoted:
+			depth++
+		case p.tokens[j].text == ")" && !p.tokens[j].quoted:
+			depth--
+		case depth == 0 && p.tokens[j].is("select", "insert", "update", "delete"):
+			return j, ctes
+		case depth == 0 && p.tokens[j].is("as") && j > 0 && p.identifier(p.tokens[j-1]):
+			// name AS (...)
+			ctes = append(ctes, fmt.Sprintf("%s.%s", p.defaultSchema, pgIdentifier(p.tokens[j-1])))
+		case depth == 0 && p.tokens[j].is("as") && j > 0 && p.tokens[j-1].text == ")":
+			// name (col, ...) AS (...)
+			for k := j - 2; k > 0; k-- {
+				if p.tokens[k].text == "(" && !p.tokens[k].quoted {
+					ctes = append(ctes, fmt.Sprintf("%s.%s", p.defaultSchema, pgIdentifier(p.tokens[k-1])))
+					break
+				}
+			}
+		}
+	}
+	return len(p.tokens), ctes
+}
+
+// fromTables FROM、JOIN之后的表，包括子查询中的表
+func (p *pgParser) fromTables() []string {
+	tables := make([]string, 0, 1)
+	for j, t := range p.tokens {
+		if !t.is("from", "join") {
+			continue
+		}
+		q := &pgParser{tokens: p.tokens, i: j + 1, defaultSchema: p.defaultSchema}
+		q.accept("lateral")
+		q.accept("only")
+		// 函数调用不是表：FROM generate_series(1, 10)
+		if _, next := q.name(q.i); next < len(q.tokens) && q.tokens[next].text == "(" && !q.tokens[next].quoted {
+			continue
+		}
+		tables = append(tables, q.names(true)...)
+	}
+	return tables
+}
+
+// names 解析以逗号分隔的表名列表，list为false时只解析一个，跳过表的别名
+func (p *pgParser) names(list bool) []string {
+	names := make([]string, 0, 1)
+	for {
+		name, next := p.name(p.i)
+		if name == "" {
+			return names
+		}
+		names = append(names, name)
+		p.i = next
+		if !list {
+			return names
+		}
+
+		// [AS] alias
+		if p.accept("as") {
+			p.i++
+		} else if p.i < len(p.tokens) && p.identifier(p.tokens[p.i]) && !p.tokens[p.i].is(pgClauseKeywords...) {
+			p.i++
+		}
+		if !p.accept(",") {
+			return names
+		}
+		p.accept("only")
+	}
+}
+
+// name 解析 [schema.]name，未加引号的标识符转为小写
+func (p *pgParser) name(i int) (string, int) {
+	if i >= len(p.tokens) || !p.identifier(p.tokens[i]) || p.tokens[i].is(pgClauseKeywords...) {
+		return "", i
+	}
+	schema, name := p.defaultSchema, pgIdentifier(p.tokens[i])
+	i++
+	if i+1 < len(p.tokens) && p.tokens[i].text == "." && !p.tokens[i].quoted && p.identifier(p.tokens[i+1]) {
+		schema, name = name, pgIdentifier(p.tokens[i+1])
+		i += 2
+	}
+	return fmt.Sprintf("%s.%s", schema, name), i
+}
+
+func (p *pgParser) identifier(t routineToken) bool {
+	if t.quoted {
+		return t.quote == '"'
+	}
+	return t.text != "" && isWordByte(t.text[0]) && (t.text[0] < '0' || t.text[0] > '9') && t.text[0] != '$'
+}
+
+func pgIdentifier(t routineToken) string {
+	if t.quoted {
+		return t.text
+	}
+	return strings.ToLower(t.text)
+}
+
+func (p *pgParser) peek(keywords ...string) bool {
+	return p.i < len(p.tokens) && p.tokens[p.i].is(keywords...)
+}
+
+func (p *pgParser) accept(keywords ...string) bool {
+	if p.peek(keywords...) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+// seek 从当前位置向后查找关键字，找到时停在关键字之后
+func (p *pgParser) seek(keyword string) bool {
+	for j := p.i; j < len(p.tokens); j++ {
+		if p.tokens[j].is(keyword) {
+			p.i = j + 1
+			return true
+		}
+	}
+	return false
+}
+
+func (p *pgParser) contains(keyword string) bool {
+	for j := p.i; j < len(p.tokens); j++ {
+		if p.tokens[j].is(keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// topLevel 当前位置之后不在括号中的关键字
+func (p *pgParser) topLevel(keyword string) bool {
+	depth := 0
+	for j := p.i; j < len(p.tokens); j++ {
+		switch {
+		case p.tokens[j].text == "(" && !p.tokens[j].quoted:
+			depth++
+		case p.tokens[j].text == ")" && !p.tokens[j].quoted:
+			depth--
+		case depth == 0 && p.tokens[j].is(keyword):
+			return true
+		}
+	}
+	return false
+}
+
+func (p *pgParser) ifExists() bool {
+	if p.peek("if") && p.i+1 < len(p.tokens) && p.tokens[p.i+1].is("exists") {
+		p.i += 2
+		return true
+	}
+	return false
+}
+
+func (p *pgParser) ifNotExists() bool {
+	if p.peek("if") && p.i+2 < len(p.tokens) && p.tokens[p.i+1].is("not") && p.tokens[p.i+2].is("exists") {
+		p.i += 3
+		return true
+	}
+	return false
+}
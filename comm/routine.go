@@ -0,0 +1,253 @@
+package comm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tidb/parser不支持存储过程、函数、触发器和事件的DDL，这里通过分词识别这些语句的类型和操作对象
+
+const (
+	RoutineCreate = "create"
+	RoutineDrop   = "drop"
+	RoutineAlter  = "alter"
+
+	RoutineProcedure = "procedure"
+	RoutineFunction  = "function"
+	RoutineTrigger   = "trigger"
+	RoutineEvent     = "event"
+)
+
+// RoutineStmt 存储过程、函数、触发器、事件相关的DDL
+type RoutineStmt struct {
+	// 操作：create, drop, alter
+	Action string
+	// 对象类型：procedure, function, trigger, event
+	Type string
+	// 对象所在的库，未指定时为空
+	Schema string
+	// 对象名称
+	Name string
+	// DROP ... IF EXISTS
+	IfExists bool
+	// CREATE ... IF NOT EXISTS
+	IfNotExists bool
+	// 触发器所在表的库，未指定时为空
+	TableSchema string
+	// 触发器所在的表
+	Table string
+}
+
+// routineToken SQL分词结果
+type routineToken struct {
+	text string
+	// 是否为被引号包裹的标识符或字符串
+	quoted bool
+	// 包裹的引号
+	quote byte
+}
+
+// is 判断是否为指定的关键字（忽略大小写）
+func (t routineToken) is(keywords ...string) bool {
+	if t.quoted {
+		return false
+	}
+	for _, k := range keywords {
+		if strings.EqualFold(t.text, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseRoutine 识别CREATE/DROP/ALTER PROCEDURE、FUNCTION、TRIGGER、EVENT语句，不是此类语句时返回false
+func ParseRoutine(sql string) (*RoutineStmt, bool) {
+	tokens := tokenizeRoutine(RemoveSQLComments(sql), 64)
+	if len(tokens) < 3 {
+		return nil, false
+	}
+
+	stmt := &RoutineStmt{}
+	i := 0
+	switch {
+	case tokens[i].is(RoutineCreate):
+		stmt.Action = RoutineCreate
+	case tokens[i].is(RoutineDrop):
+		stmt.Action = RoutineDrop
+	case tokens[i].is(RoutineAlter):
+		stmt.Action = RoutineAlter
+	default:
+		return nil, false
+	}
+	i++
+
+	// CREATE [OR REPLACE] [DEFINER = user] [AGGREGATE] {PROCEDURE | FUNCTION | TRIGGER | EVENT}
+	if stmt.Action != RoutineDrop {
+		if i+1 < len(tokens) && tokens[i].is("or") && tokens[i+1].is("replace") {
+			i += 2
+		}
+		if i < len(tokens) && tokens[i].is("definer") {
+			i = skipDefiner(tokens, i+1)
+		}
+		if stmt.Action == RoutineCreate && i < len(tokens) && tokens[i].is("aggregate") {
+			i++
+		}
+	}
+	if i >= len(tokens) {
+		return nil, false
+	}
+
+	switch {
+	case tokens[i].is(RoutineProcedure):
+		stmt.Type = RoutineProcedure
+	case tokens[i].is(RoutineFunction):
+		stmt.Type = RoutineFunction
+	case tokens[i].is(RoutineTrigger):
+		// mysql 不支持 ALTER TRIGGER
+		if stmt.Action == RoutineAlter {
+			return nil, false
+		}
+		stmt.Type = RoutineTrigger
+	case tokens[i].is(RoutineEvent):
+		stmt.Type = RoutineEvent
+	default:
+		return nil, false
+	}
+	i++
+
+	// IF [NOT] EXISTS
+	if i+1 < len(tokens) && tokens[i].is("if") {
+		if tokens[i+1].is("exists") {
+			stmt.IfExists = true
+			i += 2
+		} else if i+2 < len(tokens) && tokens[i+1].is("not") && tokens[i+2].is("exists") {
+			stmt.IfNotExists = true
+			i += 3
+		}
+	}
+
+	stmt.Schema, stmt.Name, i = parseQualifiedName(tokens, i)
+	if stmt.Name == "" {
+		return nil, false
+	}
+
+	// CREATE TRIGGER name {BEFORE | AFTER} {INSERT | UPDATE | DELETE} ON tbl_name
+	if stmt.Type == RoutineTrigger && stmt.Action == RoutineCreate {
+		for ; i < len(tokens); i++ {
+			if tokens[i].is("on") {
+				stmt.TableSchema, stmt.Table, _ = parseQualifiedName(tokens, i+1)
+				break
+			}
+		}
+	}
+	return stmt, true
+}
+
+// Objects 返回语句操作的对象，格式同ExtractingTableName: 存储过程、函数、事件返回"库."，触发器额外返回所在的表"库.表"
+func (c *RoutineStmt) Objects(defaultDB string) []string {
+	schema := c.Schema
+	if schema == "" {
+		schema = defaultDB
+	}
+	objects := []string{fmt.Sprintf("%s.", schema)}
+
+	if c.Table != "" {
+		tableSchema := c.TableSchema
+		if tableSchema == "" {
+			tableSchema = schema
+		}
+		objects = append(objects, fmt.Sprintf("%s.%s", tableSchema, c.Table))
+	}
+	return objects
+}
+
+// skipDefiner 跳过 = user 部分，user 可以是 'u'@'h'、`u`@`h`、u@h、CURRENT_USER、CURRENT_USER()
+func skipDefiner(tokens []routineToken, i int) int {
+	if i < len(tokens) && tokens[i].text == "=" && !tokens[i].quoted {
+		i++
+	}
+	if i >= len(tokens) {
+		return i
+	}
+	if tokens[i].is("current_user") {
+		i++
+		if i+1 < len(tokens) && tokens[i].text == "(" && tokens[i+1].text == ")" {
+			i += 2
+		}
+		return i
+	}
+
+	i++
+	if i+1 < len(tokens) && tokens[i].text == "@" && !tokens[i].quoted {
+		i += 2
+	}
+	return i
+}
+
+// parseQualifiedName 解析 [schema.]name
+func parseQualifiedName(tokens []routineToken, i int) (string, string, int) {
+	if i >= len(tokens) || !isIdentifier(tokens[i]) {
+		return "", "", i
+	}
+	if i+2 < len(tokens) && tokens[i+1].text == "." && !tokens[i+1].quoted && isIdentifier(tokens[i+2]) {
+		return tokens[i].text, tokens[i+2].text, i + 3
+	}
+	return "", tokens[i].text, i + 1
+}
+
+func isIdentifier(t routineToken) bool {
+	if t.text == "" {
+		return false
+	}
+	return t.quoted || isWordByte(t.text[0])
+}
+
+// tokenizeRoutine 将SQL切分为单词、引号包裹的标识符/字符串和符号，最多返回max个
+func tokenizeRoutine(sql string, max int) []routineToken {
+	tokens := make([]routineToken, 0, 16)
+	for i := 0; i < len(sql) && len(tokens) < max; {
+		c := sql[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		case c == '`' || c == '\'' || c == '"':
+			j := i + 1
+			var b strings.Builder
+			for j < len(sql) {
+				if sql[j] == '\\' && c != '`' && j+1 < len(sql) {
+					b.WriteByte(sql[j+1])
+					j += 2
+					continue
+				}
+				if sql[j] == c {
+					// 两个连续的引号表示引号本身
+					if j+1 < len(sql) && sql[j+1] == c {
+						b.WriteByte(c)
+						j += 2
+						continue
+					}
+					break
+				}
+				b.WriteByte(sql[j])
+				j++
+			}
+			tokens = append(tokens, routineToken{text: b.String(), quoted: true, quote: c})
+			i = j + 1
+		case isWordByte(c):
+			j := i
+			for j < len(sql) && isWordByte(sql[j]) {
+				j++
+			}
+			tokens = append(tokens, routineToken{text: sql[i:j]})
+			i = j
+		default:
+			tokens = append(tokens, routineToken{text: sql[i : i+1]})
+			i++
+		}
+	}
+	return tokens
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
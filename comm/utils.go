@@ -135,6 +135,9 @@ func mapsEqual(map1, map2 interface{}) bool {
 	return true
 }
 
+// Decrypt 使用固定的AES密钥解密，密钥同时作为IV，仅用于兼容已有的密文。
+//
+// Deprecated: 新的密文请使用 EncryptAESGCM/DecryptAESGCM 并自行配置密钥
 func Decrypt(cryted string) string {
 	// 转成字节数组
 	crytedByte, _ := base64.StdEncoding.DecodeString(cryted)
@@ -0,0 +1,88 @@
+package comm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version 数据库版本号
+type Version struct {
+	Major int `json:"major"`
+	Minor int `json:"minor"`
+	Patch int `json:"patch"`
+}
+
+// ParseVersion 解析 SELECT VERSION() 的结果，如 8.0.32、5.7.40-log、5.7.25-TiDB-v6.5.0，只取前面的数字部分
+func ParseVersion(s string) (Version, error) {
+	v := Version{}
+	s = strings.TrimSpace(s)
+	if i := strings.IndexFunc(s, func(r rune) bool { return r != '.' && (r < '0' || r > '9') }); i >= 0 {
+		s = s[:i]
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) < 2 {
+		return v, fmt.Errorf("invalid version: %q", s)
+	}
+
+	nums := make([]int, 3)
+	for i := 0; i < len(parts) && i < len(nums); i++ {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return v, fmt.Errorf("invalid version: %q", s)
+		}
+		nums[i] = n
+	}
+	v.Major, v.Minor, v.Patch = nums[0], nums[1], nums[2]
+	return v, nil
+}
+
+// AtLeast 版本号是否大于等于 major.minor.patch
+func (v Version) AtLeast(major, minor, patch int) bool {
+	if v.Major != major {
+		return v.Major > major
+	}
+	if v.Minor != minor {
+		return v.Minor > minor
+	}
+	return v.Patch >= patch
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Int 版本号转换为整数便于比较，如8.0.32为80032
+func (v Version) Int() int {
+	return v.Major*10000 + v.Minor*100 + v.Patch
+}
+
+// 数据库分支
+const (
+	FlavorMySQL      = "MySQL"
+	FlavorMariaDB    = "MariaDB"
+	FlavorTiDB       = "TiDB"
+	FlavorAurora     = "Aurora"
+	FlavorTencentCDB = "TencentCDB"
+)
+
+// DetectFlavor 根据 SELECT VERSION() 的结果和全局变量识别数据库分支：
+// TiDB、MariaDB的版本号中带有分支名称，Aurora存在aurora_version变量，腾讯云CDB的version_comment中带有Tencent或TXSQL
+func DetectFlavor(version string, variables map[string]string) string {
+	lower := strings.ToLower(version)
+	switch {
+	case strings.Contains(lower, "tidb"):
+		return FlavorTiDB
+	case strings.Contains(lower, "mariadb"):
+		return FlavorMariaDB
+	case variables["aurora_version"] != "":
+		return FlavorAurora
+	}
+
+	comment := strings.ToLower(variables["version_comment"])
+	if strings.Contains(comment, "tencent") || strings.Contains(comment, "txsql") {
+		return FlavorTencentCDB
+	}
+	return FlavorMySQL
+}
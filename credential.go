@@ -0,0 +1,144 @@
+package sqlrisk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/sunkaimr/sql-risk/comm"
+)
+
+// Credential 连接数据源使用的账号密码
+type Credential struct {
+	User   string `json:"user"`
+	Passwd string `json:"passwd"`
+}
+
+// CredentialProvider 在连接数据源时解析账号密码，避免在SQLRisk、WorkRisk中长期持有明文密码。
+// user为工单中指定的用户，返回的User为空时沿用该用户
+type CredentialProvider interface {
+	Credential(addr, port, user string) (Credential, error)
+}
+
+// StaticCredential 固定的账号密码，未配置CredentialProvider时使用Passwd字段构造
+type StaticCredential struct {
+	User   string
+	Passwd string
+}
+
+func (c StaticCredential) Credential(_, _, user string) (Credential, error) {
+	if c.User != "" {
+		user = c.User
+	}
+	return Credential{User: user, Passwd: c.Passwd}, nil
+}
+
+// EnvCredential 从环境变量读取账号密码，UserEnv为空或对应环境变量为空时沿用工单中的用户
+type EnvCredential struct {
+	UserEnv   string
+	PasswdEnv string
+}
+
+func (c EnvCredential) Credential(_, _, user string) (Credential, error) {
+	if c.UserEnv != "" {
+		if u := os.Getenv(c.UserEnv); u != "" {
+			user = u
+		}
+	}
+	passwd, ok := os.LookupEnv(c.PasswdEnv)
+	if !ok {
+		return Credential{}, fmt.Errorf("environment variable %s not set", c.PasswdEnv)
+	}
+	return Credential{User: user, Passwd: passwd}, nil
+}
+
+// KeyFileCredential 从本地文件读取加密的账号密码，文件为json格式：
+//
+//	{"127.0.0.1:3306": {"user": "root", "passwd": "<comm.EncryptAESGCM的结果>"}}
+//
+// 优先匹配"user@addr:port"，其次匹配"addr:port"，都不存在时匹配"*"
+type KeyFileCredential struct {
+	Path string
+	// AES密钥，长度为16、24或32字节
+	Key []byte
+}
+
+func (c KeyFileCredential) Credential(addr, port, user string) (Credential, error) {
+	buf, err := os.ReadFile(c.Path)
+	if err != nil {
+		return Credential{}, fmt.Errorf("read key file failed, %s", err)
+	}
+
+	entries := make(map[string]Credential)
+	if err = json.Unmarshal(buf, &entries); err != nil {
+		return Credential{}, fmt.Errorf("unmarshal key file %s failed, %s", c.Path, err)
+	}
+
+	hostPort := fmt.Sprintf("%s:%s", addr, port)
+	var (
+		cred  Credential
+		found bool
+	)
+	for _, key := range []string{fmt.Sprintf("%s@%s", user, hostPort), hostPort, "*"} {
+		if cred, found = entries[key]; found {
+			break
+		}
+	}
+	if !found {
+		return Credential{}, fmt.Errorf("credential of %s not found in key file %s", hostPort, c.Path)
+	}
+
+	cred.Passwd, err = comm.DecryptAESGCM(c.Key, cred.Passwd)
+	if err != nil {
+		return Credential{}, fmt.Errorf("decrypt passwd of %s failed, %s", hostPort, err)
+	}
+	if cred.User == "" {
+		cred.User = user
+	}
+	return cred, nil
+}
+
+// ExecCredential 执行外部命令获取账号密码，数据源通过环境变量SQLRISK_ADDR、SQLRISK_PORT、SQLRISK_USER传入。
+// 命令的标准输出为json格式 {"user": "", "passwd": ""} 或者直接输出密码
+type ExecCredential struct {
+	Command string
+	Args    []string
+	// 命令执行超时时间，默认10s
+	Timeout time.Duration
+}
+
+func (c ExecCredential) Credential(addr, port, user string) (Credential, error) {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.Command, c.Args...)
+	cmd.Env = append(os.Environ(), "SQLRISK_ADDR="+addr, "SQLRISK_PORT="+port, "SQLRISK_USER="+user)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return Credential{}, fmt.Errorf("exec credential command %s failed, %s, %s", c.Command, err, strings.TrimSpace(stderr.String()))
+	}
+
+	out := bytes.TrimSpace(stdout.Bytes())
+	cred := Credential{}
+	if len(out) > 0 && out[0] == '{' {
+		if err := json.Unmarshal(out, &cred); err != nil {
+			return Credential{}, fmt.Errorf("unmarshal output of credential command %s failed, %s", c.Command, err)
+		}
+	} else {
+		cred.Passwd = string(out)
+	}
+	if cred.User == "" {
+		cred.User = user
+	}
+	return cred, nil
+}
@@ -0,0 +1,143 @@
+package sqlrisk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sunkaimr/sql-risk/comm"
+)
+
+func TestCredentialProvider(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	crypted, err := comm.EncryptAESGCM(key, "key-file-pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "credential.json")
+	content := fmt.Sprintf(`{"10.0.0.1:3306": {"user": "dba", "passwd": %q}, "root@10.0.0.2:3306": {"passwd": %q}}`, crypted, crypted)
+	if err = os.WriteFile(keyFile, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	script := filepath.Join(dir, "cred.sh")
+	if err = os.WriteFile(script, []byte("#!/bin/sh\necho \"{\\\"passwd\\\": \\\"$SQLRISK_ADDR-$SQLRISK_USER\\\"}\"\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("SQLRISK_TEST_PASSWD", "env-pass")
+
+	tests := []struct {
+		name     string
+		provider CredentialProvider
+		addr     string
+		user     string
+		expect   Credential
+		err      bool
+	}{
+		{
+			name:     "test001",
+			provider: StaticCredential{Passwd: "static-pass"},
+			addr:     "10.0.0.1",
+			user:     "root",
+			expect:   Credential{User: "root", Passwd: "static-pass"},
+		},
+		{
+			name:     "test002",
+			provider: EnvCredential{PasswdEnv: "SQLRISK_TEST_PASSWD"},
+			addr:     "10.0.0.1",
+			user:     "root",
+			expect:   Credential{User: "root", Passwd: "env-pass"},
+		},
+		{
+			name:     "test003",
+			provider: EnvCredential{PasswdEnv: "SQLRISK_TEST_NOT_SET"},
+			addr:     "10.0.0.1",
+			user:     "root",
+			err:      true,
+		},
+		{
+			name:     "test004",
+			provider: KeyFileCredential{Path: keyFile, Key: key},
+			addr:     "10.0.0.1",
+			user:     "root",
+			expect:   Credential{User: "dba", Passwd: "key-file-pass"},
+		},
+		{
+			name:     "test005",
+			provider: KeyFileCredential{Path: keyFile, Key: key},
+			addr:     "10.0.0.2",
+			user:     "root",
+			expect:   Credential{User: "root", Passwd: "key-file-pass"},
+		},
+		{
+			name:     "test006",
+			provider: KeyFileCredential{Path: keyFile, Key: key},
+			addr:     "10.0.0.3",
+			user:     "root",
+			err:      true,
+		},
+		{
+			name:     "test007",
+			provider: KeyFileCredential{Path: keyFile, Key: []byte("fedcba9876543210fedcba9876543210")},
+			addr:     "10.0.0.1",
+			user:     "root",
+			err:      true,
+		},
+		{
+			name:     "test008",
+			provider: ExecCredential{Command: script},
+			addr:     "10.0.0.1",
+			user:     "root",
+			expect:   Credential{User: "root", Passwd: "10.0.0.1-root"},
+		},
+		{
+			name:     "test009",
+			provider: ExecCredential{Command: "false"},
+			addr:     "10.0.0.1",
+			user:     "root",
+			err:      true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cred, err := test.provider.Credential(test.addr, "3306", test.user)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expect error but got %+v", cred)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cred != test.expect {
+				t.Fatalf("expect %+v but got %+v", test.expect, cred)
+			}
+		})
+	}
+}
+
+func TestSQLRiskCredential(t *testing.T) {
+	r := NewSqlRisk("", "10.0.0.1", "", "3306", "root", "plain", "test", "select 1", nil)
+	cred, err := r.primary().credential(r.cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cred.Passwd != "plain" {
+		t.Fatalf("expect passwd from Passwd field but got %s", cred.Passwd)
+	}
+
+	r = NewSqlRisk("", "10.0.0.2", "", "3306", "root", "plain", "test", "select 1", nil)
+	r.Credential = StaticCredential{User: "dba", Passwd: "provider"}
+	cred, err = r.primary().credential(r.cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cred.User != "dba" || cred.Passwd != "provider" {
+		t.Fatalf("expect credential from provider but got %+v", cred)
+	}
+}
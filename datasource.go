@@ -0,0 +1,159 @@
+package sqlrisk
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Endpoint 数据源的一个连接地址
+type Endpoint struct {
+	Addr   string `json:"addr"`
+	Port   string `json:"port"`
+	User   string `json:"user"`
+	Passwd string `json:"-"`
+	// 未配置时使用Passwd
+	Credential CredentialProvider `json:"-"`
+}
+
+// DataSource 一个数据库集群：Addr为集群的vip，ReadWriteAddr为读写库的地址（用于查询监控），
+// Replicas为只读库，查询表元数据、COUNT(*)等较重的查询优先发往只读库
+type DataSource struct {
+	ID            string     `json:"id"`
+	Primary       Endpoint   `json:"primary"`
+	ReadWriteAddr string     `json:"read_write_addr"`
+	Replicas      []Endpoint `json:"replicas"`
+	// 数据库类型，为空时根据数据库版本识别
+	Backend BackendType `json:"backend"`
+	// 监控类别，对应MetricsTemplate.Class，为空时依次尝试所有模板
+	MetricsClass string `json:"metrics_class"`
+}
+
+// DataSourceRegistry 以数据源ID索引的数据源注册表
+type DataSourceRegistry struct {
+	mu      sync.RWMutex
+	sources map[string]*DataSource
+}
+
+var defaultRegistry = NewDataSourceRegistry()
+
+func NewDataSourceRegistry() *DataSourceRegistry {
+	return &DataSourceRegistry{sources: make(map[string]*DataSource)}
+}
+
+// Register 注册数据源，ID相同时覆盖
+func (r *DataSourceRegistry) Register(ds *DataSource) error {
+	if ds == nil || ds.ID == "" {
+		return fmt.Errorf("datasource id is null")
+	}
+	if ds.Primary.Addr == "" || ds.Primary.Port == "" {
+		return fmt.Errorf("primary addr or port of datasource %s is null", ds.ID)
+	}
+	for i, replica := range ds.Replicas {
+		if replica.Addr == "" || replica.Port == "" {
+			return fmt.Errorf("addr or port of replica %d of datasource %s is null", i, ds.ID)
+		}
+	}
+	switch ds.Backend {
+	case "", MySQLBackend, TiDBBackend, PostgresBackend:
+	default:
+		return fmt.Errorf("unsupported backend %s of datasource %s", ds.Backend, ds.ID)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sources[ds.ID] = ds
+	return nil
+}
+
+// Get 根据ID获取数据源
+func (r *DataSourceRegistry) Get(id string) (*DataSource, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ds, ok := r.sources[id]
+	if !ok {
+		return nil, fmt.Errorf("datasource %s not registered", id)
+	}
+	return ds, nil
+}
+
+// Remove 删除数据源
+func (r *DataSourceRegistry) Remove(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.sources, id)
+}
+
+// RegisterDataSource 向默认注册表注册数据源
+func RegisterDataSource(ds *DataSource) error {
+	return defaultRegistry.Register(ds)
+}
+
+// GetDataSource 从默认注册表获取数据源
+func GetDataSource(id string) (*DataSource, error) {
+	return defaultRegistry.Get(id)
+}
+
+// RemoveDataSource 从默认注册表删除数据源
+func RemoveDataSource(id string) {
+	defaultRegistry.Remove(id)
+}
+
+// connect 通过CredentialProvider解析账号密码后连接，同一个地址只解析一次
+func (e Endpoint) connect(cache *MemoryCache, database string) (*Connector, error) {
+	cred, err := e.credential(cache)
+	if err != nil {
+		return nil, err
+	}
+	return NewConnector(NewDSN(e.Addr, e.Port, cred.User, cred.Passwd, database))
+}
+
+func (e Endpoint) credential(cache *MemoryCache) (Credential, error) {
+	key := strings.Join([]string{"Credential", e.Addr, e.Port, e.User}, "|")
+	if v, ok := cache.Get(key); ok {
+		return v.(Credential), nil
+	}
+
+	var provider CredentialProvider = StaticCredential{Passwd: e.Passwd}
+	if e.Credential != nil {
+		provider = e.Credential
+	}
+	cred, err := provider.Credential(e.Addr, e.Port, e.User)
+	if err != nil {
+		return Credential{}, fmt.Errorf("resolve credential of %s:%s failed, %s", e.Addr, e.Port, err)
+	}
+
+	cache.Set(key, cred, 0)
+	return cred, nil
+}
+
+// primary SQL所在数据源的主库（vip）地址
+func (c *SQLRisk) primary() Endpoint {
+	return Endpoint{Addr: c.Addr, Port: c.Port, User: c.User, Passwd: c.Passwd, Credential: c.Credential}
+}
+
+// connect 连接主库，用于需要读取实时状态的查询，如事务、版本
+func (c *SQLRisk) connect(database string) (*Connector, error) {
+	return c.primary().connect(c.cache, database)
+}
+
+// connectReplica 依次尝试连接只读库，都不可用时回退到主库，用于表元数据、COUNT(*)等较重的查询
+func (c *SQLRisk) connectReplica(database string) (*Connector, error) {
+	for _, replica := range c.Replicas {
+		key := strings.Join([]string{"ReplicaDown", replica.Addr, replica.Port}, "|")
+		if _, down := c.cache.Get(key); down {
+			continue
+		}
+
+		conn, err := replica.connect(c.cache, database)
+		if err == nil {
+			if err = conn.Conn.Ping(); err == nil {
+				return conn, nil
+			}
+			_ = conn.Close()
+		}
+		// 同一个工单内不再尝试不可用的只读库
+		c.cache.Set(key, err.Error(), 0)
+	}
+	return c.connect(database)
+}
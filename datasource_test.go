@@ -0,0 +1,112 @@
+package sqlrisk
+
+import (
+	"testing"
+)
+
+func TestDataSourceRegistry(t *testing.T) {
+	tests := []struct {
+		name string
+		ds   *DataSource
+		err  bool
+	}{
+		{
+			name: "test001",
+			ds:   &DataSource{ID: "ds1", Primary: Endpoint{Addr: "10.0.0.1", Port: "3306", User: "root"}},
+		},
+		{
+			name: "test002",
+			ds:   &DataSource{Primary: Endpoint{Addr: "10.0.0.1", Port: "3306"}},
+			err:  true,
+		},
+		{
+			name: "test003",
+			ds:   &DataSource{ID: "ds3", Primary: Endpoint{Addr: "10.0.0.1"}},
+			err:  true,
+		},
+		{
+			name: "test004",
+			ds: &DataSource{ID: "ds4", Primary: Endpoint{Addr: "10.0.0.1", Port: "3306"},
+				Replicas: []Endpoint{{Addr: "10.0.0.2"}}},
+			err: true,
+		},
+	}
+
+	r := NewDataSourceRegistry()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := r.Register(test.ds)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expect error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			ds, err := r.Get(test.ds.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ds != test.ds {
+				t.Fatalf("expect %+v but got %+v", test.ds, ds)
+			}
+		})
+	}
+
+	r.Remove("ds1")
+	if _, err := r.Get("ds1"); err == nil {
+		t.Fatalf("expect error after remove but got nil")
+	}
+}
+
+func TestNewWorkRiskWithDataSource(t *testing.T) {
+	ds := &DataSource{
+		ID:            "test-ds",
+		Primary:       Endpoint{Addr: "10.0.0.1", Port: "3306", User: "dba", Passwd: "pass"},
+		ReadWriteAddr: "10.0.0.11",
+		Replicas:      []Endpoint{{Addr: "10.0.0.2", Port: "3306", User: "ro"}},
+	}
+	if err := RegisterDataSource(ds); err != nil {
+		t.Fatal(err)
+	}
+	defer RemoveDataSource(ds.ID)
+
+	w, err := NewWorkRisk("1", ds.ID, "test", "select 1;", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Addr != "10.0.0.1" || w.Port != "3306" || w.User != "dba" || w.ReadWriteAddr != "10.0.0.11" || len(w.Replicas) != 1 {
+		t.Fatalf("unexpected work risk %+v", w)
+	}
+
+	if err = w.SplitStatement(); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.SQLRisks) != 1 || w.SQLRisks[0].DataSourceID != ds.ID || len(w.SQLRisks[0].Replicas) != 1 {
+		t.Fatalf("datasource not passed to sql risk")
+	}
+
+	if _, err = NewWorkRisk("2", "not-exist", "test", "select 1;", nil); err == nil {
+		t.Fatalf("expect error for unregistered datasource but got nil")
+	}
+}
+
+func TestConnectReplicaFallback(t *testing.T) {
+	r := NewSqlRisk("", "127.0.0.1", "", "3306", "root", "", "test", "select 1", nil)
+	// 端口1上没有mysql，只读库不可用时应回退到主库
+	r.Replicas = []Endpoint{{Addr: "127.0.0.1", Port: "1", User: "root"}}
+
+	conn, err := r.connectReplica("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if conn.Addr != "127.0.0.1:3306" {
+		t.Fatalf("expect fallback to primary but got %s", conn.Addr)
+	}
+	if _, ok := r.cache.Get("ReplicaDown|127.0.0.1|1"); !ok {
+		t.Fatalf("expect replica marked down")
+	}
+}
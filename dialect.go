@@ -0,0 +1,107 @@
+package sqlrisk
+
+import (
+	"fmt"
+)
+
+// Dialect 与数据库类型相关的表元数据、执行计划、事务和锁的查询。
+// database对MySQL为库名，对PostgreSQL为schema
+type Dialect interface {
+	TableExist(database, table string) (bool, error)
+	// TableSize 表大小，单位MB
+	TableSize(database, table string) (int, error)
+	// TableRows 表行数，来自统计信息
+	TableRows(database, table string) (int, error)
+	// ExplainRows 执行计划中预估的最大扫描行数
+	ExplainRows(sql string) (int64, error)
+	// Transactions 正在运行的事务
+	Transactions() ([]TrxResult, error)
+	// LockSessions 在表上持有或等待锁的其他会话
+	LockSessions(database, table string) ([]LockSession, error)
+	Close() error
+}
+
+// mysqlDialect MySQL、TiDB的实现，表大小、行数和事务的查询方式由Backend区分
+type mysqlDialect struct {
+	conn    *Connector
+	backend Backend
+}
+
+func (d mysqlDialect) TableExist(database, table string) (bool, error) {
+	return d.conn.TableExist(database, table)
+}
+
+func (d mysqlDialect) TableSize(database, table string) (int, error) {
+	return d.backend.TableSize(d.conn, database, table)
+}
+
+func (d mysqlDialect) TableRows(database, table string) (int, error) {
+	return d.backend.TableRows(d.conn, database, table)
+}
+
+func (d mysqlDialect) ExplainRows(sql string) (int64, error) {
+	explain, err := d.conn.Explain(sql)
+	if err != nil {
+		return 0, err
+	}
+
+	var rows int64
+	for _, v := range explain.ExplainRows {
+		if v.Rows > rows {
+			rows = v.Rows
+		}
+	}
+	return rows, nil
+}
+
+func (d mysqlDialect) Transactions() ([]TrxResult, error) {
+	return d.backend.Transactions(d.conn)
+}
+
+// LockSessions 未开启mdl监控时，以访问过表的未提交的空闲事务近似代替元数据锁的持有者
+func (d mysqlDialect) LockSessions(database, table string) ([]LockSession, error) {
+	sessions, enabled, err := d.conn.MetadataLocks(database, table)
+	if err != nil {
+		return nil, fmt.Errorf("query metadata locks failed, %s", err)
+	}
+
+	data, err := d.conn.DataLocks(database, table)
+	if err != nil {
+		return nil, fmt.Errorf("query data locks failed, %s", err)
+	}
+	sessions = append(sessions, data...)
+
+	if !enabled {
+		idle, err := d.conn.IdleTransactions(database, table)
+		if err != nil {
+			return nil, fmt.Errorf("query idle transactions failed, %s", err)
+		}
+		sessions = append(sessions, idle...)
+	}
+	return sessions, nil
+}
+
+func (d mysqlDialect) Close() error {
+	return d.conn.Close()
+}
+
+// dialect 连接数据源，replica为true时MySQL优先连接只读库
+func (c *SQLRisk) dialect(database string, replica bool) (Dialect, error) {
+	if c.postgres() {
+		conn, err := c.connectPostgres()
+		if err != nil {
+			return nil, err
+		}
+		return conn, nil
+	}
+
+	connect := c.connect
+	if replica {
+		connect = c.connectReplica
+	}
+	conn, err := connect(database)
+	if err != nil {
+		return nil, fmt.Errorf("new mysql connect failed, %s", err)
+	}
+	return mysqlDialect{conn: conn, backend: GetBackend(c.backendType())}, nil
+}
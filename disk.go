@@ -0,0 +1,147 @@
+package sqlrisk
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sunkaimr/sql-risk/comm"
+	"github.com/sunkaimr/sql-risk/policy"
+)
+
+const (
+	// diskTrendWindow 拟合磁盘增长趋势使用的历史数据时长和采样间隔
+	diskTrendWindow = 24 * time.Hour
+	diskTrendStep   = 10 * time.Minute
+
+	// ddlIndexSizeRatio 新建二级索引的大小按表大小的1/ddlIndexSizeRatio估算
+	ddlIndexSizeRatio = 4
+)
+
+// DiskGrowthRate 以最小二乘法拟合磁盘剩余空间的变化，返回每小时消耗的空间(MB)，剩余空间在增加时返回0
+func DiskGrowthRate(points []MetricPoint) float64 {
+	if len(points) < 2 {
+		return 0
+	}
+
+	n := float64(len(points))
+	var sumX, sumY, sumXY, sumXX float64
+	for _, p := range points {
+		x := p.Time.Sub(points[0].Time).Hours()
+		sumX += x
+		sumY += p.Value
+		sumXY += x * p.Value
+		sumXX += x * x
+	}
+	d := n*sumXX - sumX*sumX
+	if d == 0 {
+		return 0
+	}
+
+	slope := (n*sumXY - sumX*sumY) / d
+	if slope >= 0 {
+		return 0
+	}
+	return -slope
+}
+
+// RequiredDiskMB SQL执行过程中需要的磁盘空间(MB)：
+// DML的binlog和undo按预估的binlog大小各算一份；COPY算法的DDL需要临时表，按表大小（含索引）的2倍计算，
+// INPLACE重建表需要写一份新的表空间，按表大小计算；INPLACE添加索引需要写新索引和排序的临时文件，
+// 新索引按表大小的1/ddlIndexSizeRatio估算，排序文件与索引大小相当；未能预测DDL执行方式时按重建表计算
+func (c *SQLRisk) RequiredDiskMB() int {
+	operate, _ := c.GetItemValueWithOperateType(policy.Operate.ID)
+	switch operate {
+	case policy.Operate.V.DML:
+		binlog, _ := c.GetItemValueWithInt(policy.EstimatedBinlogMB.ID)
+		return binlog * 2
+	case policy.Operate.V.DDL:
+		tabSize, _ := c.GetItemValueWithInt(policy.TabSize.ID)
+		algorithm, err := c.GetItemValueWithString(policy.DDLAlgorithm.ID)
+		rebuild, _ := c.GetItemValueWithBool(policy.DDLRebuild.ID)
+		switch {
+		case algorithm == DDLAlgorithmCopy:
+			return tabSize * 2
+		case rebuild:
+			return tabSize
+		case err != nil:
+			return tabSize
+		case algorithm == DDLAlgorithmInplace:
+			return c.newIndexes() * tabSize / ddlIndexSizeRatio * 2
+		}
+	}
+	return 0
+}
+
+// newIndexes DDL新建的二级索引个数
+func (c *SQLRisk) newIndexes() int {
+	indexes := []policy.KeyWordType{policy.KeyWord.V.CreateIdx, policy.KeyWord.V.CreateUniIdx, policy.KeyWord.V.AlertAddIdx, policy.KeyWord.V.AlertAddUniIdx}
+	if keywords, ok := c.GetItemValue(policy.KeyWords.ID).([]policy.KeyWordType); ok {
+		n := 0
+		for _, kw := range keywords {
+			if comm.EleExist(kw, indexes) {
+				n++
+			}
+		}
+		return n
+	}
+	if c.HasKeyWord(indexes...) {
+		return 1
+	}
+	return 0
+}
+
+// CollectProjectedFreeDisk 预测执行后的磁盘剩余空间百分比：
+// 剩余空间 - SQL需要的空间 - 每小时的增长 × (预测时长 + DDL耗时)，未采集剩余空间（未配置监控）时跳过，
+// 监控不可用时只记录错误，不影响其他风险项的识别
+func (c *SQLRisk) CollectProjectedFreeDisk() error {
+	if _, err := c.GetItemValueWithInt(policy.FreeDisk.ID); err != nil {
+		return nil
+	}
+	start := time.Now()
+	pct, err := c.projectedFreeDiskPct()
+	if err != nil {
+		c.SetItemError(policy.ProjectedFreeDiskPct.Name, err)
+		return nil
+	}
+	c.SetItemValue(policy.ProjectedFreeDiskPct.Name, policy.ProjectedFreeDiskPct.ID, pct, int(time.Now().Sub(start).Milliseconds()))
+	return nil
+}
+
+func (c *SQLRisk) projectedFreeDiskPct() (int, error) {
+	freeDisk, err := c.GetItemValueWithInt(policy.FreeDisk.ID)
+	if err != nil {
+		return 0, fmt.Errorf("attempt to query FreeDisk for collecting ProjectedFreeDiskPct failed, %s", err)
+	}
+
+	now := time.Now()
+	metrics := NewMetricsProvider(c.Config.Runtime)
+	total, err := metrics.DiskTotal(c.metricsTarget(), now)
+	if err != nil {
+		return 0, fmt.Errorf("query disk total failed, %s", err)
+	}
+	if total <= 0 {
+		return 0, fmt.Errorf("invalid disk total %v", total)
+	}
+
+	projected := float64(freeDisk) - float64(c.RequiredDiskMB()) - c.diskConsumedMB(metrics, now)
+	if projected < 0 {
+		projected = 0
+	}
+	return int(projected * 100 / total), nil
+}
+
+// diskConsumedMB 按磁盘的增长趋势预测 预测时长 + DDL耗时 内消耗的空间(MB)，无法查询历史时不考虑增长趋势
+func (c *SQLRisk) diskConsumedMB(metrics MetricsProvider, now time.Time) float64 {
+	trend, ok := metrics.(DiskTrendProvider)
+	if !ok || c.Config.RiskConfig.DiskForecastHours <= 0 {
+		return 0
+	}
+	points, err := trend.DiskFreeRange(c.metricsTarget(), now.Add(-diskTrendWindow), now, diskTrendStep)
+	if err != nil {
+		return 0
+	}
+
+	duration, _ := c.GetItemValueWithInt(policy.DDLDuration.ID)
+	hours := float64(c.Config.RiskConfig.DiskForecastHours) + float64(duration)/3600
+	return DiskGrowthRate(points) * hours
+}
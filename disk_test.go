@@ -0,0 +1,170 @@
+package sqlrisk
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/sunkaimr/sql-risk/policy"
+)
+
+func TestDiskGrowthRate(t *testing.T) {
+	now := time.Now()
+	series := func(values ...float64) []MetricPoint {
+		points := make([]MetricPoint, 0, len(values))
+		for i, v := range values {
+			points = append(points, MetricPoint{Time: now.Add(time.Duration(i) * time.Hour), Value: v})
+		}
+		return points
+	}
+
+	tests := []struct {
+		name   string
+		points []MetricPoint
+		want   float64
+	}{
+		{"test001", series(1000, 900, 800, 700), 100},
+		{"test002", series(1000, 1010, 1020), 0},
+		{"test003", series(1000), 0},
+		{"test004", series(1000, 950, 910, 850), 49},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := DiskGrowthRate(test.points); math.Abs(got-test.want) > 0.01 {
+				t.Fatalf("DiskGrowthRate got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCollectProjectedFreeDisk(t *testing.T) {
+	now := time.Now()
+	history := []MetricPoint{{Time: now.Add(-2 * time.Hour), Value: 10240}, {Time: now.Add(-time.Hour), Value: 10140}, {Time: now, Value: 10040}}
+
+	tests := []struct {
+		name    string
+		items   map[string]any
+		hours   int
+		history []MetricPoint
+		want    int
+	}{
+		// 10040 - 2×1024 = 7992，占总空间102400的7%
+		{"test001", map[string]any{policy.Operate.ID: policy.Operate.V.DML, policy.EstimatedBinlogMB.ID: 1024}, 0, nil, 7},
+		// COPY算法：10040 - 2×4096 = 1848
+		{"test002", map[string]any{policy.Operate.ID: policy.Operate.V.DDL, policy.TabSize.ID: 4096, policy.DDLAlgorithm.ID: DDLAlgorithmCopy}, 0, nil, 1},
+		// INPLACE不重建表不需要额外空间，每小时消耗100MB，预测24小时+DDL耗时1小时：10040 - 2500 = 7540
+		{"test003", map[string]any{policy.Operate.ID: policy.Operate.V.DDL, policy.TabSize.ID: 4096, policy.DDLAlgorithm.ID: DDLAlgorithmInplace,
+			policy.DDLRebuild.ID: false, policy.DDLDuration.ID: 3600}, 24, history, 7},
+		// 剩余空间不足时为0
+		{"test004", map[string]any{policy.Operate.ID: policy.Operate.V.DDL, policy.TabSize.ID: 40960, policy.DDLRebuild.ID: true}, 0, nil, 0},
+		{"test005", map[string]any{policy.Operate.ID: policy.Operate.V.DQL}, 24, history, 7},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := &SQLRisk{Config: &Config{
+				Runtime:    MetricsConfig{Static: &StaticMetricsProvider{Disk: 10040, DiskTotalMB: 102400, DiskHistory: test.history}},
+				RiskConfig: RiskConfig{DiskForecastHours: test.hours},
+			}}
+			c.SetItemValue(policy.FreeDisk.Name, policy.FreeDisk.ID, 10040, 0)
+			for id, v := range test.items {
+				c.SetItemValue(id, id, v, 0)
+			}
+
+			if err := c.CollectProjectedFreeDisk(); err != nil {
+				t.Fatalf("CollectProjectedFreeDisk failed, got error: %s", err)
+			}
+			got, err := c.GetItemValueWithInt(policy.ProjectedFreeDiskPct.ID)
+			if err != nil || got != test.want {
+				t.Fatalf("ProjectedFreeDiskPct got %d, %v, want %d", got, err, test.want)
+			}
+		})
+	}
+
+	// 未采集剩余空间时跳过，不记录错误
+	c := &SQLRisk{Config: newDefaultConfig()}
+	if err := c.CollectProjectedFreeDisk(); err != nil || len(c.Errors) != 0 {
+		t.Fatalf("CollectProjectedFreeDisk without FreeDisk got %v, errors: %+v", err, c.Errors)
+	}
+
+	// 监控不可用时只记录错误
+	c = &SQLRisk{Config: &Config{Runtime: MetricsConfig{Provider: FallbackMetricsProvider{errMetrics{}}}}}
+	c.SetItemValue(policy.FreeDisk.Name, policy.FreeDisk.ID, 10040, 0)
+	if err := c.CollectProjectedFreeDisk(); err != nil || len(c.Errors) != 1 {
+		t.Fatalf("CollectProjectedFreeDisk with unavailable metrics got %v, errors: %+v", err, c.Errors)
+	}
+}
+
+func TestCollectDiskSufficient(t *testing.T) {
+	now := time.Now()
+	history := []MetricPoint{{Time: now.Add(-2 * time.Hour), Value: 10240}, {Time: now.Add(-time.Hour), Value: 10140}, {Time: now, Value: 10040}}
+
+	tests := []struct {
+		name    string
+		items   map[string]any
+		hours   int
+		history []MetricPoint
+		want    bool
+	}{
+		// binlog和undo共需要2×1024
+		{"test001", map[string]any{policy.Operate.ID: policy.Operate.V.DML, policy.EstimatedBinlogMB.ID: 1024}, 0, nil, true},
+		{"test002", map[string]any{policy.Operate.ID: policy.Operate.V.DML, policy.EstimatedBinlogMB.ID: 6000}, 0, nil, false},
+		// COPY算法需要2×6000，大于剩余空间10040
+		{"test003", map[string]any{policy.Operate.ID: policy.Operate.V.DDL, policy.TabSize.ID: 6000, policy.DDLAlgorithm.ID: DDLAlgorithmCopy}, 0, nil, false},
+		// 查询不需要额外的空间，与表大小无关
+		{"test004", map[string]any{policy.Operate.ID: policy.Operate.V.DQL, policy.TabSize.ID: 20000}, 0, nil, true},
+		// 重建表需要9000，每小时消耗100MB，预测24小时+DDL耗时1小时后剩余7540
+		{"test005", map[string]any{policy.Operate.ID: policy.Operate.V.DDL, policy.TabSize.ID: 9000, policy.DDLRebuild.ID: true}, 0, history, true},
+		{"test006", map[string]any{policy.Operate.ID: policy.Operate.V.DDL, policy.TabSize.ID: 9000, policy.DDLRebuild.ID: true,
+			policy.DDLDuration.ID: 3600}, 24, history, false},
+		// INPLACE添加索引需要新索引和排序文件：2×40000/4 = 20000，大于剩余空间10040
+		{"test007", map[string]any{policy.Operate.ID: policy.Operate.V.DDL, policy.KeyWord.ID: policy.KeyWord.V.AlertAddIdx, policy.TabSize.ID: 40000,
+			policy.DDLAlgorithm.ID: DDLAlgorithmInplace, policy.DDLRebuild.ID: false}, 0, nil, false},
+		{"test008", map[string]any{policy.Operate.ID: policy.Operate.V.DDL, policy.KeyWord.ID: policy.KeyWord.V.CreateIdx, policy.TabSize.ID: 12000,
+			policy.DDLAlgorithm.ID: DDLAlgorithmInplace, policy.DDLRebuild.ID: false}, 0, nil, true},
+		// 删除索引只修改元数据
+		{"test009", map[string]any{policy.Operate.ID: policy.Operate.V.DDL, policy.KeyWord.ID: policy.KeyWord.V.AlertDropIdx, policy.TabSize.ID: 40000,
+			policy.DDLAlgorithm.ID: DDLAlgorithmInplace, policy.DDLRebuild.ID: false}, 0, nil, true},
+		// 未能预测DDL执行方式时按重建表需要表大小的空间
+		{"test010", map[string]any{policy.Operate.ID: policy.Operate.V.DDL, policy.TabSize.ID: 12000}, 0, nil, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := &SQLRisk{Config: &Config{
+				Runtime:    MetricsConfig{Static: &StaticMetricsProvider{Disk: 10040, DiskTotalMB: 102400, DiskHistory: test.history}},
+				RiskConfig: RiskConfig{DiskForecastHours: test.hours},
+			}}
+			c.SetItemValue(policy.FreeDisk.Name, policy.FreeDisk.ID, 10040, 0)
+			for id, v := range test.items {
+				c.SetItemValue(id, id, v, 0)
+			}
+
+			got, err := c.CollectDiskSufficient()
+			if err != nil || got != test.want {
+				t.Fatalf("CollectDiskSufficient got %v, %v, want %v", got, err, test.want)
+			}
+		})
+	}
+}
+
+func TestCollectDiskValues(t *testing.T) {
+	// 未配置监控时跳过磁盘相关的评估项，不按0处理
+	c := &SQLRisk{Config: &Config{}}
+	if err := c.CollectDiskValues(); err != nil {
+		t.Fatalf("CollectDiskValues without metrics failed, got error: %s", err)
+	}
+	if _, err := c.GetItemValueWithInt(policy.FreeDisk.ID); err == nil {
+		t.Fatalf("FreeDisk without metrics expect unset")
+	}
+	if _, err := c.GetItemValueWithBool(policy.DiskSufficient.ID); err == nil || len(c.Errors) != 0 {
+		t.Fatalf("DiskSufficient without metrics expect unset, errors: %+v", c.Errors)
+	}
+
+	// 监控查询失败时返回错误
+	c = &SQLRisk{Config: &Config{Runtime: MetricsConfig{Provider: FallbackMetricsProvider{errMetrics{}}}}}
+	if disk, err := c.CollectFreeDisk(); err == nil {
+		t.Fatalf("CollectFreeDisk expect error but got %d", disk)
+	}
+	if cpu, err := c.CollectCpuUsage(); err == nil {
+		t.Fatalf("CollectCpuUsage expect error but got %d", cpu)
+	}
+}
@@ -7,6 +7,7 @@ require (
 	github.com/antonmedv/expr v1.12.7
 	github.com/go-sql-driver/mysql v1.7.1
 	github.com/json-iterator/go v1.1.12
+	github.com/lib/pq v1.10.9
 	github.com/pingcap/tidb v0.0.0-20220923141543-ecd67531f172
 	github.com/pingcap/tidb/parser v0.0.0-20220923141543-ecd67531f172
 	github.com/tidwall/gjson v1.14.4
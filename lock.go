@@ -0,0 +1,243 @@
+package sqlrisk
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 会话持有或等待的锁的来源
+const (
+	LockSourceMetadata = "metadata_lock"
+	LockSourceData     = "data_lock"
+	LockSourceIdleTrx  = "idle_trx"
+	// 未开启语句历史，无法判断空闲事务是否访问过表
+	LockSourceIdleTrxUnknown = "idle_trx_unknown"
+	// PostgreSQL pg_locks中的表级锁
+	LockSourceRelation = "relation_lock"
+)
+
+// LockSession 在表上持有或等待锁的会话
+type LockSession struct {
+	// processlist ID，可用于KILL
+	ID   int64  `json:"id"`
+	User string `json:"user"`
+	Host string `json:"host"`
+	DB   string `json:"db"`
+	// processlist中的Command，空闲事务为Sleep
+	Command string `json:"command"`
+	State   string `json:"state"`
+	// 持续时间，会话在事务中时为事务持续时间，单位s
+	Time  int    `json:"time"`
+	Query string `json:"query"`
+	// 锁类型，元数据锁如SHARED_READ、EXCLUSIVE，InnoDB锁如RECORD X
+	LockType string `json:"lock_type"`
+	// GRANTED, PENDING, WAITING
+	LockStatus string `json:"lock_status"`
+	Source     string `json:"source"`
+}
+
+// 与DML需要的SHARED_WRITE元数据锁冲突的锁类型
+var dmlConflictMDL = []string{"EXCLUSIVE", "SHARED_NO_WRITE", "SHARED_NO_READ_WRITE", "SHARED_READ_ONLY"}
+
+// 与DML需要的ROW EXCLUSIVE锁冲突的PostgreSQL表级锁，DDL需要的ACCESS EXCLUSIVE锁与所有表级锁冲突
+var pgDMLConflictModes = []string{"ShareLock", "ShareRowExclusiveLock", "ExclusiveLock", "AccessExclusiveLock"}
+
+// MetadataLocks 查询其他会话在表上持有或等待的元数据锁，enabled为false表示未开启mdl监控，此时无法判断
+func (db *Connector) MetadataLocks(database, table string) (sessions []LockSession, enabled bool, err error) {
+	res, err := db.Query("SELECT ENABLED FROM performance_schema.setup_instruments WHERE NAME = 'wait/lock/metadata/sql/mdl'")
+	if err != nil {
+		return nil, false, fmt.Errorf("exec sql query failed, %s", err)
+	}
+	status := ""
+	for res.Rows.Next() {
+		err = res.Rows.Scan(&status)
+		if err != nil {
+			return nil, false, fmt.Errorf("scan rows failed, %s", err)
+		}
+	}
+	err = res.Rows.Close()
+	if err != nil {
+		return nil, false, fmt.Errorf("close scan rows failed, %s", err)
+	}
+	if status != "YES" {
+		return nil, false, nil
+	}
+
+	sqlQuery := fmt.Sprintf("SELECT "+
+		"t.PROCESSLIST_ID, coalesce(t.PROCESSLIST_USER, ''), coalesce(t.PROCESSLIST_HOST, ''), coalesce(t.PROCESSLIST_DB, ''), "+
+		"coalesce(t.PROCESSLIST_COMMAND, ''), coalesce(t.PROCESSLIST_STATE, ''), "+
+		"coalesce(TIMESTAMPDIFF(SECOND, x.trx_started, NOW()), t.PROCESSLIST_TIME, 0), coalesce(t.PROCESSLIST_INFO, ''), "+
+		"ml.LOCK_TYPE, ml.LOCK_STATUS "+
+		"FROM performance_schema.metadata_locks ml "+
+		"JOIN performance_schema.threads t ON ml.OWNER_THREAD_ID = t.THREAD_ID "+
+		"LEFT JOIN information_schema.INNODB_TRX x ON x.trx_mysql_thread_id = t.PROCESSLIST_ID "+
+		"WHERE ml.OBJECT_TYPE = 'TABLE' AND ml.OBJECT_SCHEMA = '%s' AND ml.OBJECT_NAME = '%s' "+
+		"AND t.PROCESSLIST_ID <> CONNECTION_ID()", database, table)
+	sessions, err = db.queryLockSessions(sqlQuery, LockSourceMetadata)
+	return sessions, true, err
+}
+
+// DataLocks 查询其他事务在表上持有或等待的InnoDB锁，8.0使用performance_schema.data_locks，
+// 5.7使用information_schema.INNODB_LOCKS（只包含存在锁等待的锁）
+func (db *Connector) DataLocks(database, table string) ([]LockSession, error) {
+	sqlQuery := fmt.Sprintf("SELECT "+
+		"x.trx_mysql_thread_id, coalesce(p.USER, ''), coalesce(p.HOST, ''), coalesce(p.DB, ''), "+
+		"coalesce(p.COMMAND, ''), coalesce(p.STATE, ''), "+
+		"TIMESTAMPDIFF(SECOND, x.trx_started, NOW()), coalesce(x.trx_query, ''), "+
+		"concat(dl.LOCK_TYPE, ' ', dl.LOCK_MODE), dl.LOCK_STATUS "+
+		"FROM performance_schema.data_locks dl "+
+		"JOIN information_schema.INNODB_TRX x ON dl.ENGINE_TRANSACTION_ID = x.trx_id "+
+		"LEFT JOIN information_schema.PROCESSLIST p ON p.ID = x.trx_mysql_thread_id "+
+		"WHERE dl.OBJECT_SCHEMA = '%s' AND dl.OBJECT_NAME = '%s' "+
+		"AND x.trx_mysql_thread_id <> CONNECTION_ID()", database, table)
+	sessions, err := db.queryLockSessions(sqlQuery, LockSourceData)
+	// 只有表不存在（5.7）时回退，其他错误直接返回
+	if err == nil || !tableNotExist(err) {
+		return sessions, err
+	}
+
+	sqlQuery = fmt.Sprintf("SELECT "+
+		"x.trx_mysql_thread_id, coalesce(p.USER, ''), coalesce(p.HOST, ''), coalesce(p.DB, ''), "+
+		"coalesce(p.COMMAND, ''), coalesce(p.STATE, ''), "+
+		"TIMESTAMPDIFF(SECOND, x.trx_started, NOW()), coalesce(x.trx_query, ''), "+
+		"concat(l.lock_type, ' ', l.lock_mode), IF(x.trx_requested_lock_id = l.lock_id, 'WAITING', 'GRANTED') "+
+		"FROM information_schema.INNODB_LOCKS l "+
+		"JOIN information_schema.INNODB_TRX x ON l.lock_trx_id = x.trx_id "+
+		"LEFT JOIN information_schema.PROCESSLIST p ON p.ID = x.trx_mysql_thread_id "+
+		"WHERE l.lock_table = '`%s`.`%s`' "+
+		"AND x.trx_mysql_thread_id <> CONNECTION_ID()", database, table)
+	return db.queryLockSessions(sqlQuery, LockSourceData)
+}
+
+// IdleTransactions 查询处于空闲状态（Sleep）但事务未提交、且最近执行的语句访问过表的会话，这类会话不执行SQL，
+// 但仍持有事务中访问过的表的锁。通过performance_schema.events_statements_history判断访问过的表（每个线程只保留最近的语句），
+// 未开启语句历史时无法判断，返回所有空闲事务并标记为LockSourceIdleTrxUnknown
+func (db *Connector) IdleTransactions(database, table string) ([]LockSession, error) {
+	res, err := db.Query("SELECT ENABLED FROM performance_schema.setup_consumers WHERE NAME = 'events_statements_history'")
+	if err != nil {
+		return nil, fmt.Errorf("exec sql query failed, %s", err)
+	}
+	status := ""
+	for res.Rows.Next() {
+		err = res.Rows.Scan(&status)
+		if err != nil {
+			return nil, fmt.Errorf("scan rows failed, %s", err)
+		}
+	}
+	err = res.Rows.Close()
+	if err != nil {
+		return nil, fmt.Errorf("close scan rows failed, %s", err)
+	}
+
+	sqlQuery := "SELECT " +
+		"x.trx_mysql_thread_id, coalesce(p.USER, ''), coalesce(p.HOST, ''), coalesce(p.DB, ''), " +
+		"coalesce(p.COMMAND, ''), coalesce(p.STATE, ''), " +
+		"TIMESTAMPDIFF(SECOND, x.trx_started, NOW()), coalesce(x.trx_query, ''), " +
+		"'TRANSACTION', x.trx_state " +
+		"FROM information_schema.INNODB_TRX x " +
+		"JOIN information_schema.PROCESSLIST p ON p.ID = x.trx_mysql_thread_id " +
+		"WHERE p.COMMAND = 'Sleep' AND x.trx_mysql_thread_id <> CONNECTION_ID()"
+	if status != "YES" {
+		return db.queryLockSessions(sqlQuery, LockSourceIdleTrxUnknown)
+	}
+
+	sqlQuery += fmt.Sprintf(" AND EXISTS (SELECT 1 FROM performance_schema.events_statements_history h "+
+		"JOIN performance_schema.threads t ON h.THREAD_ID = t.THREAD_ID "+
+		"WHERE t.PROCESSLIST_ID = x.trx_mysql_thread_id AND h.SQL_TEXT LIKE '%%%s%%' "+
+		"AND (h.CURRENT_SCHEMA = '%s' OR h.SQL_TEXT LIKE '%%%s%%'))", table, database, database)
+	return db.queryLockSessions(sqlQuery, LockSourceIdleTrx)
+}
+
+// tableNotExist 查询的表不存在（Error 1146）
+func tableNotExist(err error) bool {
+	return strings.Contains(err.Error(), "1146") || strings.Contains(err.Error(), "doesn't exist")
+}
+
+func (db *Connector) queryLockSessions(sqlQuery, source string) ([]LockSession, error) {
+	res, err := db.Query(sqlQuery)
+	if err != nil {
+		return nil, fmt.Errorf("exec sql query failed, %s", err)
+	}
+
+	sessions := make([]LockSession, 0, 1)
+	for res.Rows.Next() {
+		s := LockSession{Source: source}
+		err = res.Rows.Scan(&s.ID, &s.User, &s.Host, &s.DB, &s.Command, &s.State, &s.Time, &s.Query, &s.LockType, &s.LockStatus)
+		if err != nil {
+			return nil, fmt.Errorf("scan rows failed, %s", err)
+		}
+		sessions = append(sessions, s)
+	}
+	err = res.Rows.Close()
+	if err != nil {
+		return nil, fmt.Errorf("close scan rows failed, %s", err)
+	}
+	return sessions, nil
+}
+
+// BlockingSessions 从表上持有或等待锁的会话中筛选出会阻塞本条SQL的会话，同一个会话只保留一次，
+// 无法判断是否访问过表的空闲事务不算作阻塞，见UnknownSessions：
+// DDL需要排他的元数据锁，表上任何其他会话持有或排在前面等待的元数据锁都会使DDL排队，
+// 排队期间DDL又会阻塞该表上所有新的读写；DML只与排他类的元数据锁冲突，InnoDB行锁无法判断是否与本条SQL
+// 访问的行重叠，只有表上已有事务在等待行锁时才认为存在锁竞争
+func BlockingSessions(ddl bool, sessions []LockSession) []LockSession {
+	blocking := make([]LockSession, 0, 1)
+	seen := make(map[int64]bool, len(sessions))
+	for _, s := range sessions {
+		if seen[s.ID] {
+			continue
+		}
+
+		switch s.Source {
+		case LockSourceMetadata:
+			if !ddl && !containsFold(dmlConflictMDL, s.LockType) {
+				continue
+			}
+		case LockSourceData:
+			// 持有InnoDB锁的事务同时持有元数据锁，会阻塞DDL；表级意向锁与DML不冲突，
+			// 已授予的行锁通常与本条SQL访问的行不重叠
+			if !ddl && (strings.HasPrefix(s.LockType, "TABLE I") || !strings.EqualFold(s.LockStatus, "WAITING")) {
+				continue
+			}
+		case LockSourceRelation:
+			if !ddl && !containsFold(pgDMLConflictModes, s.LockType) {
+				continue
+			}
+		case LockSourceIdleTrx:
+			// 空闲事务持有的行锁已包含在InnoDB锁中，只有DDL需要等待其持有的元数据锁
+			if !ddl {
+				continue
+			}
+		case LockSourceIdleTrxUnknown:
+			continue
+		}
+		seen[s.ID] = true
+		blocking = append(blocking, s)
+	}
+	return blocking
+}
+
+// UnknownSessions DDL可能需要等待的空闲事务：未开启语句历史，无法判断是否访问过表，不计入阻塞的会话
+func UnknownSessions(ddl bool, sessions []LockSession) []LockSession {
+	unknown := make([]LockSession, 0)
+	if !ddl {
+		return unknown
+	}
+	seen := make(map[int64]bool, len(sessions))
+	for _, s := range sessions {
+		if s.Source == LockSourceIdleTrxUnknown && !seen[s.ID] {
+			seen[s.ID] = true
+			unknown = append(unknown, s)
+		}
+	}
+	return unknown
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
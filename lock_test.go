@@ -0,0 +1,151 @@
+package sqlrisk
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var lockSessionRows = []string{"id", "user", "host", "db", "command", "state", "time", "query", "lock_type", "lock_status"}
+
+func TestMetadataLocks(t *testing.T) {
+	conn, mock, err := mockDBConn("test")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer conn.Close()
+
+	// 未开启mdl监控
+	mock.ExpectExec("USE `test`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("^SELECT ENABLED FROM performance_schema.setup_instruments").WillReturnRows(mock.NewRows([]string{"ENABLED"}).AddRow("NO"))
+	_, enabled, err := conn.MetadataLocks("test", "student")
+	if err != nil {
+		t.Fatalf("MetadataLocks failed, got error: %s", err)
+	}
+	if enabled {
+		t.Fatalf("MetadataLocks got enabled, want disabled")
+	}
+
+	mock.ExpectExec("USE `test`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("^SELECT ENABLED FROM performance_schema.setup_instruments").WillReturnRows(mock.NewRows([]string{"ENABLED"}).AddRow("YES"))
+	mock.ExpectExec("USE `test`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM performance_schema.metadata_locks").WillReturnRows(mock.NewRows(lockSessionRows).
+		AddRow(12, "app", "10.0.0.5:5123", "test", "Sleep", "", 900, "", "SHARED_WRITE", "GRANTED"))
+	sessions, enabled, err := conn.MetadataLocks("test", "student")
+	if err != nil {
+		t.Fatalf("MetadataLocks failed, got error: %s", err)
+	}
+	want := []LockSession{{ID: 12, User: "app", Host: "10.0.0.5:5123", DB: "test", Command: "Sleep", Time: 900,
+		LockType: "SHARED_WRITE", LockStatus: "GRANTED", Source: LockSourceMetadata}}
+	if !enabled || !reflect.DeepEqual(sessions, want) {
+		t.Fatalf("MetadataLocks got: %+v, want: %+v", sessions, want)
+	}
+}
+
+func TestDataLocks(t *testing.T) {
+	conn, mock, err := mockDBConn("test")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer conn.Close()
+
+	// 5.7没有performance_schema.data_locks，回退到INNODB_LOCKS
+	mock.ExpectExec("USE `test`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM performance_schema.data_locks").WillReturnError(fmt.Errorf("table doesn't exist"))
+	mock.ExpectExec("USE `test`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM information_schema.INNODB_LOCKS").WillReturnRows(mock.NewRows(lockSessionRows).
+		AddRow(15, "app", "10.0.0.6:6123", "test", "Query", "updating", 30, "update student set a = 1", "RECORD X", "GRANTED"))
+	sessions, err := conn.DataLocks("test", "student")
+	if err != nil {
+		t.Fatalf("DataLocks failed, got error: %s", err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != 15 || sessions[0].Source != LockSourceData {
+		t.Fatalf("DataLocks got: %+v", sessions)
+	}
+
+	// 其他错误不回退，避免INNODB_LOCKS不存在的错误覆盖真正的错误
+	mock.ExpectExec("USE `test`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM performance_schema.data_locks").WillReturnError(fmt.Errorf("Error 1142: SELECT command denied"))
+	_, err = conn.DataLocks("test", "student")
+	if err == nil || !strings.Contains(err.Error(), "1142") {
+		t.Fatalf("DataLocks got error: %v, want permission denied", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIdleTransactions(t *testing.T) {
+	conn, mock, err := mockDBConn("test")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer conn.Close()
+
+	// 开启语句历史时只返回访问过表的空闲事务
+	mock.ExpectExec("USE `test`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM performance_schema.setup_consumers").WillReturnRows(mock.NewRows([]string{"ENABLED"}).AddRow("YES"))
+	mock.ExpectExec("USE `test`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM information_schema.INNODB_TRX x .*events_statements_history h .* LIKE '%student%'").
+		WillReturnRows(mock.NewRows(lockSessionRows).AddRow(21, "app", "10.0.0.7:7123", "test", "Sleep", "", 600, "", "TRANSACTION", "RUNNING"))
+	sessions, err := conn.IdleTransactions("test", "student")
+	if err != nil {
+		t.Fatalf("IdleTransactions failed, got error: %s", err)
+	}
+	if len(sessions) != 1 || sessions[0].Source != LockSourceIdleTrx {
+		t.Fatalf("IdleTransactions got: %+v", sessions)
+	}
+
+	// 未开启语句历史时无法判断
+	mock.ExpectExec("USE `test`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM performance_schema.setup_consumers").WillReturnRows(mock.NewRows([]string{"ENABLED"}).AddRow("NO"))
+	mock.ExpectExec("USE `test`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM information_schema.INNODB_TRX x").
+		WillReturnRows(mock.NewRows(lockSessionRows).AddRow(22, "app", "10.0.0.7:7124", "other", "Sleep", "", 600, "", "TRANSACTION", "RUNNING"))
+	sessions, err = conn.IdleTransactions("test", "student")
+	if err != nil {
+		t.Fatalf("IdleTransactions failed, got error: %s", err)
+	}
+	if len(sessions) != 1 || sessions[0].Source != LockSourceIdleTrxUnknown {
+		t.Fatalf("IdleTransactions got: %+v", sessions)
+	}
+}
+
+func TestBlockingSessions(t *testing.T) {
+	sessions := []LockSession{
+		{ID: 1, LockType: "SHARED_READ", LockStatus: "GRANTED", Source: LockSourceMetadata},
+		{ID: 2, LockType: "EXCLUSIVE", LockStatus: "PENDING", Source: LockSourceMetadata},
+		{ID: 3, LockType: "TABLE IX", LockStatus: "GRANTED", Source: LockSourceData},
+		{ID: 4, LockType: "RECORD X", LockStatus: "GRANTED", Source: LockSourceData},
+		{ID: 4, LockType: "TABLE IX", LockStatus: "GRANTED", Source: LockSourceData},
+		{ID: 5, LockType: "TRANSACTION", LockStatus: "RUNNING", Source: LockSourceIdleTrx},
+		{ID: 6, LockType: "TRANSACTION", LockStatus: "RUNNING", Source: LockSourceIdleTrxUnknown},
+		{ID: 7, LockType: "RECORD X,REC_NOT_GAP", LockStatus: "WAITING", Source: LockSourceData},
+	}
+
+	tests := []struct {
+		name string
+		ddl  bool
+		want []int64
+	}{
+		{"test001", true, []int64{1, 2, 3, 4, 5, 7}},
+		{"test002", false, []int64{2, 7}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ids := make([]int64, 0)
+			for _, s := range BlockingSessions(test.ddl, sessions) {
+				ids = append(ids, s.ID)
+			}
+			if !reflect.DeepEqual(ids, test.want) {
+				t.Fatalf("BlockingSessions(%v) got %v, want %v", test.ddl, ids, test.want)
+			}
+			if unknown := UnknownSessions(test.ddl, sessions); test.ddl && (len(unknown) != 1 || unknown[0].ID != 6) || !test.ddl && len(unknown) != 0 {
+				t.Fatalf("UnknownSessions(%v) got %+v", test.ddl, unknown)
+			}
+		})
+	}
+}
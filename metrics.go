@@ -0,0 +1,453 @@
+package sqlrisk
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MetricsTarget 查询监控的数据源
+type MetricsTarget struct {
+	Addr string
+	Port string
+	// 监控类别，为空时依次尝试所有模板
+	Class string
+	// Connect 连接数据源，从数据库自身估算监控值时使用
+	Connect func() (*Connector, error)
+}
+
+// MetricsProvider 查询数据源所在主机的监控
+type MetricsProvider interface {
+	// CpuUsage CPU使用率
+	CpuUsage(target MetricsTarget, t time.Time) (float64, error)
+	// DiskFree 磁盘剩余空间(MB)
+	DiskFree(target MetricsTarget, t time.Time) (float64, error)
+	// DiskTotal 磁盘的总大小(MB)
+	DiskTotal(target MetricsTarget, t time.Time) (float64, error)
+}
+
+// MetricPoint 区间查询的一个采样点
+type MetricPoint struct {
+	Time  time.Time
+	Value float64
+}
+
+// DiskTrendProvider 能查询磁盘剩余空间历史的监控，用于拟合磁盘增长趋势
+type DiskTrendProvider interface {
+	DiskFreeRange(target MetricsTarget, start, end time.Time, step time.Duration) ([]MetricPoint, error)
+}
+
+// LoadMetric 评估业务负载的指标
+type LoadMetric string
+
+const (
+	LoadMetricCpu    LoadMetric = "cpu"
+	LoadMetricQPS    LoadMetric = "qps"
+	LoadMetricDiskIO LoadMetric = "disk_io"
+)
+
+// LoadMetrics 评估历史负载时查询的所有指标
+var LoadMetrics = []LoadMetric{LoadMetricCpu, LoadMetricQPS, LoadMetricDiskIO}
+
+// LoadHistoryProvider 能查询负载历史的监控，用于统计业务高峰期和推荐执行窗口
+type LoadHistoryProvider interface {
+	LoadRange(target MetricsTarget, metric LoadMetric, start, end time.Time, step time.Duration) ([]MetricPoint, error)
+}
+
+// MetricsConfig 监控配置
+type MetricsConfig struct {
+	// Prometheus/Thanos的地址，为空时不查询Prometheus，如 http://thanos-realtime.xxx.com
+	Url string `json:"url"`
+	// PromQL模板，为空时使用DefaultMetricsTemplates
+	Templates []MetricsTemplate `json:"templates,omitempty"`
+	// 单次查询的超时时间（秒），为0时使用DefaultQueryTimeout
+	Timeout int `json:"timeout"`
+	// 认证信息不随配置保存，BearerToken优先
+	BearerToken string `json:"-"`
+	Username    string `json:"-"`
+	Password    string `json:"-"`
+	// 跳过证书校验，自签名证书时使用
+	InsecureSkipVerify bool `json:"insecure_skip_verify"`
+	// 自定义TLS配置，如客户端证书，优先于InsecureSkipVerify
+	TLSConfig *tls.Config `json:"-"`
+	// 从MySQL自身估算时的磁盘容量(MB)和CPU核数，未配置时不估算
+	DiskCapacity int `json:"disk_capacity"`
+	CpuCores     int `json:"cpu_cores"`
+	// 固定的监控值，配置后不再查询Prometheus和MySQL，用于测试
+	Static *StaticMetricsProvider `json:"static,omitempty"`
+	// 自定义的监控查询，优先级最高
+	Provider MetricsProvider `json:"-"`
+}
+
+// NewMetricsProvider 按配置创建监控查询，依次查询Prometheus、MySQL自身估算，都失败时返回所有错误
+func NewMetricsProvider(config MetricsConfig) MetricsProvider {
+	if config.Provider != nil {
+		return config.Provider
+	}
+	if config.Static != nil {
+		return config.Static
+	}
+
+	providers := make(FallbackMetricsProvider, 0, 2)
+	if config.Url != "" {
+		templates := config.Templates
+		if len(templates) == 0 {
+			templates = DefaultMetricsTemplates
+		}
+		providers = append(providers, &PrometheusMetricsProvider{Client: config.client(), Templates: templates})
+	}
+	if config.DiskCapacity > 0 || config.CpuCores > 0 {
+		providers = append(providers, MySQLMetricsProvider{DiskCapacity: config.DiskCapacity, CpuCores: config.CpuCores})
+	}
+	return providers
+}
+
+// configured 是否配置了任何监控，未配置时依赖监控的评估项不采集，也不记录错误
+func (config MetricsConfig) configured() bool {
+	return config.Provider != nil || config.Static != nil || config.Url != "" || config.DiskCapacity > 0 || config.CpuCores > 0
+}
+
+func (config MetricsConfig) client() *Client {
+	c := NewClientWithTLS(config.Url, config.TLSConfig)
+	if config.TLSConfig == nil && config.InsecureSkipVerify {
+		c.Transport = insecureTransport
+	}
+	if config.Timeout > 0 {
+		c.Timeout = time.Duration(config.Timeout) * time.Second
+	}
+	c.BearerToken = config.BearerToken
+	c.Username = config.Username
+	c.Password = config.Password
+	return c
+}
+
+// PrometheusMetricsProvider 按数据源的监控类别选择PromQL模板查询Prometheus
+type PrometheusMetricsProvider struct {
+	Client    *Client
+	Templates []MetricsTemplate
+}
+
+func (p *PrometheusMetricsProvider) CpuUsage(target MetricsTarget, t time.Time) (float64, error) {
+	return p.query(target, t, func(m MetricsTemplate) []string { return m.CpuUsage })
+}
+
+func (p *PrometheusMetricsProvider) DiskFree(target MetricsTarget, t time.Time) (float64, error) {
+	return p.query(target, t, func(m MetricsTemplate) []string { return m.DiskFree })
+}
+
+func (p *PrometheusMetricsProvider) DiskTotal(target MetricsTarget, t time.Time) (float64, error) {
+	return p.query(target, t, func(m MetricsTemplate) []string { return m.DiskTotal })
+}
+
+// DiskFreeRange 依次查询DiskFree模板，返回第一个有数据的序列
+func (p *PrometheusMetricsProvider) DiskFreeRange(target MetricsTarget, start, end time.Time, step time.Duration) ([]MetricPoint, error) {
+	return p.queryRange(target, start, end, step, func(m MetricsTemplate) []string { return m.DiskFree })
+}
+
+// LoadRange 依次查询指标对应的模板，返回第一个有数据的序列
+func (p *PrometheusMetricsProvider) LoadRange(target MetricsTarget, metric LoadMetric, start, end time.Time, step time.Duration) ([]MetricPoint, error) {
+	var fn func(MetricsTemplate) []string
+	switch metric {
+	case LoadMetricCpu:
+		fn = func(m MetricsTemplate) []string { return m.CpuUsage }
+	case LoadMetricQPS:
+		fn = func(m MetricsTemplate) []string { return m.QPS }
+	case LoadMetricDiskIO:
+		fn = func(m MetricsTemplate) []string { return m.DiskIO }
+	default:
+		return nil, fmt.Errorf("unsupported load metric %q", metric)
+	}
+	return p.queryRange(target, start, end, step, fn)
+}
+
+func (p *PrometheusMetricsProvider) queryRange(target MetricsTarget, start, end time.Time, step time.Duration, metric func(MetricsTemplate) []string) ([]MetricPoint, error) {
+	pql := expandTemplates(p.Templates, target.Class, target, metric)
+	if len(pql) == 0 {
+		return nil, fmt.Errorf("no metrics template for class %q", target.Class)
+	}
+
+	err := NoDataPointError
+	for _, q := range pql {
+		var data *MatrixData
+		data, err = p.Client.QueryRange(q, start, end, strconv.Itoa(int(step.Seconds())))
+		if err != nil {
+			err = fmt.Errorf("query_range(%s) failed %w", q, err)
+			continue
+		}
+		var points []MetricPoint
+		points, err = parseMatrix(data)
+		if err == nil {
+			return points, nil
+		}
+	}
+	return nil, err
+}
+
+func (p *PrometheusMetricsProvider) query(target MetricsTarget, t time.Time, metric func(MetricsTemplate) []string) (float64, error) {
+	pql := expandTemplates(p.Templates, target.Class, target, metric)
+	if len(pql) == 0 {
+		return 0, fmt.Errorf("no metrics template for class %q", target.Class)
+	}
+	return p.Client.GeneralQuery(t, pql...)
+}
+
+// expandTemplates 展开指定类别的模板，class为空时展开所有模板
+func expandTemplates(templates []MetricsTemplate, class string, target MetricsTarget, metric func(MetricsTemplate) []string) []string {
+	pql := make([]string, 0, len(templates))
+	for _, m := range templates {
+		if class != "" && m.Class != class {
+			continue
+		}
+		for _, tmpl := range metric(m) {
+			pql = append(pql, os.Expand(tmpl, func(key string) string {
+				switch key {
+				case "ip":
+					return target.Addr
+				case "port":
+					return target.Port
+				}
+				return "$" + key
+			}))
+		}
+	}
+	return pql
+}
+
+// MySQLMetricsProvider 没有Prometheus监控时从MySQL自身估算。
+// MySQL无法查询所在主机的文件系统，剩余空间为配置的磁盘容量减去数据目录（表、binlog）的大小；
+// CPU使用率以 Threads_running 占CPU核数的比例近似
+type MySQLMetricsProvider struct {
+	// 磁盘容量(MB)
+	DiskCapacity int
+	CpuCores     int
+}
+
+func (p MySQLMetricsProvider) CpuUsage(target MetricsTarget, _ time.Time) (float64, error) {
+	if p.CpuCores <= 0 {
+		return 0, fmt.Errorf("cpu cores is not configured")
+	}
+	conn, err := p.connect(target)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	running, err := conn.GlobalStatus("Threads_running")
+	if err != nil {
+		return 0, err
+	}
+	usage := running * 100 / float64(p.CpuCores)
+	if usage > 100 {
+		usage = 100
+	}
+	return usage, nil
+}
+
+func (p MySQLMetricsProvider) DiskFree(target MetricsTarget, _ time.Time) (float64, error) {
+	if p.DiskCapacity <= 0 {
+		return 0, fmt.Errorf("disk capacity is not configured")
+	}
+	conn, err := p.connect(target)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	used, err := conn.DataDirUsed()
+	if err != nil {
+		return 0, err
+	}
+	return float64(p.DiskCapacity) - used, nil
+}
+
+func (p MySQLMetricsProvider) DiskTotal(MetricsTarget, time.Time) (float64, error) {
+	if p.DiskCapacity <= 0 {
+		return 0, fmt.Errorf("disk capacity is not configured")
+	}
+	return float64(p.DiskCapacity), nil
+}
+
+func (p MySQLMetricsProvider) connect(target MetricsTarget) (*Connector, error) {
+	if target.Connect == nil {
+		return nil, fmt.Errorf("no connection to %s", target.Addr)
+	}
+	conn, err := target.Connect()
+	if err != nil {
+		return nil, fmt.Errorf("new mysql connect failed, %s", err)
+	}
+	return conn, nil
+}
+
+// StaticMetricsProvider 返回固定的监控值，用于测试或没有监控的环境
+type StaticMetricsProvider struct {
+	Cpu  float64 `json:"cpu"`
+	Disk float64 `json:"disk"`
+	// 磁盘的总大小(MB)
+	DiskTotalMB float64 `json:"disk_total"`
+	// 磁盘剩余空间的历史，用于测试磁盘增长趋势
+	DiskHistory []MetricPoint `json:"-"`
+	// 各负载指标的历史，用于测试业务高峰期和执行窗口推荐
+	LoadHistory map[LoadMetric][]MetricPoint `json:"-"`
+}
+
+func (p *StaticMetricsProvider) CpuUsage(MetricsTarget, time.Time) (float64, error) {
+	return p.Cpu, nil
+}
+
+func (p *StaticMetricsProvider) DiskFree(MetricsTarget, time.Time) (float64, error) {
+	return p.Disk, nil
+}
+
+func (p *StaticMetricsProvider) DiskTotal(MetricsTarget, time.Time) (float64, error) {
+	return p.DiskTotalMB, nil
+}
+
+func (p *StaticMetricsProvider) DiskFreeRange(MetricsTarget, time.Time, time.Time, time.Duration) ([]MetricPoint, error) {
+	if len(p.DiskHistory) == 0 {
+		return nil, NoDataPointError
+	}
+	return p.DiskHistory, nil
+}
+
+func (p *StaticMetricsProvider) LoadRange(_ MetricsTarget, metric LoadMetric, _, _ time.Time, _ time.Duration) ([]MetricPoint, error) {
+	if len(p.LoadHistory[metric]) == 0 {
+		return nil, NoDataPointError
+	}
+	return p.LoadHistory[metric], nil
+}
+
+// FallbackMetricsProvider 依次查询，返回第一个成功的结果
+type FallbackMetricsProvider []MetricsProvider
+
+func (p FallbackMetricsProvider) CpuUsage(target MetricsTarget, t time.Time) (float64, error) {
+	return p.query(func(m MetricsProvider) (float64, error) { return m.CpuUsage(target, t) })
+}
+
+func (p FallbackMetricsProvider) DiskFree(target MetricsTarget, t time.Time) (float64, error) {
+	return p.query(func(m MetricsProvider) (float64, error) { return m.DiskFree(target, t) })
+}
+
+func (p FallbackMetricsProvider) DiskTotal(target MetricsTarget, t time.Time) (float64, error) {
+	return p.query(func(m MetricsProvider) (float64, error) { return m.DiskTotal(target, t) })
+}
+
+// DiskFreeRange 依次查询支持历史查询的监控
+func (p FallbackMetricsProvider) DiskFreeRange(target MetricsTarget, start, end time.Time, step time.Duration) ([]MetricPoint, error) {
+	return p.queryRange(func(m MetricsProvider) ([]MetricPoint, bool, error) {
+		trend, ok := m.(DiskTrendProvider)
+		if !ok {
+			return nil, false, nil
+		}
+		points, err := trend.DiskFreeRange(target, start, end, step)
+		return points, true, err
+	})
+}
+
+// LoadRange 依次查询支持负载历史查询的监控
+func (p FallbackMetricsProvider) LoadRange(target MetricsTarget, metric LoadMetric, start, end time.Time, step time.Duration) ([]MetricPoint, error) {
+	return p.queryRange(func(m MetricsProvider) ([]MetricPoint, bool, error) {
+		history, ok := m.(LoadHistoryProvider)
+		if !ok {
+			return nil, false, nil
+		}
+		points, err := history.LoadRange(target, metric, start, end, step)
+		return points, true, err
+	})
+}
+
+// queryRange fn返回的bool表示监控是否支持该区间查询
+func (p FallbackMetricsProvider) queryRange(fn func(MetricsProvider) ([]MetricPoint, bool, error)) ([]MetricPoint, error) {
+	errs := make([]string, 0, len(p))
+	for _, m := range p {
+		points, ok, err := fn(m)
+		if !ok {
+			continue
+		}
+		if err == nil {
+			return points, nil
+		}
+		errs = append(errs, err.Error())
+	}
+	if len(errs) == 0 {
+		return nil, NoRangeMetricsProviderError
+	}
+	return nil, errors.New(strings.Join(errs, "; "))
+}
+
+func (p FallbackMetricsProvider) query(fn func(MetricsProvider) (float64, error)) (float64, error) {
+	if len(p) == 0 {
+		return 0, NoMetricsProviderError
+	}
+	errs := make([]string, 0, len(p))
+	for _, m := range p {
+		v, err := fn(m)
+		if err == nil {
+			return v, nil
+		}
+		errs = append(errs, err.Error())
+	}
+	return 0, errors.New(strings.Join(errs, "; "))
+}
+
+// GlobalStatus 查询 SHOW GLOBAL STATUS 中的数值
+func (db *Connector) GlobalStatus(name string) (float64, error) {
+	res, err := db.Query(fmt.Sprintf("SHOW GLOBAL STATUS LIKE '%s'", name))
+	if err != nil {
+		return 0, fmt.Errorf("exec sql query failed, %s", err)
+	}
+
+	key, value := "", ""
+	for res.Rows.Next() {
+		err = res.Rows.Scan(&key, &value)
+		if err != nil {
+			return 0, fmt.Errorf("scan rows failed, %s", err)
+		}
+	}
+	err = res.Rows.Close()
+	if err != nil {
+		return 0, fmt.Errorf("close scan rows failed, %s", err)
+	}
+	if key == "" {
+		return 0, fmt.Errorf("global status %s not found", name)
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// DataDirUsed 数据目录中表和binlog的大小(MB)
+func (db *Connector) DataDirUsed() (float64, error) {
+	res, err := db.Query("SELECT coalesce(sum(DATA_LENGTH + INDEX_LENGTH + DATA_FREE), 0) / 1024 / 1024 FROM information_schema.TABLES")
+	if err != nil {
+		return 0, fmt.Errorf("exec sql query failed, %s", err)
+	}
+
+	used := 0.0
+	for res.Rows.Next() {
+		err = res.Rows.Scan(&used)
+		if err != nil {
+			return 0, fmt.Errorf("scan rows failed, %s", err)
+		}
+	}
+	err = res.Rows.Close()
+	if err != nil {
+		return 0, fmt.Errorf("close scan rows failed, %s", err)
+	}
+
+	// 未开启binlog时报错，不计入
+	res, err = db.Query("SHOW BINARY LOGS")
+	if err != nil {
+		return used, nil
+	}
+	rows, err := scanRowMaps(res.Rows)
+	if err != nil {
+		return 0, err
+	}
+	for _, row := range rows {
+		size, _ := strconv.ParseFloat(row["File_size"], 64)
+		used += size / 1024 / 1024
+	}
+	return used, nil
+}
@@ -0,0 +1,128 @@
+package sqlrisk
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPrometheusMetricsProvider(t *testing.T) {
+	queries := make([]string, 0)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query().Get("query")
+		queries = append(queries, query)
+		w.WriteHeader(http.StatusOK)
+		if !strings.Contains(query, "mountpoint='/'") {
+			w.Write([]byte(`{"status": "success", "data": {"resultType": "vector", "result": []}}`))
+			return
+		}
+		w.Write([]byte(`{"status": "success", "data": {"resultType": "vector", "result": [{"metric": {}, "value": [1688372379, "2048"]}]}}`))
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		class   string
+		want    float64
+		queries int
+		wantErr bool
+	}{
+		{"test001", MetricsClassNode, 2048, 2, false},
+		{"test002", "", 2048, 3, false},
+		{"test003", MetricsClassWindows, 0, 1, true},
+		{"test004", "unknown", 0, 0, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			queries = queries[:0]
+			p := NewMetricsProvider(MetricsConfig{Url: server.URL})
+			got, err := p.DiskFree(MetricsTarget{Addr: "10.2.16.15", Class: test.class}, time.Now())
+			if (err != nil) != test.wantErr || got != test.want {
+				t.Fatalf("DiskFree got %v, %v, want %v", got, err, test.want)
+			}
+			if len(queries) != test.queries {
+				t.Fatalf("DiskFree queried %v, want %d queries", queries, test.queries)
+			}
+			for _, q := range queries {
+				if strings.Contains(q, "$") || !strings.Contains(q, "10.2.16.15") {
+					t.Fatalf("DiskFree query %s not expanded", q)
+				}
+			}
+		})
+	}
+}
+
+func TestMySQLMetricsProvider(t *testing.T) {
+	conn, mock, err := mockDBConn("test")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	target := MetricsTarget{Addr: "10.2.16.15", Connect: func() (*Connector, error) { return conn, nil }}
+	p := MySQLMetricsProvider{DiskCapacity: 10240, CpuCores: 8}
+
+	mock.ExpectExec("USE `test`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM information_schema.TABLES").WillReturnRows(mock.NewRows([]string{"used"}).AddRow(4096.0))
+	mock.ExpectExec("USE `test`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("^SHOW BINARY LOGS").WillReturnRows(mock.NewRows([]string{"Log_name", "File_size", "Encrypted"}).
+		AddRow("binlog.000001", "1073741824", "No"))
+	mock.ExpectClose()
+	disk, err := p.DiskFree(target, time.Now())
+	if err != nil || disk != 5120 {
+		t.Fatalf("DiskFree got %v, %v, want 5120", disk, err)
+	}
+
+	conn, mock, _ = mockDBConn("test")
+	mock.ExpectExec("USE `test`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("^SHOW GLOBAL STATUS LIKE 'Threads_running'").
+		WillReturnRows(mock.NewRows([]string{"Variable_name", "Value"}).AddRow("Threads_running", "6"))
+	cpu, err := p.CpuUsage(target, time.Now())
+	if err != nil || cpu != 75 {
+		t.Fatalf("CpuUsage got %v, %v, want 75", cpu, err)
+	}
+
+	if _, err = (MySQLMetricsProvider{}).DiskFree(target, time.Now()); err == nil {
+		t.Fatalf("DiskFree without disk capacity expect error but got nil")
+	}
+}
+
+func TestFallbackMetricsProvider(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  MetricsConfig
+		want    float64
+		wantErr bool
+	}{
+		{"test001", MetricsConfig{}, 0, true},
+		{"test002", MetricsConfig{Url: "http://127.0.0.1:1", Static: &StaticMetricsProvider{Cpu: 30}}, 30, false},
+		{"test003", MetricsConfig{Provider: FallbackMetricsProvider{errMetrics{}, &StaticMetricsProvider{Cpu: 50}}}, 50, false},
+		{"test004", MetricsConfig{Provider: FallbackMetricsProvider{errMetrics{}, errMetrics{}}}, 0, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := NewMetricsProvider(test.config).CpuUsage(MetricsTarget{}, time.Now())
+			if (err != nil) != test.wantErr || got != test.want {
+				t.Fatalf("CpuUsage got %v, %v, want %v", got, err, test.want)
+			}
+		})
+	}
+}
+
+type errMetrics struct{}
+
+func (errMetrics) CpuUsage(MetricsTarget, time.Time) (float64, error) {
+	return 0, fmt.Errorf("unavailable")
+}
+
+func (errMetrics) DiskTotal(MetricsTarget, time.Time) (float64, error) {
+	return 0, fmt.Errorf("unavailable")
+}
+
+func (errMetrics) DiskFree(MetricsTarget, time.Time) (float64, error) {
+	return 0, fmt.Errorf("unavailable")
+}
@@ -256,6 +256,170 @@ func (db *Connector) TableRows(table string) (int, error) {
 	return rows, err
 }
 
+// ServerVersion 查询数据库版本
+func (db *Connector) ServerVersion() (string, error) {
+	res, err := db.Query("SELECT VERSION()")
+	if err != nil {
+		return "", fmt.Errorf("exec sql query failed, %s", err)
+	}
+
+	version := ""
+	for res.Rows.Next() {
+		err = res.Rows.Scan(&version)
+		if err != nil {
+			return "", fmt.Errorf("scan rows failed, %s", err)
+		}
+	}
+	err = res.Rows.Close()
+	if err != nil {
+		return "", fmt.Errorf("close scan rows failed, %s", err)
+	}
+	return version, nil
+}
+
+// ReplicaHost 主库上发现的从库地址，Port为空表示未知
+type ReplicaHost struct {
+	Host string
+	Port string
+}
+
+// ReplicaHosts 发现主库的从库：优先使用 SHOW REPLICAS(8.0.22+)/SHOW SLAVE HOSTS，
+// 从库未配置report_host时Host为空，此时从processlist中的Binlog Dump线程获取从库IP
+func (db *Connector) ReplicaHosts() ([]ReplicaHost, error) {
+	hosts := make([]ReplicaHost, 0, 2)
+	for _, query := range []string{"SHOW REPLICAS", "SHOW SLAVE HOSTS"} {
+		res, err := db.Query(query)
+		if err != nil {
+			continue
+		}
+		rows, err := scanRowMaps(res.Rows)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			if row["Host"] == "" {
+				continue
+			}
+			hosts = append(hosts, ReplicaHost{Host: row["Host"], Port: row["Port"]})
+		}
+		break
+	}
+	if len(hosts) != 0 {
+		return hosts, nil
+	}
+
+	res, err := db.Query("SELECT HOST FROM information_schema.PROCESSLIST WHERE COMMAND IN ('Binlog Dump', 'Binlog Dump GTID')")
+	if err != nil {
+		return nil, fmt.Errorf("exec sql query failed, %s", err)
+	}
+	host := ""
+	for res.Rows.Next() {
+		err = res.Rows.Scan(&host)
+		if err != nil {
+			return nil, fmt.Errorf("scan rows failed, %s", err)
+		}
+		// processlist中的HOST为 ip:客户端端口
+		if i := strings.LastIndex(host, ":"); i != -1 {
+			host = host[:i]
+		}
+		hosts = append(hosts, ReplicaHost{Host: host})
+	}
+	err = res.Rows.Close()
+	if err != nil {
+		return nil, fmt.Errorf("close scan rows failed, %s", err)
+	}
+	return hosts, nil
+}
+
+// ReplicationLag 在从库上查询复制延迟，单位s；不是从库或复制线程已停止(Seconds_Behind_Source为NULL)时ok为false
+func (db *Connector) ReplicationLag() (lag int, ok bool, err error) {
+	var rows []map[string]string
+	for _, query := range []string{"SHOW REPLICA STATUS", "SHOW SLAVE STATUS"} {
+		res, e := db.Query(query)
+		if e != nil {
+			err = fmt.Errorf("exec sql query failed, %s", e)
+			continue
+		}
+		rows, err = scanRowMaps(res.Rows)
+		break
+	}
+	if err != nil || len(rows) == 0 {
+		return 0, false, err
+	}
+
+	// 多源复制时取最大的延迟
+	for _, row := range rows {
+		v, exist := row["Seconds_Behind_Source"]
+		if !exist {
+			v, exist = row["Seconds_Behind_Master"]
+		}
+		if !exist || v == "" {
+			continue
+		}
+		n, e := strconv.Atoi(v)
+		if e != nil {
+			return 0, false, fmt.Errorf("parse seconds behind source %q failed, %s", v, e)
+		}
+		if !ok || n > lag {
+			lag = n
+		}
+		ok = true
+	}
+	return lag, ok, nil
+}
+
+// AvgRowLength 查询表的平均行长度，单位字节
+func (db *Connector) AvgRowLength(d, table string) (int, error) {
+	sqlQuery := fmt.Sprintf("SELECT AVG_ROW_LENGTH FROM information_schema.TABLES WHERE TABLE_SCHEMA = '%s' AND TABLE_NAME = '%s'", d, table)
+
+	res, err := db.Query(sqlQuery)
+	if err != nil {
+		return 0, fmt.Errorf("exec sql query failed, %s", err)
+	}
+
+	length := sql.NullInt64{}
+	for res.Rows.Next() {
+		err = res.Rows.Scan(&length)
+		if err != nil {
+			return 0, fmt.Errorf("scan rows failed, %s", err)
+		}
+	}
+	err = res.Rows.Close()
+	if err != nil {
+		return 0, fmt.Errorf("close scan rows failed, %s", err)
+	}
+	return int(length.Int64), nil
+}
+
+// scanRowMaps 将列数不固定的查询结果(如SHOW REPLICA STATUS)转换为列名到值的映射，NULL转换为空字符串
+func scanRowMaps(rows *sql.Rows) ([]map[string]string, error) {
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("get columns failed, %s", err)
+	}
+
+	result := make([]map[string]string, 0, 1)
+	for rows.Next() {
+		values := make([]sql.RawBytes, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err = rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("scan rows failed, %s", err)
+		}
+
+		row := make(map[string]string, len(columns))
+		for i, col := range columns {
+			row[col] = string(values[i])
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
 // TableConstraints 查询表包含哪些约束
 func (db *Connector) TableConstraints(d, table string) (map[string][]string, error) {
 	var err error
@@ -1,9 +1,13 @@
 package sqlrisk
 
 import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"testing"
+
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/sunkaimr/sql-risk/comm"
-	"testing"
 )
 
 func mockDBConn(database string) (*Connector, sqlmock.Sqlmock, error) {
@@ -178,6 +182,25 @@ func TestTableRows(t *testing.T) {
 	}
 }
 
+func TestServerVersion(t *testing.T) {
+	conn, mock, err := mockDBConn("test")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer conn.Close()
+
+	mock.ExpectExec("USE `test`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("^SELECT VERSION").WillReturnRows(mock.NewRows([]string{"VERSION()"}).AddRow("8.0.32-log"))
+	o, err := conn.ServerVersion()
+	if err != nil {
+		t.Fatalf("ServerVersion failed, got error: %s", err)
+	}
+
+	if o != "8.0.32-log" {
+		t.Fatalf("ServerVersion got: %v, want: %v", o, "8.0.32-log")
+	}
+}
+
 func TestTableConstraints(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -367,3 +390,119 @@ func TestTableIndex(t *testing.T) {
 		})
 	}
 }
+
+func TestReplicaHosts(t *testing.T) {
+	conn, mock, err := mockDBConn("test")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer conn.Close()
+
+	// 8.0.22以下不支持SHOW REPLICAS，回退到SHOW SLAVE HOSTS
+	mock.ExpectExec("USE `test`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("^SHOW REPLICAS").WillReturnError(fmt.Errorf("syntax error"))
+	mock.ExpectExec("USE `test`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("^SHOW SLAVE HOSTS").WillReturnRows(mock.NewRows([]string{"Server_id", "Host", "Port", "Master_id", "Slave_UUID"}).
+		AddRow(2, "10.0.0.2", 3306, 1, "uuid-2").
+		AddRow(3, "", 3306, 1, "uuid-3"))
+	o, err := conn.ReplicaHosts()
+	if err != nil {
+		t.Fatalf("ReplicaHosts failed, got error: %s", err)
+	}
+	want := []ReplicaHost{{Host: "10.0.0.2", Port: "3306"}}
+	if !reflect.DeepEqual(o, want) {
+		t.Fatalf("ReplicaHosts got: %v, want: %v", o, want)
+	}
+
+	// 未配置report_host时从processlist获取
+	mock.ExpectExec("USE `test`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("^SHOW REPLICAS").WillReturnRows(mock.NewRows([]string{"Server_Id", "Host", "Port", "Source_Id", "Replica_UUID"}))
+	mock.ExpectExec("USE `test`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("^SELECT HOST FROM information_schema.PROCESSLIST").WillReturnRows(mock.NewRows([]string{"HOST"}).AddRow("10.0.0.3:51234"))
+	o, err = conn.ReplicaHosts()
+	if err != nil {
+		t.Fatalf("ReplicaHosts failed, got error: %s", err)
+	}
+	want = []ReplicaHost{{Host: "10.0.0.3"}}
+	if !reflect.DeepEqual(o, want) {
+		t.Fatalf("ReplicaHosts got: %v, want: %v", o, want)
+	}
+}
+
+func TestReplicationLag(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns []string
+		rows    [][]driver.Value
+		lag     int
+		ok      bool
+	}{
+		{
+			name:    "test001",
+			columns: []string{"Replica_IO_Running", "Seconds_Behind_Source"},
+			rows:    [][]driver.Value{{"Yes", 120}},
+			lag:     120,
+			ok:      true,
+		},
+		{
+			name:    "test002",
+			columns: []string{"Replica_IO_Running", "Seconds_Behind_Source"},
+			rows:    [][]driver.Value{{"No", nil}},
+			ok:      false,
+		},
+		{
+			name:    "test003",
+			columns: []string{"Replica_IO_Running", "Seconds_Behind_Source"},
+			ok:      false,
+		},
+		{
+			name:    "test004",
+			columns: []string{"Channel_Name", "Seconds_Behind_Source"},
+			rows:    [][]driver.Value{{"c1", 5}, {"c2", 30}},
+			lag:     30,
+			ok:      true,
+		},
+	}
+
+	conn, mock, err := mockDBConn("test")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer conn.Close()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rows := mock.NewRows(test.columns)
+			for _, r := range test.rows {
+				rows.AddRow(r...)
+			}
+			mock.ExpectExec("USE `test`").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery("^SHOW REPLICA STATUS").WillReturnRows(rows)
+			lag, ok, err := conn.ReplicationLag()
+			if err != nil {
+				t.Fatalf("ReplicationLag failed, got error: %s", err)
+			}
+			if lag != test.lag || ok != test.ok {
+				t.Fatalf("ReplicationLag got: %v %v, want: %v %v", lag, ok, test.lag, test.ok)
+			}
+		})
+	}
+}
+
+func TestAvgRowLength(t *testing.T) {
+	conn, mock, err := mockDBConn("test")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer conn.Close()
+
+	mock.ExpectExec("USE `test`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("^SELECT AVG_ROW_LENGTH").WillReturnRows(mock.NewRows([]string{"AVG_ROW_LENGTH"}).AddRow(128))
+	o, err := conn.AvgRowLength("test", "student")
+	if err != nil {
+		t.Fatalf("AvgRowLength failed, got error: %s", err)
+	}
+	if o != 128 {
+		t.Fatalf("AvgRowLength got: %v, want: %v", o, 128)
+	}
+}
@@ -0,0 +1,358 @@
+package sqlrisk
+
+import (
+	"strings"
+
+	"github.com/pingcap/tidb/parser/ast"
+	"github.com/sunkaimr/sql-risk/comm"
+)
+
+// DDL执行算法，参考 https://dev.mysql.com/doc/refman/8.0/en/innodb-online-ddl-operations.html
+const (
+	DDLAlgorithmInstant = "INSTANT"
+	DDLAlgorithmInplace = "INPLACE"
+	DDLAlgorithmCopy    = "COPY"
+)
+
+// DDL执行期间的锁级别
+const (
+	DDLLockNone      = "NONE"
+	DDLLockShared    = "SHARED"
+	DDLLockExclusive = "EXCLUSIVE"
+)
+
+// 估算DDL耗时使用的处理速度，单位MB/s或行/s
+const (
+	ddlCopySpeed    = 20
+	ddlRebuildSpeed = 50
+	ddlIndexSpeed   = 100
+	ddlRowsSpeed    = 100000
+)
+
+var ddlAlgorithmOrder = map[string]int{DDLAlgorithmInstant: 1, DDLAlgorithmInplace: 2, DDLAlgorithmCopy: 3}
+var ddlLockOrder = map[string]int{DDLLockNone: 1, DDLLockShared: 2, DDLLockExclusive: 3}
+
+// OnlineDDL DDL的执行方式预测结果
+type OnlineDDL struct {
+	// 算法：INSTANT, INPLACE, COPY
+	Algorithm string `json:"algorithm"`
+	// 执行期间的锁：NONE 不阻塞DML，SHARED 阻塞写，EXCLUSIVE 阻塞读写
+	Lock string `json:"lock"`
+	// 是否需要重建表
+	Rebuild bool `json:"rebuild"`
+	// 预计耗时，单位s
+	Duration int `json:"duration"`
+}
+
+// DDLTableMeta 预测DDL执行方式和生成在线改表方案时用到的表信息
+type DDLTableMeta struct {
+	// 表大小，单位MB
+	Size int
+	// 表行数
+	Rows int
+	// 是否存在主键
+	PrimaryKeyExist bool
+	// 是否存在外键
+	ForeignKeyExist bool
+	// 是否存在触发器
+	TriggerExist bool
+}
+
+// ddlImpact 单个子句的执行方式
+type ddlImpact struct {
+	algorithm string
+	lock      string
+	rebuild   bool
+	// 不重建表的INPLACE操作是否需要扫描全表，如添加索引
+	scan bool
+}
+
+// AnalyzeOnlineDDL 根据ALTER TABLE、CREATE INDEX、DROP INDEX语句、数据库版本（区分分支）和表信息预测DDL的执行算法、锁级别、是否重建表和耗时，
+// 多个子句时取最慢的算法和最高的锁级别，不是此类语句时返回false
+func AnalyzeOnlineDDL(stmt ast.StmtNode, server *ServerInfo, meta DDLTableMeta) (*OnlineDDL, bool) {
+	var impacts []ddlImpact
+	requestAlg, requestLock := "", ""
+
+	switch st := stmt.(type) {
+	case *ast.AlterTableStmt:
+		for _, spec := range st.Specs {
+			switch spec.Tp {
+			case ast.AlterTableAlgorithm:
+				requestAlg = ddlAlgorithm(spec.Algorithm)
+			case ast.AlterTableLock:
+				requestLock = ddlLock(spec.LockType)
+			}
+		}
+		impacts = alterImpacts(st, server, meta, requestAlg)
+	case *ast.CreateIndexStmt:
+		spec := &ast.AlterTableSpec{
+			Tp:         ast.AlterTableAddConstraint,
+			Constraint: &ast.Constraint{Tp: indexConstraintType(st.KeyType)},
+		}
+		impacts = append(impacts, specImpact(spec, server, meta, ""))
+		if st.LockAlg != nil {
+			requestAlg, requestLock = ddlAlgorithm(st.LockAlg.AlgorithmTp), ddlLock(st.LockAlg.LockTp)
+		}
+	case *ast.DropIndexStmt:
+		impacts = append(impacts, specImpact(&ast.AlterTableSpec{Tp: ast.AlterTableDropIndex}, server, meta, ""))
+		if st.LockAlg != nil {
+			requestAlg, requestLock = ddlAlgorithm(st.LockAlg.AlgorithmTp), ddlLock(st.LockAlg.LockTp)
+		}
+	default:
+		return nil, false
+	}
+
+	ddl := &OnlineDDL{Algorithm: DDLAlgorithmInstant, Lock: DDLLockNone}
+	scan := false
+	for _, i := range impacts {
+		ddl.Algorithm = maxDDLOrder(ddlAlgorithmOrder, ddl.Algorithm, i.algorithm)
+		ddl.Lock = maxDDLOrder(ddlLockOrder, ddl.Lock, i.lock)
+		ddl.Rebuild = ddl.Rebuild || i.rebuild
+		scan = scan || i.scan
+	}
+
+	// 显式指定的ALGORITHM和LOCK只能比预测的更保守，否则mysql会直接报错
+	if requestAlg != "" {
+		ddl.Algorithm = maxDDLOrder(ddlAlgorithmOrder, ddl.Algorithm, requestAlg)
+	}
+	if ddl.Algorithm == DDLAlgorithmCopy {
+		ddl.Rebuild = true
+		ddl.Lock = maxDDLOrder(ddlLockOrder, ddl.Lock, DDLLockShared)
+	}
+	if requestLock != "" {
+		ddl.Lock = maxDDLOrder(ddlLockOrder, ddl.Lock, requestLock)
+	}
+
+	ddl.Duration = estimateDDLDuration(ddl, scan, meta)
+	return ddl, true
+}
+
+// alterImpacts ALTER TABLE每个子句的执行方式
+func alterImpacts(st *ast.AlterTableStmt, server *ServerInfo, meta DDLTableMeta, requestAlg string) []ddlImpact {
+	impacts := make([]ddlImpact, 0, len(st.Specs))
+
+	// 同时删除和添加主键时可以INPLACE重建表
+	dropPK, addPK := false, false
+	for _, spec := range st.Specs {
+		switch {
+		case spec.Tp == ast.AlterTableDropPrimaryKey:
+			dropPK = true
+		case spec.Tp == ast.AlterTableAddConstraint && spec.Constraint != nil && spec.Constraint.Tp == ast.ConstraintPrimaryKey:
+			addPK = true
+		}
+	}
+
+	for _, spec := range st.Specs {
+		if spec.Tp == ast.AlterTableAlgorithm || spec.Tp == ast.AlterTableLock {
+			continue
+		}
+		if spec.Tp == ast.AlterTableDropPrimaryKey && addPK {
+			impacts = append(impacts, ddlImpact{algorithm: DDLAlgorithmInplace, lock: DDLLockNone, rebuild: true})
+			continue
+		}
+		if dropPK && spec.Tp == ast.AlterTableAddConstraint && spec.Constraint != nil && spec.Constraint.Tp == ast.ConstraintPrimaryKey {
+			impacts = append(impacts, ddlImpact{algorithm: DDLAlgorithmInplace, lock: DDLLockNone, rebuild: true})
+			continue
+		}
+		impacts = append(impacts, specImpact(spec, server, meta, requestAlg))
+	}
+	return impacts
+}
+
+// specImpact 单个子句的执行方式，未知原列定义时修改列按改变数据类型处理
+func specImpact(spec *ast.AlterTableSpec, server *ServerInfo, meta DDLTableMeta, requestAlg string) ddlImpact {
+	version := server.Version
+	copyImpact := ddlImpact{algorithm: DDLAlgorithmCopy, lock: DDLLockShared, rebuild: true}
+	rebuild := ddlImpact{algorithm: DDLAlgorithmInplace, lock: DDLLockNone, rebuild: true}
+	inplace := ddlImpact{algorithm: DDLAlgorithmInplace, lock: DDLLockNone}
+	instant := inplace
+	// 显式指定其他算法时不会使用INSTANT
+	if server.SupportInstantDDL() && (requestAlg == "" || requestAlg == DDLAlgorithmInstant) {
+		instant = ddlImpact{algorithm: DDLAlgorithmInstant, lock: DDLLockNone}
+	}
+
+	// 5.6之前不支持online DDL，只有添加和删除二级索引不需要复制表
+	if !version.AtLeast(5, 6, 0) {
+		switch spec.Tp {
+		case ast.AlterTableDropIndex:
+			return ddlImpact{algorithm: DDLAlgorithmInplace, lock: DDLLockShared}
+		case ast.AlterTableAddConstraint:
+			if spec.Constraint != nil && (spec.Constraint.Tp == ast.ConstraintIndex || spec.Constraint.Tp == ast.ConstraintKey) {
+				return ddlImpact{algorithm: DDLAlgorithmInplace, lock: DDLLockShared, scan: true}
+			}
+		}
+		return copyImpact
+	}
+
+	switch spec.Tp {
+	case ast.AlterTableAddColumns:
+		for _, col := range spec.NewColumns {
+			for _, opt := range col.Options {
+				// 添加自增列和存储的生成列需要复制表
+				if opt.Tp == ast.ColumnOptionAutoIncrement || opt.Tp == ast.ColumnOptionGenerated && opt.Stored {
+					return copyImpact
+				}
+			}
+		}
+		// 支持INSTANT的版本都可以INSTANT添加列到最后，添加到其他位置需要更高的版本
+		atLast := spec.Position == nil || spec.Position.Tp == ast.ColumnPositionNone
+		if instant.algorithm == DDLAlgorithmInstant && (atLast || instantColumnAnyPosition(server)) {
+			return instant
+		}
+		return rebuild
+	case ast.AlterTableDropColumn:
+		if instant.algorithm == DDLAlgorithmInstant && instantColumnAnyPosition(server) {
+			return instant
+		}
+		return rebuild
+	case ast.AlterTableRenameColumn:
+		// MySQL 8.0.28开始可以INSTANT重命名列，MariaDB支持RENAME COLUMN语法的版本都可以INSTANT重命名
+		if instant.algorithm == DDLAlgorithmInstant && server.SupportRenameColumn() &&
+			(server.Flavor == comm.FlavorMariaDB || server.Flavor == comm.FlavorTiDB || version.AtLeast(8, 0, 28)) {
+			return instant
+		}
+		return inplace
+	case ast.AlterTableModifyColumn, ast.AlterTableChangeColumn:
+		return copyImpact
+	case ast.AlterTableAlterColumn, ast.AlterTableRenameTable:
+		return instant
+	case ast.AlterTableDropIndex, ast.AlterTableDropForeignKey, ast.AlterTableRenameIndex:
+		return inplace
+	case ast.AlterTableAddConstraint:
+		if spec.Constraint == nil {
+			return copyImpact
+		}
+		switch spec.Constraint.Tp {
+		case ast.ConstraintPrimaryKey:
+			return rebuild
+		case ast.ConstraintIndex, ast.ConstraintKey:
+			return ddlImpact{algorithm: DDLAlgorithmInplace, lock: DDLLockNone, scan: true}
+		case ast.ConstraintUniq, ast.ConstraintUniqIndex, ast.ConstraintUniqKey:
+			// 没有主键时第一个非空唯一索引会成为聚簇索引，需要重建表
+			if !meta.PrimaryKeyExist {
+				return rebuild
+			}
+			return ddlImpact{algorithm: DDLAlgorithmInplace, lock: DDLLockNone, scan: true}
+		case ast.ConstraintFulltext:
+			// 第一个全文索引需要添加FTS_DOC_ID列并重建表
+			return ddlImpact{algorithm: DDLAlgorithmInplace, lock: DDLLockShared, rebuild: true}
+		}
+		// 外键在foreign_key_checks开启时只支持COPY
+		return copyImpact
+	case ast.AlterTableDropPrimaryKey:
+		return copyImpact
+	case ast.AlterTableOption:
+		return optionImpact(spec.Options, instant, inplace, rebuild, copyImpact)
+	case ast.AlterTableForce:
+		return rebuild
+	case ast.AlterTableAddPartitions:
+		return ddlImpact{algorithm: DDLAlgorithmInplace, lock: DDLLockShared}
+	case ast.AlterTableDropPartition, ast.AlterTableTruncatePartition, ast.AlterTableExchangePartition:
+		return ddlImpact{algorithm: DDLAlgorithmInplace, lock: DDLLockExclusive}
+	}
+	return copyImpact
+}
+
+// instantColumnAnyPosition 是否可以INSTANT添加任意位置的列和删除列，MySQL 8.0.29、MariaDB 10.4开始支持
+func instantColumnAnyPosition(server *ServerInfo) bool {
+	switch server.Flavor {
+	case comm.FlavorTiDB:
+		return true
+	case comm.FlavorMariaDB:
+		return server.Version.AtLeast(10, 4, 0)
+	default:
+		return server.Version.AtLeast(8, 0, 29)
+	}
+}
+
+// optionImpact 修改表选项的执行方式
+func optionImpact(options []*ast.TableOption, instant, inplace, rebuild, copyImpact ddlImpact) ddlImpact {
+	impact := instant
+	for _, opt := range options {
+		switch opt.Tp {
+		case ast.TableOptionCharset, ast.TableOptionCollate:
+			if opt.UintValue == ast.TableOptionCharsetWithConvertTo {
+				return copyImpact
+			}
+			impact = inplace
+		case ast.TableOptionEngine:
+			// 修改为其他存储引擎需要复制表，ENGINE=InnoDB 相当于重建表
+			if !strings.EqualFold(opt.StrValue, "innodb") {
+				return copyImpact
+			}
+			impact = rebuild
+		case ast.TableOptionRowFormat, ast.TableOptionKeyBlockSize:
+			impact = rebuild
+		case ast.TableOptionComment:
+		default:
+			if impact.algorithm == DDLAlgorithmInstant {
+				impact = inplace
+			}
+		}
+	}
+	return impact
+}
+
+// estimateDDLDuration 估算DDL耗时，按表大小和行数分别估算后取较大值
+func estimateDDLDuration(ddl *OnlineDDL, scan bool, meta DDLTableMeta) int {
+	speed := 0
+	switch {
+	case ddl.Algorithm == DDLAlgorithmCopy:
+		speed = ddlCopySpeed
+	case ddl.Rebuild:
+		speed = ddlRebuildSpeed
+	case scan:
+		speed = ddlIndexSpeed
+	default:
+		// 只修改元数据
+		return 0
+	}
+
+	duration := meta.Size / speed
+	// 行数按重建表的速度比例折算
+	if rows := meta.Rows * ddlRebuildSpeed / speed / ddlRowsSpeed; rows > duration {
+		duration = rows
+	}
+	return duration
+}
+
+func ddlAlgorithm(a ast.AlgorithmType) string {
+	switch a {
+	case ast.AlgorithmTypeCopy:
+		return DDLAlgorithmCopy
+	case ast.AlgorithmTypeInplace:
+		return DDLAlgorithmInplace
+	case ast.AlgorithmTypeInstant:
+		return DDLAlgorithmInstant
+	}
+	return ""
+}
+
+func ddlLock(l ast.LockType) string {
+	switch l {
+	case ast.LockTypeNone:
+		return DDLLockNone
+	case ast.LockTypeShared:
+		return DDLLockShared
+	case ast.LockTypeExclusive:
+		return DDLLockExclusive
+	}
+	return ""
+}
+
+func indexConstraintType(k ast.IndexKeyType) ast.ConstraintType {
+	switch k {
+	case ast.IndexKeyTypeUnique:
+		return ast.ConstraintUniqIndex
+	case ast.IndexKeyTypeFullText:
+		return ast.ConstraintFulltext
+	}
+	return ast.ConstraintIndex
+}
+
+func maxDDLOrder(order map[string]int, a, b string) string {
+	if order[b] > order[a] {
+		return b
+	}
+	return a
+}
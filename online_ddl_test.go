@@ -0,0 +1,70 @@
+package sqlrisk
+
+import (
+	"testing"
+
+	"github.com/pingcap/tidb/parser"
+	"github.com/sunkaimr/sql-risk/comm"
+)
+
+func TestAnalyzeOnlineDDL(t *testing.T) {
+	mysql80 := &ServerInfo{Version: comm.Version{Major: 8, Minor: 0, Patch: 32}, Flavor: comm.FlavorMySQL}
+	mysql8011 := &ServerInfo{Version: comm.Version{Major: 8, Minor: 0, Patch: 11}, Flavor: comm.FlavorMySQL}
+	mysql57 := &ServerInfo{Version: comm.Version{Major: 5, Minor: 7, Patch: 40}, Flavor: comm.FlavorMySQL}
+	mysql55 := &ServerInfo{Version: comm.Version{Major: 5, Minor: 5, Patch: 62}, Flavor: comm.FlavorMySQL}
+	mariadb102 := &ServerInfo{Version: comm.Version{Major: 10, Minor: 2, Patch: 44}, Flavor: comm.FlavorMariaDB}
+	mariadb103 := &ServerInfo{Version: comm.Version{Major: 10, Minor: 3, Patch: 39}, Flavor: comm.FlavorMariaDB}
+	mariadb106 := &ServerInfo{Version: comm.Version{Major: 10, Minor: 6, Patch: 12}, Flavor: comm.FlavorMariaDB}
+	meta := DDLTableMeta{Size: 10240, Rows: 50000000, PrimaryKeyExist: true}
+
+	tests := []struct {
+		name    string
+		sql     string
+		server  *ServerInfo
+		meta    DDLTableMeta
+		want    OnlineDDL
+	}{
+		{"test001", "ALTER TABLE t ADD COLUMN a INT", mysql80, meta, OnlineDDL{DDLAlgorithmInstant, DDLLockNone, false, 0}},
+		{"test002", "ALTER TABLE t ADD COLUMN a INT FIRST", mysql8011, meta, OnlineDDL{DDLAlgorithmInplace, DDLLockNone, true, 500}},
+		{"test003", "ALTER TABLE t ADD COLUMN a INT", mysql57, meta, OnlineDDL{DDLAlgorithmInplace, DDLLockNone, true, 500}},
+		{"test004", "ALTER TABLE t ADD COLUMN a INT, ALGORITHM=INPLACE", mysql80, meta, OnlineDDL{DDLAlgorithmInplace, DDLLockNone, true, 500}},
+		{"test005", "ALTER TABLE t MODIFY COLUMN a BIGINT", mysql80, meta, OnlineDDL{DDLAlgorithmCopy, DDLLockShared, true, 1250}},
+		{"test006", "ALTER TABLE t ADD INDEX idx_a (a)", mysql80, meta, OnlineDDL{DDLAlgorithmInplace, DDLLockNone, false, 250}},
+		{"test007", "CREATE UNIQUE INDEX idx_a ON t (a)", mysql80, DDLTableMeta{Size: 100}, OnlineDDL{DDLAlgorithmInplace, DDLLockNone, true, 2}},
+		{"test008", "ALTER TABLE t DROP PRIMARY KEY", mysql80, meta, OnlineDDL{DDLAlgorithmCopy, DDLLockShared, true, 1250}},
+		{"test009", "ALTER TABLE t DROP PRIMARY KEY, ADD PRIMARY KEY (id, a)", mysql80, meta, OnlineDDL{DDLAlgorithmInplace, DDLLockNone, true, 500}},
+		{"test010", "ALTER TABLE t ADD COLUMN a INT, DROP INDEX idx_b, LOCK=SHARED", mysql80, meta, OnlineDDL{DDLAlgorithmInplace, DDLLockShared, false, 0}},
+		{"test011", "ALTER TABLE t CONVERT TO CHARACTER SET utf8mb4", mysql80, meta, OnlineDDL{DDLAlgorithmCopy, DDLLockShared, true, 1250}},
+		{"test012", "ALTER TABLE t ENGINE=InnoDB", mysql57, meta, OnlineDDL{DDLAlgorithmInplace, DDLLockNone, true, 500}},
+		{"test013", "ALTER TABLE t COMMENT='学生表'", mysql80, meta, OnlineDDL{DDLAlgorithmInstant, DDLLockNone, false, 0}},
+		{"test014", "ALTER TABLE t DROP PARTITION p1", mysql80, meta, OnlineDDL{DDLAlgorithmInplace, DDLLockExclusive, false, 0}},
+		{"test015", "ALTER TABLE t ADD COLUMN a INT", mysql55, meta, OnlineDDL{DDLAlgorithmCopy, DDLLockShared, true, 1250}},
+		{"test016", "DROP INDEX idx_a ON t", mysql80, meta, OnlineDDL{DDLAlgorithmInplace, DDLLockNone, false, 0}},
+		{"test017", "ALTER TABLE t RENAME COLUMN a TO b, ALGORITHM=COPY", mysql80, meta, OnlineDDL{DDLAlgorithmCopy, DDLLockShared, true, 1250}},
+		// MariaDB按自己的版本判断是否支持INSTANT
+		{"test018", "ALTER TABLE t ADD COLUMN a INT", mariadb102, meta, OnlineDDL{DDLAlgorithmInplace, DDLLockNone, true, 500}},
+		{"test019", "ALTER TABLE t ADD COLUMN a INT", mariadb103, meta, OnlineDDL{DDLAlgorithmInstant, DDLLockNone, false, 0}},
+		{"test020", "ALTER TABLE t ADD COLUMN a INT FIRST", mariadb103, meta, OnlineDDL{DDLAlgorithmInplace, DDLLockNone, true, 500}},
+		{"test021", "ALTER TABLE t DROP COLUMN a", mariadb106, meta, OnlineDDL{DDLAlgorithmInstant, DDLLockNone, false, 0}},
+		{"test022", "ALTER TABLE t RENAME COLUMN a TO b", mariadb103, meta, OnlineDDL{DDLAlgorithmInplace, DDLLockNone, false, 0}},
+		{"test023", "ALTER TABLE t RENAME COLUMN a TO b", mariadb106, meta, OnlineDDL{DDLAlgorithmInstant, DDLLockNone, false, 0}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stmt, err := parser.New().ParseOneStmt(test.sql, "", "")
+			if err != nil {
+				t.Fatalf("parse %q failed, %s", test.sql, err)
+			}
+			got, ok := AnalyzeOnlineDDL(stmt, test.server, test.meta)
+			if !ok || *got != test.want {
+				t.Fatalf("AnalyzeOnlineDDL(%q) failed, got:%+v, want:%+v", test.sql, got, test.want)
+			}
+		})
+	}
+
+	stmt, _ := parser.New().ParseOneStmt("SELECT 1", "", "")
+	if _, ok := AnalyzeOnlineDDL(stmt, mysql80, meta); ok {
+		t.Fatalf("AnalyzeOnlineDDL(SELECT 1) should return false")
+	}
+}
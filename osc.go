@@ -0,0 +1,132 @@
+package sqlrisk
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/tidb/parser/ast"
+	"github.com/pingcap/tidb/parser/format"
+)
+
+// 在线改表工具
+const (
+	OSCToolGhost = "gh-ost"
+	OSCToolPtOSC = "pt-online-schema-change"
+)
+
+// OSCPlan 使用gh-ost或pt-online-schema-change在线改表的方案
+type OSCPlan struct {
+	// 推荐使用的工具，存在无法规避的阻碍时为空
+	Tool string `json:"tool"`
+	// 推荐工具的命令行，密码通过--ask-pass交互输入
+	Command string `json:"command"`
+	// 需要使用在线改表工具的原因
+	Reason string `json:"reason"`
+	// 无法使用在线改表工具或需要注意的问题
+	Blockers []string `json:"blockers"`
+}
+
+// OSCTarget 在线改表工具连接的数据源和操作的表
+type OSCTarget struct {
+	Host     string
+	Port     string
+	User     string
+	Database string
+	Table    string
+}
+
+// NewOSCPlan 根据ALTER TABLE语句和表信息生成在线改表方案：
+// 没有主键时两种工具都无法使用；存在外键或触发器时gh-ost无法使用，改为推荐pt-online-schema-change
+func NewOSCPlan(st *ast.AlterTableStmt, target OSCTarget, meta DDLTableMeta, reason string) *OSCPlan {
+	plan := &OSCPlan{Reason: reason, Blockers: make([]string, 0, 1)}
+
+	blocked := false
+	if !meta.PrimaryKeyExist {
+		blocked = true
+		plan.Blockers = append(plan.Blockers, "表没有主键，gh-ost和pt-online-schema-change都需要主键或非空唯一索引来分批复制数据")
+	}
+
+	specs := make([]string, 0, len(st.Specs))
+	for _, spec := range st.Specs {
+		switch spec.Tp {
+		case ast.AlterTableAlgorithm, ast.AlterTableLock:
+			// 在线改表工具自己控制执行方式，去掉ALGORITHM和LOCK
+			continue
+		case ast.AlterTableRenameTable:
+			blocked = true
+			plan.Blockers = append(plan.Blockers, "在线改表工具不支持重命名表，请单独执行RENAME TABLE")
+			continue
+		}
+
+		var sb strings.Builder
+		if err := spec.Restore(format.NewRestoreCtx(format.DefaultRestoreFlags, &sb)); err != nil {
+			blocked = true
+			plan.Blockers = append(plan.Blockers, fmt.Sprintf("restore alter spec failed, %s", err))
+			continue
+		}
+		specs = append(specs, sb.String())
+	}
+	if len(specs) == 0 {
+		blocked = true
+		plan.Blockers = append(plan.Blockers, "没有需要在线改表工具执行的子句")
+	}
+
+	plan.Tool = OSCToolGhost
+	if meta.ForeignKeyExist {
+		plan.Tool = OSCToolPtOSC
+		plan.Blockers = append(plan.Blockers, "表存在外键，gh-ost不支持外键，pt-online-schema-change需要指定--alter-foreign-keys-method")
+	}
+	if meta.TriggerExist {
+		plan.Tool = OSCToolPtOSC
+		plan.Blockers = append(plan.Blockers, "表存在触发器，gh-ost不支持触发器，pt-online-schema-change需要mysql 5.7.2及以上版本并指定--preserve-triggers")
+	}
+	if blocked {
+		plan.Tool = ""
+		return plan
+	}
+
+	alter := strings.Join(specs, ", ")
+	switch plan.Tool {
+	case OSCToolGhost:
+		plan.Command = strings.Join([]string{
+			OSCToolGhost,
+			"--host=" + shellQuote(target.Host),
+			"--port=" + shellQuote(target.Port),
+			"--user=" + shellQuote(target.User),
+			"--ask-pass",
+			"--database=" + shellQuote(target.Database),
+			"--table=" + shellQuote(target.Table),
+			"--alter=" + shellQuote(alter),
+			"--allow-on-master",
+			"--chunk-size=1000",
+			"--max-load=Threads_running=25",
+			"--critical-load=Threads_running=100",
+			"--initially-drop-ghost-table",
+			"--initially-drop-old-table",
+			"--execute",
+		}, " ")
+	case OSCToolPtOSC:
+		args := []string{
+			OSCToolPtOSC,
+			"--alter " + shellQuote(alter),
+			shellQuote(fmt.Sprintf("h=%s,P=%s,u=%s,D=%s,t=%s", target.Host, target.Port, target.User, target.Database, target.Table)),
+			"--ask-pass",
+			"--chunk-size=1000",
+			"--max-load=Threads_running=25",
+			"--critical-load=Threads_running=100",
+		}
+		if meta.ForeignKeyExist {
+			args = append(args, "--alter-foreign-keys-method=auto")
+		}
+		if meta.TriggerExist {
+			args = append(args, "--preserve-triggers")
+		}
+		plan.Command = strings.Join(append(args, "--execute"), " ")
+	}
+	return plan
+}
+
+// shellQuote 用单引号包裹参数，参数中的单引号转义为 '"'"'
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
@@ -0,0 +1,99 @@
+package sqlrisk
+
+import (
+	"testing"
+
+	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/parser/ast"
+	"github.com/sunkaimr/sql-risk/policy"
+)
+
+func TestNewOSCPlan(t *testing.T) {
+	target := OSCTarget{Host: "10.0.0.1", Port: "3306", User: "dba", Database: "test", Table: "student"}
+
+	tests := []struct {
+		name     string
+		sql      string
+		meta     DDLTableMeta
+		tool     string
+		command  string
+		blockers int
+	}{
+		{
+			name: "test001",
+			sql:  "ALTER TABLE student ADD COLUMN age INT NOT NULL DEFAULT 0 COMMENT '年龄', ALGORITHM=COPY",
+			meta: DDLTableMeta{PrimaryKeyExist: true},
+			tool: OSCToolGhost,
+			command: "gh-ost --host='10.0.0.1' --port='3306' --user='dba' --ask-pass --database='test' --table='student' " +
+				`--alter='ADD COLUMN ` + "`age`" + ` INT NOT NULL DEFAULT 0 COMMENT '"'"'年龄'"'"'' ` +
+				"--allow-on-master --chunk-size=1000 --max-load=Threads_running=25 --critical-load=Threads_running=100 " +
+				"--initially-drop-ghost-table --initially-drop-old-table --execute",
+		},
+		{
+			name: "test002",
+			sql:  "ALTER TABLE student MODIFY COLUMN name VARCHAR(64), ADD INDEX idx_name (name)",
+			meta: DDLTableMeta{PrimaryKeyExist: true, ForeignKeyExist: true, TriggerExist: true},
+			tool: OSCToolPtOSC,
+			command: "pt-online-schema-change --alter 'MODIFY COLUMN `name` VARCHAR(64), ADD INDEX `idx_name`(`name`)' " +
+				"'h=10.0.0.1,P=3306,u=dba,D=test,t=student' --ask-pass --chunk-size=1000 " +
+				"--max-load=Threads_running=25 --critical-load=Threads_running=100 " +
+				"--alter-foreign-keys-method=auto --preserve-triggers --execute",
+			blockers: 2,
+		},
+		{
+			name:     "test003",
+			sql:      "ALTER TABLE student ADD COLUMN age INT",
+			meta:     DDLTableMeta{PrimaryKeyExist: false},
+			blockers: 1,
+		},
+		{
+			name:     "test004",
+			sql:      "ALTER TABLE student RENAME TO pupil, LOCK=NONE",
+			meta:     DDLTableMeta{PrimaryKeyExist: true},
+			blockers: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stmt, err := parser.New().ParseOneStmt(test.sql, "", "")
+			if err != nil {
+				t.Fatalf("parse %q failed, %s", test.sql, err)
+			}
+			got := NewOSCPlan(stmt.(*ast.AlterTableStmt), target, test.meta, "test")
+			if got.Tool != test.tool || got.Command != test.command || len(got.Blockers) != test.blockers {
+				t.Fatalf("NewOSCPlan(%q) failed, got:%+v, want tool:%s command:%s blockers:%d",
+					test.sql, got, test.tool, test.command, test.blockers)
+			}
+		})
+	}
+}
+
+func TestGenerateOSCPlan(t *testing.T) {
+	tests := []struct {
+		name   string
+		sql    string
+		items  []ItemValue
+		reason string
+	}{
+		{"test001", "ALTER TABLE student ADD COLUMN age INT", []ItemValue{{ID: policy.TabSize.ID, Value: 4096}}, "表大小4096MB超过2048MB"},
+		{"test002", "ALTER TABLE student ADD COLUMN age INT", []ItemValue{{ID: policy.TabSize.ID, Value: 10}, {ID: policy.DDLAlgorithm.ID, Value: DDLAlgorithmCopy}}, "DDL需要以COPY算法执行，期间阻塞DML"},
+		{"test003", "ALTER TABLE student ADD COLUMN age INT", []ItemValue{{ID: policy.TabSize.ID, Value: 10}}, ""},
+		{"test004", "DELETE FROM student", []ItemValue{{ID: policy.TabSize.ID, Value: 4096}}, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := NewSqlRisk("", "10.0.0.1", "", "3306", "dba", "", "test", test.sql, nil)
+			r.ItemValues = test.items
+			got := r.GenerateOSCPlan()
+			reason := ""
+			if got != nil {
+				reason = got.Reason
+			}
+			if reason != test.reason {
+				t.Fatalf("GenerateOSCPlan(%q) failed, got reason:%q, want:%q", test.sql, reason, test.reason)
+			}
+		})
+	}
+}
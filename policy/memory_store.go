@@ -0,0 +1,65 @@
+package policy
+
+import (
+	"fmt"
+)
+
+// MemoryStore 策略只保存在内存中，适用于命令行工具和测试等不需要持久化策略的场景
+type MemoryStore struct {
+	Policies []Policy
+}
+
+// Init 策略为空时生成默认策略
+func (c *MemoryStore) Init() error {
+	policies := c.Policies
+	if len(policies) == 0 {
+		policies = GenerateDefaultPolicy()
+	}
+
+	err := c.PolicyWriter(policies)
+	if err != nil {
+		return fmt.Errorf("write default ploicy failed, %s", err)
+	}
+	return nil
+}
+
+// PolicyReader 从内存读取策略
+func (c *MemoryStore) PolicyReader() ([]Policy, error) {
+	policyMeta = c.Policies
+	return c.Policies, nil
+}
+
+// PolicyWriter 校验策略并生成expr表达式后保存到内存
+func (c *MemoryStore) PolicyWriter(policies []Policy) error {
+	var err error
+	// 生成策略名字
+	for i, p := range policies {
+		policies[i].ID = i
+		if p.Type != AggRule || p.RuleID != RuleMatch.ID || p.Name != "" {
+			continue
+		}
+		policies[i].Name = generatePolicyName(p, policies)
+	}
+
+	// 校验策略
+	for _, p := range policies {
+		err = ValidatePolicy(p)
+		if err != nil {
+			return fmt.Errorf("policy(%s) validate failed, %s", p.PolicyID, err)
+		}
+	}
+
+	// 生成expr表达式
+	policies, err = GeneratePolicyExpr(policies)
+	if err != nil {
+		return fmt.Errorf("generate policy expr failed, %s", err)
+	}
+
+	operateTypeMeta = GenerateOperateTypeMeta()
+	actionTypeMeta = GenerateActionTypeMeta()
+	keyWordTypeMeta = GenerateKeyWordTypeMeta()
+	ruleMeta = GenerateRuleMeta()
+	policyMeta = policies
+	c.Policies = policies
+	return nil
+}
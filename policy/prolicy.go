@@ -126,7 +126,7 @@ func GenerateRuleMeta() []RuleMeta {
 			Type:        BasicRule,
 			ValueType:   RuleValueTypeKeyWord,
 			Operator:    []OperatorType{RuleOperatorEQ, RuleOperatorNE},
-			Description: "SQL的关键字",
+			Description: "SQL的关键字，语句包含多个关键字时（如ALTER TABLE的多个子句）匹配其中任意一个即可",
 		},
 		// TableSize	BASIC	int	<,<=,==,>,>=,between
 		{
@@ -218,6 +218,258 @@ func GenerateRuleMeta() []RuleMeta {
 			Operator:    []OperatorType{RuleOperatorLT, RuleOperatorLE, RuleOperatorGT, RuleOperatorGE, RuleOperatorBETWEEN},
 			Description: "获取当前集群最近5分钟内CPU的使用率",
 		},
+		// DCLUser	BASIC	string	!=,==
+		{
+			ID:          DCLUser.ID,
+			Name:        DCLUser.Name,
+			Type:        BasicRule,
+			ValueType:   RuleValueTypeString,
+			Operator:    []OperatorType{RuleOperatorEQ, RuleOperatorNE},
+			Description: "授权和用户管理语句涉及的用户，格式: 'user'@'host'，多个用户以逗号分隔",
+		},
+		// PrivilegeScope	BASIC	string	!=,==
+		{
+			ID:          PrivilegeScope.ID,
+			Name:        PrivilegeScope.Name,
+			Type:        BasicRule,
+			ValueType:   RuleValueTypeString,
+			Operator:    []OperatorType{RuleOperatorEQ, RuleOperatorNE},
+			Description: "GRANT和REVOKE的权限范围: global, database, table",
+		},
+		// GrantAll	BASIC	bool	!=,==
+		{
+			ID:          GrantAll.ID,
+			Name:        GrantAll.Name,
+			Type:        BasicRule,
+			ValueType:   RuleValueTypeBool,
+			Operator:    []OperatorType{RuleOperatorEQ, RuleOperatorNE},
+			Description: "判断是否授予或回收所有权限(ALL PRIVILEGES)",
+		},
+		// WithGrantOption	BASIC	bool	!=,==
+		{
+			ID:          WithGrantOption.ID,
+			Name:        WithGrantOption.Name,
+			Type:        BasicRule,
+			ValueType:   RuleValueTypeBool,
+			Operator:    []OperatorType{RuleOperatorEQ, RuleOperatorNE},
+			Description: "判断授权时是否带有WITH GRANT OPTION",
+		},
+		// AnyHost	BASIC	bool	!=,==
+		{
+			ID:          AnyHost.ID,
+			Name:        AnyHost.Name,
+			Type:        BasicRule,
+			ValueType:   RuleValueTypeBool,
+			Operator:    []OperatorType{RuleOperatorEQ, RuleOperatorNE},
+			Description: "判断授权或创建用户时主机是否为'%'",
+		},
+		// DDLAlgorithm	BASIC	string	!=,==
+		{
+			ID:          DDLAlgorithm.ID,
+			Name:        DDLAlgorithm.Name,
+			Type:        BasicRule,
+			ValueType:   RuleValueTypeString,
+			Operator:    []OperatorType{RuleOperatorEQ, RuleOperatorNE},
+			Description: "预测DDL执行时使用的算法: INSTANT, INPLACE, COPY",
+		},
+		// DDLLock	BASIC	string	!=,==
+		{
+			ID:          DDLLock.ID,
+			Name:        DDLLock.Name,
+			Type:        BasicRule,
+			ValueType:   RuleValueTypeString,
+			Operator:    []OperatorType{RuleOperatorEQ, RuleOperatorNE},
+			Description: "预测DDL执行期间的锁级别: NONE 不阻塞DML, SHARED 阻塞写, EXCLUSIVE 阻塞读写",
+		},
+		// DDLRebuild	BASIC	bool	!=,==
+		{
+			ID:          DDLRebuild.ID,
+			Name:        DDLRebuild.Name,
+			Type:        BasicRule,
+			ValueType:   RuleValueTypeBool,
+			Operator:    []OperatorType{RuleOperatorEQ, RuleOperatorNE},
+			Description: "预测DDL是否需要重建表",
+		},
+		// DDLDuration	BASIC	int	<,<=,==,>,>=,between
+		{
+			ID:          DDLDuration.ID,
+			Name:        DDLDuration.Name,
+			Type:        BasicRule,
+			ValueType:   RuleValueTypeInt,
+			Operator:    []OperatorType{RuleOperatorLT, RuleOperatorLE, RuleOperatorGT, RuleOperatorGE, RuleOperatorBETWEEN},
+			Description: "根据表大小和行数预测DDL的耗时，单位s",
+		},
+		// ReplicaLag	BASIC	int	<,<=,==,>,>=,between
+		{
+			ID:          ReplicaLag.ID,
+			Name:        ReplicaLag.Name,
+			Type:        BasicRule,
+			ValueType:   RuleValueTypeInt,
+			Operator:    []OperatorType{RuleOperatorLT, RuleOperatorLE, RuleOperatorGT, RuleOperatorGE, RuleOperatorBETWEEN},
+			Description: "所有从库中最大的复制延迟(Seconds_Behind_Source)，单位s",
+		},
+		// EstimatedBinlogMB	BASIC	int	<,<=,==,>,>=,between
+		{
+			ID:          EstimatedBinlogMB.ID,
+			Name:        EstimatedBinlogMB.Name,
+			Type:        BasicRule,
+			ValueType:   RuleValueTypeInt,
+			Operator:    []OperatorType{RuleOperatorLT, RuleOperatorLE, RuleOperatorGT, RuleOperatorGE, RuleOperatorBETWEEN},
+			Description: "根据影响行数和表的平均行长度预估DML产生的binlog大小，单位MB",
+		},
+		// LockBlocked	BASIC	bool	!=,==
+		{
+			ID:          LockBlocked.ID,
+			Name:        LockBlocked.Name,
+			Type:        BasicRule,
+			ValueType:   RuleValueTypeBool,
+			Operator:    []OperatorType{RuleOperatorEQ, RuleOperatorNE},
+			Description: "表上是否存在持有冲突的元数据锁、InnoDB锁或未提交空闲事务的会话，SQL执行时需要等待这些会话",
+		},
+		// LockWaitSeconds	BASIC	int	<,<=,==,>,>=,between
+		{
+			ID:          LockWaitSeconds.ID,
+			Name:        LockWaitSeconds.Name,
+			Type:        BasicRule,
+			ValueType:   RuleValueTypeInt,
+			Operator:    []OperatorType{RuleOperatorLT, RuleOperatorLE, RuleOperatorGT, RuleOperatorGE, RuleOperatorBETWEEN},
+			Description: "阻塞会话中持续时间最长的会话已经持续的时间，单位s",
+		},
+		// ServerVersion	BASIC	int	<,<=,==,>,>=,between
+		{
+			ID:          ServerVersion.ID,
+			Name:        ServerVersion.Name,
+			Type:        BasicRule,
+			ValueType:   RuleValueTypeInt,
+			Operator:    []OperatorType{RuleOperatorLT, RuleOperatorLE, RuleOperatorGT, RuleOperatorGE, RuleOperatorBETWEEN},
+			Description: "数据库版本号，主版本*10000+次版本*100+修订号，如8.0.32为80032",
+		},
+		// ServerFlavor	BASIC	string	!=,==
+		{
+			ID:          ServerFlavor.ID,
+			Name:        ServerFlavor.Name,
+			Type:        BasicRule,
+			ValueType:   RuleValueTypeString,
+			Operator:    []OperatorType{RuleOperatorEQ, RuleOperatorNE},
+			Description: "数据库分支: MySQL, MariaDB, TiDB, Aurora, TencentCDB",
+		},
+		// BinlogFormat	BASIC	string	!=,==
+		{
+			ID:          BinlogFormat.ID,
+			Name:        BinlogFormat.Name,
+			Type:        BasicRule,
+			ValueType:   RuleValueTypeString,
+			Operator:    []OperatorType{RuleOperatorEQ, RuleOperatorNE},
+			Description: "binlog_format: ROW, STATEMENT, MIXED",
+		},
+		// ReadOnly	BASIC	bool	!=,==
+		{
+			ID:          ReadOnly.ID,
+			Name:        ReadOnly.Name,
+			Type:        BasicRule,
+			ValueType:   RuleValueTypeBool,
+			Operator:    []OperatorType{RuleOperatorEQ, RuleOperatorNE},
+			Description: "数据源是否开启了read_only或super_read_only",
+		},
+		// LockWaitTimeout	BASIC	int	<,<=,==,>,>=,between
+		{
+			ID:          LockWaitTimeout.ID,
+			Name:        LockWaitTimeout.Name,
+			Type:        BasicRule,
+			ValueType:   RuleValueTypeInt,
+			Operator:    []OperatorType{RuleOperatorLT, RuleOperatorLE, RuleOperatorGT, RuleOperatorGE, RuleOperatorBETWEEN},
+			Description: "全局lock_wait_timeout，DDL等待元数据锁的最长时间，单位s",
+		},
+		// InstantDDL	BASIC	bool	!=,==
+		{
+			ID:          InstantDDL.ID,
+			Name:        InstantDDL.Name,
+			Type:        BasicRule,
+			ValueType:   RuleValueTypeBool,
+			Operator:    []OperatorType{RuleOperatorEQ, RuleOperatorNE},
+			Description: "数据库版本是否支持INSTANT算法的DDL",
+		},
+		// RenameColumn	BASIC	bool	!=,==
+		{
+			ID:          RenameColumn.ID,
+			Name:        RenameColumn.Name,
+			Type:        BasicRule,
+			ValueType:   RuleValueTypeBool,
+			Operator:    []OperatorType{RuleOperatorEQ, RuleOperatorNE},
+			Description: "数据库版本是否支持ALTER TABLE ... RENAME COLUMN",
+		},
+		// TiDBTxnSizeMB	BASIC	int	<,<=,==,>,>=,between
+		{
+			ID:          TiDBTxnSizeMB.ID,
+			Name:        TiDBTxnSizeMB.Name,
+			Type:        BasicRule,
+			ValueType:   RuleValueTypeInt,
+			Operator:    []OperatorType{RuleOperatorLT, RuleOperatorLE, RuleOperatorGT, RuleOperatorGE, RuleOperatorBETWEEN},
+			Description: "TiDB中DML事务的预估大小，按影响行数×平均行长度估算，单位MB",
+		},
+		// TiDBTxnOverLimit	BASIC	bool	!=,==
+		{
+			ID:          TiDBTxnOverLimit.ID,
+			Name:        TiDBTxnOverLimit.Name,
+			Type:        BasicRule,
+			ValueType:   RuleValueTypeBool,
+			Operator:    []OperatorType{RuleOperatorEQ, RuleOperatorNE},
+			Description: "TiDB中DML事务的预估大小是否超过performance.txn-total-size-limit",
+		},
+		// TiDBPendingDDLJobs	BASIC	int	<,<=,==,>,>=,between
+		{
+			ID:          TiDBPendingDDLJobs.ID,
+			Name:        TiDBPendingDDLJobs.Name,
+			Type:        BasicRule,
+			ValueType:   RuleValueTypeInt,
+			Operator:    []OperatorType{RuleOperatorLT, RuleOperatorLE, RuleOperatorGT, RuleOperatorGE, RuleOperatorBETWEEN},
+			Description: "TiDB中排队或正在执行的DDL任务数，DDL任务串行执行",
+		},
+		// ProjectedFreeDiskPct	BASIC	int	<,<=,==,>,>=,between
+		{
+			ID:          ProjectedFreeDiskPct.ID,
+			Name:        ProjectedFreeDiskPct.Name,
+			Type:        BasicRule,
+			ValueType:   RuleValueTypeInt,
+			Operator:    []OperatorType{RuleOperatorLT, RuleOperatorLE, RuleOperatorGT, RuleOperatorGE, RuleOperatorBETWEEN},
+			Description: "按磁盘增长趋势和SQL所需的空间（binlog、undo、COPY算法的临时表）预测执行后的磁盘剩余空间占总空间的百分比",
+		},
+		// BusyPeriod	BASIC	bool	!=,==
+		{
+			ID:          BusyPeriod.ID,
+			Name:        BusyPeriod.Name,
+			Type:        BasicRule,
+			ValueType:   RuleValueTypeBool,
+			Operator:    []OperatorType{RuleOperatorEQ, RuleOperatorNE},
+			Description: "按过去7天CPU、QPS、磁盘IO统计各时段的负载，判断当前时段是否明显高于全天平均负载",
+		},
+		// SwapTable	BASIC	bool	!=,==
+		{
+			ID:          SwapTable.ID,
+			Name:        SwapTable.Name,
+			Type:        BasicRule,
+			ValueType:   RuleValueTypeBool,
+			Operator:    []OperatorType{RuleOperatorEQ, RuleOperatorNE},
+			Description: "按工单中语句的顺序模拟表结构，RENAME TABLE将工单中新建并已写入数据的影子表切换为原表名，原表重命名保留",
+		},
+		// SwapEmptyTable	BASIC	bool	!=,==
+		{
+			ID:          SwapEmptyTable.ID,
+			Name:        SwapEmptyTable.Name,
+			Type:        BasicRule,
+			ValueType:   RuleValueTypeBool,
+			Operator:    []OperatorType{RuleOperatorEQ, RuleOperatorNE},
+			Description: "按工单中语句的顺序模拟表结构，RENAME TABLE将工单中新建但没有写入过数据的表切换为原表名",
+		},
+		// BackfillAfterAlter	BASIC	bool	!=,==
+		{
+			ID:          BackfillAfterAlter.ID,
+			Name:        BackfillAfterAlter.Name,
+			Type:        BasicRule,
+			ValueType:   RuleValueTypeBool,
+			Operator:    []OperatorType{RuleOperatorEQ, RuleOperatorNE},
+			Description: "按工单中语句的顺序模拟表结构，不带WHERE条件的UPDATE更新了工单中前面的语句新增的列",
+		},
 		// RuleMatch	AGG	BASIC	ALL,ANY
 		{
 			ID:          RuleMatch.ID,
@@ -407,6 +659,160 @@ func GenerateDefaultPolicy() []Policy {
 			Description: "RENAME操作",
 			Suggestion:  "",
 		},
+		{
+			PolicyID:    "OPE.GRANT.000",
+			Name:        "GRANT动作类型",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      Action.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       Action.V.Grant,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    10,
+			Description: "GRANT操作",
+			Suggestion:  "",
+		},
+		{
+			PolicyID:    "OPE.REVOKE.000",
+			Name:        "REVOKE动作类型",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      Action.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       Action.V.Revoke,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    10,
+			Description: "REVOKE操作",
+			Suggestion:  "",
+		},
+		{
+			PolicyID:    "OPE.USER.000",
+			Name:        "USER动作类型",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      Action.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       Action.V.User,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    10,
+			Description: "用户管理操作",
+			Suggestion:  "",
+		},
+		{
+			PolicyID:    "OPE.FLUSH.000",
+			Name:        "FLUSH动作类型",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      Action.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       Action.V.Flush,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    10,
+			Description: "FLUSH操作",
+			Suggestion:  "",
+		},
+		{
+			PolicyID:    "OPE.SET.000",
+			Name:        "SET动作类型",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      Action.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       Action.V.Set,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    10,
+			Description: "SET操作",
+			Suggestion:  "",
+		},
+		{
+			PolicyID:    "OPE.USE.000",
+			Name:        "USE动作类型",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      Action.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       Action.V.Use,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    10,
+			Description: "USE操作",
+			Suggestion:  "",
+		},
+		{
+			PolicyID:    "OPE.LOCK.000",
+			Name:        "LOCK动作类型",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      Action.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       Action.V.Lock,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    10,
+			Description: "LOCK操作",
+			Suggestion:  "",
+		},
+		{
+			PolicyID:    "OPE.KILL.000",
+			Name:        "KILL动作类型",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      Action.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       Action.V.Kill,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    10,
+			Description: "KILL操作",
+			Suggestion:  "",
+		},
+		{
+			PolicyID:    "OPE.OPTIMIZE.000",
+			Name:        "OPTIMIZE动作类型",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      Action.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       Action.V.Optimize,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    10,
+			Description: "OPTIMIZE操作",
+			Suggestion:  "",
+		},
+		{
+			PolicyID:    "OPE.ANALYZE.000",
+			Name:        "ANALYZE动作类型",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      Action.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       Action.V.Analyze,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    10,
+			Description: "ANALYZE操作",
+			Suggestion:  "",
+		},
+		{
+			PolicyID:    "OPE.LOAD.000",
+			Name:        "LOAD动作类型",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      Action.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       Action.V.Load,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    10,
+			Description: "LOAD操作",
+			Suggestion:  "",
+		},
 		// KeyWord
 		{
 			PolicyID:    "OPE.UNKNOWN.001",
@@ -548,6 +954,20 @@ func GenerateDefaultPolicy() []Policy {
 			Description: "删除表",
 			Suggestion:  "删除表时请确认表已经不再使用，必要时请提前做好数据备份",
 		},
+		{
+			PolicyID:    "OPE.DROP.009",
+			Name:        "删除事件",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.DropEvent,
+			Level:       comm.Fatal,
+			Special:     false,
+			Priority:    999,
+			Description: "删除事件",
+			Suggestion:  "删除事件是禁止的操作，如有疑问请联系管理员",
+		},
 		{
 			PolicyID:    "OPE.TRUNCATE.001",
 			Name:        "截断表",
@@ -689,13 +1109,27 @@ func GenerateDefaultPolicy() []Policy {
 			Suggestion:  "触发器操作是禁止的操作，如有疑问请联系管理员",
 		},
 		{
-			PolicyID:    "OPE.ALTER.001",
-			Name:        "alter操作",
+			PolicyID:    "OPE.CREATE.010",
+			Name:        "创建事件",
 			Enable:      true,
 			Type:        BasicRule,
 			RuleID:      KeyWord.ID,
 			Operator:    RuleOperatorEQ,
-			Value:       KeyWord.V.Alter,
+			Value:       KeyWord.V.CreateEvent,
+			Level:       comm.Fatal,
+			Special:     false,
+			Priority:    999,
+			Description: "创建事件",
+			Suggestion:  "事件调度器中的定时任务难以审计和维护，创建事件是禁止的操作，如有疑问请联系管理员",
+		},
+		{
+			PolicyID:    "OPE.ALTER.001",
+			Name:        "alter操作",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.Alter,
 			Level:       comm.High,
 			Special:     false,
 			Priority:    60,
@@ -856,6 +1290,216 @@ func GenerateDefaultPolicy() []Policy {
 			Description: "删除索引",
 			Suggestion:  "",
 		},
+		{
+			PolicyID:    "OPE.ALTER.013",
+			Name:        "修改存储过程",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.AlterProcedure,
+			Level:       comm.Fatal,
+			Special:     false,
+			Priority:    999,
+			Description: "修改存储过程的特性",
+			Suggestion:  "修改存储过程是禁止的操作，如有疑问请联系管理员",
+		},
+		{
+			PolicyID:    "OPE.ALTER.014",
+			Name:        "修改函数",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.AlterFunc,
+			Level:       comm.Fatal,
+			Special:     false,
+			Priority:    999,
+			Description: "修改函数的特性",
+			Suggestion:  "修改函数是禁止的操作，如有疑问请联系管理员",
+		},
+		{
+			PolicyID:    "OPE.ALTER.015",
+			Name:        "修改事件",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.AlterEvent,
+			Level:       comm.Fatal,
+			Special:     false,
+			Priority:    999,
+			Description: "修改事件",
+			Suggestion:  "修改事件是禁止的操作，如有疑问请联系管理员",
+		},
+		{
+			PolicyID:    "OPE.ALTER.016",
+			Name:        "重命名表",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.AlertRenameTab,
+			Level:       comm.High,
+			Special:     false,
+			Priority:    60,
+			Description: "ALTER TABLE ... RENAME修改表名",
+			Suggestion:  "重命名表会导致依赖原表名的应用和视图报错，请确认已同步修改",
+		},
+		{
+			PolicyID:    "OPE.ALTER.017",
+			Name:        "修改存储引擎",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.AlertEngine,
+			Level:       comm.High,
+			Special:     false,
+			Priority:    60,
+			Description: "修改表的存储引擎",
+			Suggestion:  "修改存储引擎会以COPY方式重建表，期间阻塞写入，大表建议使用gh-ost或pt-osc",
+		},
+		{
+			PolicyID:    "OPE.ALTER.018",
+			Name:        "转换字符集",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.AlertConvert,
+			Level:       comm.High,
+			Special:     false,
+			Priority:    60,
+			Description: "CONVERT TO CHARACTER SET转换表中所有字符列的字符集",
+			Suggestion:  "转换字符集会重建表并改写全部数据，可能导致索引长度超限或数据截断",
+		},
+		{
+			PolicyID:    "OPE.ALTER.019",
+			Name:        "修改默认字符集",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.AlertCharset,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    60,
+			Description: "修改表的默认字符集",
+			Suggestion:  "只影响之后新增的列，不会修改已有列的字符集",
+		},
+		{
+			PolicyID:    "OPE.ALTER.020",
+			Name:        "添加分区",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.AlertAddPart,
+			Level:       comm.High,
+			Special:     false,
+			Priority:    60,
+			Description: "给表添加分区",
+			Suggestion:  "",
+		},
+		{
+			PolicyID:    "OPE.ALTER.021",
+			Name:        "删除分区",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.AlertDropPart,
+			Level:       comm.Fatal,
+			Special:     false,
+			Priority:    999,
+			Description: "删除分区会同时删除分区内的所有数据",
+			Suggestion:  "删除分区的数据无法恢复，如有疑问请联系管理员",
+		},
+		{
+			PolicyID:    "OPE.ALTER.022",
+			Name:        "清空分区",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.AlertTruncPart,
+			Level:       comm.Fatal,
+			Special:     false,
+			Priority:    999,
+			Description: "清空分区内的所有数据",
+			Suggestion:  "清空分区的数据无法恢复，如有疑问请联系管理员",
+		},
+		{
+			PolicyID:    "OPE.ALTER.023",
+			Name:        "修改分区",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.AlertPartition,
+			Level:       comm.High,
+			Special:     false,
+			Priority:    60,
+			Description: "重组、合并、交换、重建分区或修改表的分区方式",
+			Suggestion:  "此类操作会迁移分区数据或重建表，耗时较长",
+		},
+		{
+			PolicyID:    "OPE.ALTER.024",
+			Name:        "指定DDL算法",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.AlertAlgorithm,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    50,
+			Description: "ALTER TABLE中指定了ALGORITHM",
+			Suggestion:  "",
+		},
+		{
+			PolicyID:    "OPE.ALTER.025",
+			Name:        "使用COPY算法",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.AlertAlgCopy,
+			Level:       comm.High,
+			Special:     false,
+			Priority:    60,
+			Description: "ALTER TABLE中指定了ALGORITHM=COPY",
+			Suggestion:  "COPY算法会复制整张表且期间阻塞写入，大表建议使用gh-ost或pt-osc",
+		},
+		{
+			PolicyID:    "OPE.ALTER.026",
+			Name:        "指定DDL锁级别",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.AlertLock,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    50,
+			Description: "ALTER TABLE中指定了LOCK",
+			Suggestion:  "",
+		},
+		{
+			PolicyID:    "OPE.ALTER.027",
+			Name:        "使用排他锁",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.AlertLockExcl,
+			Level:       comm.High,
+			Special:     false,
+			Priority:    60,
+			Description: "ALTER TABLE中指定了LOCK=EXCLUSIVE",
+			Suggestion:  "执行期间会阻塞表的读写，请在业务低峰期执行",
+		},
 		{
 			PolicyID:    "OPE.INSERT.001",
 			Name:        "插入数据",
@@ -997,256 +1641,833 @@ func GenerateDefaultPolicy() []Policy {
 			Suggestion:  "注意：影响行数是通过EXPLAIN得到的结果与最终的数据修改量可能会有偏差",
 		},
 		{
-			PolicyID:    "OPE.AFFECTROWS.003",
-			Name:        "影响行数小于等于2w",
-			Enable:      false,
+			PolicyID:    "OPE.AFFECTROWS.003",
+			Name:        "影响行数小于等于2w",
+			Enable:      false,
+			Type:        BasicRule,
+			RuleID:      AffectRows.ID,
+			Operator:    RuleOperatorLE,
+			Value:       20000,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    50,
+			Description: "DELETE或UPDATE操作的影响行数是否小于2w",
+			Suggestion:  "注意：影响行数是通过EXPLAIN得到的结果与最终的数据修改量可能会有偏差",
+		},
+		{
+			PolicyID:    "OPE.AFFECTROWS.004",
+			Name:        "影响行数大于2w",
+			Enable:      false,
+			Type:        BasicRule,
+			RuleID:      AffectRows.ID,
+			Operator:    RuleOperatorGT,
+			Value:       20000,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    70,
+			Description: "DELETE或UPDATE操作的影响行数是否大于2w",
+			Suggestion:  "注意：影响行数是通过EXPLAIN得到的结果与最终的数据修改量可能会有偏差",
+		},
+		{
+			PolicyID:    "OPE.AFFECTROWS.005",
+			Name:        "影响行数小于等于10w",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      AffectRows.ID,
+			Operator:    RuleOperatorLE,
+			Value:       100000,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    70,
+			Description: "DELETE或UPDATE操作的影响行数是否小于等于10w",
+			Suggestion:  "注意：影响行数是通过EXPLAIN得到的结果与最终的数据修改量可能会有偏差",
+		},
+		{
+			PolicyID:    "RUN.CAPACITY.001",
+			Name:        "表大小大于2G",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      TabSize.ID,
+			Operator:    RuleOperatorGT,
+			Value:       2048,
+			Level:       comm.High,
+			Special:     false,
+			Priority:    50,
+			Description: "大表",
+			Suggestion:  "",
+		},
+		{
+			PolicyID:    "RUN.CAPACITY.002",
+			Name:        "表大小小于等于2G",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      TabSize.ID,
+			Operator:    RuleOperatorLE,
+			Value:       2048,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    50,
+			Description: "小表",
+			Suggestion:  "",
+		},
+		{
+			PolicyID:    "RUN.CAPACITY.003",
+			Name:        "表行数小于等于10w",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      TabRows.ID,
+			Operator:    RuleOperatorLE,
+			Value:       100000,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    10,
+			Description: "表行数小于等于10w",
+			Suggestion:  "",
+		},
+		{
+			PolicyID:    "RUN.CAPACITY.004",
+			Name:        "表行数大于10w",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      TabRows.ID,
+			Operator:    RuleOperatorGT,
+			Value:       100000,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    10,
+			Description: "表行数大于10w",
+			Suggestion:  "",
+		},
+		{
+			PolicyID:    "RUN.CAPACITY.005",
+			Name:        "表行数小于等于2w",
+			Enable:      false,
+			Type:        BasicRule,
+			RuleID:      TabRows.ID,
+			Operator:    RuleOperatorLE,
+			Value:       20000,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    10,
+			Description: "表行数小于等于2w",
+			Suggestion:  "",
+		},
+		{
+			PolicyID:    "RUN.CAPACITY.006",
+			Name:        "磁盘容量充足",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      DiskSufficient.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       true,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    10,
+			Description: "磁盘可用空间大于表大小",
+			Suggestion:  "磁盘可用空间大于表大小，磁盘容量充足可以进行DDL操作",
+		},
+		{
+			PolicyID:    "RUN.CAPACITY.007",
+			Name:        "磁盘容量不充足",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      DiskSufficient.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       false,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    10,
+			Description: "磁盘可用空间小于表大小",
+			Suggestion:  "磁盘可用空间小于表大小，此时磁盘容量不足以支持DDL操作",
+		},
+		{
+			PolicyID:    "RUN.TABINFO.001",
+			Name:        "表存在主键",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      PrimaryKeyExist.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       true,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    10,
+			Description: "表存在主键",
+			Suggestion:  "",
+		},
+		{
+			PolicyID:    "RUN.TABINFO.002",
+			Name:        "表不存在主键",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      PrimaryKeyExist.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       false,
+			Level:       comm.Fatal,
+			Special:     false,
+			Priority:    999,
+			Description: "表不存在主键",
+			Suggestion:  "当表没有设置主键时不建议对表进行操作，你可以先给表添加主键再进行其他操作",
+		},
+		{
+			PolicyID:    "RUN.TABINFO.003",
+			Name:        "表存在外键",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      ForeignKeyExist.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       true,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    10,
+			Description: "表存在外键",
+			Suggestion:  "",
+		},
+		{
+			PolicyID:    "RUN.TABINFO.004",
+			Name:        "表不存在外键",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      ForeignKeyExist.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       false,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    10,
+			Description: "表不存在外键",
+			Suggestion:  "",
+		},
+		{
+			PolicyID:    "RUN.TABINFO.005",
+			Name:        "表存在触发器",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      TriggerExist.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       true,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    10,
+			Description: "表存在触发器",
+			Suggestion:  "",
+		},
+		{
+			PolicyID:    "RUN.TABINFO.006",
+			Name:        "表不存在触发器",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      TriggerExist.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       false,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    10,
+			Description: "表不存在触发器",
+			Suggestion:  "",
+		},
+		{
+			PolicyID:    "RUN.TABINFO.007",
+			Name:        "where条件中存在索引列",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      IndexExistInWhere.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       true,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    10,
+			Description: "where条件中跟的各列，至少有一列存在索引",
+			Suggestion:  "",
+		},
+		{
+			PolicyID:    "RUN.TABINFO.008",
+			Name:        "where条件中不存在索引列",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      IndexExistInWhere.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       false,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    10,
+			Description: "where条件中跟的各列，都不存在索引",
+			Suggestion:  "",
+		},
+		// 会话和管理语句
+		{
+			PolicyID:    "OPE.SET.001",
+			Name:        "设置会话变量",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.SetVariable,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    60,
+			Description: "设置会话级变量",
+			Suggestion:  "",
+		},
+		{
+			PolicyID:    "OPE.SET.002",
+			Name:        "设置全局变量",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.SetGlobal,
+			Level:       comm.High,
+			Special:     false,
+			Priority:    100,
+			Description: "设置全局变量会影响整个实例",
+			Suggestion:  "修改全局变量会影响实例上的所有连接，建议由DBA来操作",
+		},
+		{
+			PolicyID:    "OPE.SET.003",
+			Name:        "关闭外键检查",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.DisableFKCheck,
+			Level:       comm.High,
+			Special:     false,
+			Priority:    100,
+			Description: "关闭外键检查(foreign_key_checks=0)",
+			Suggestion:  "关闭外键检查后写入的数据不再校验外键约束，可能导致数据不一致",
+		},
+		{
+			PolicyID:    "OPE.SET.004",
+			Name:        "关闭binlog",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.DisableBinlog,
+			Level:       comm.High,
+			Special:     false,
+			Priority:    100,
+			Description: "关闭binlog(sql_log_bin=0)",
+			Suggestion:  "关闭binlog后的变更不会同步到从库，会导致主从数据不一致",
+		},
+		{
+			PolicyID:    "OPE.USE.001",
+			Name:        "切换库",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.Use,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    60,
+			Description: "切换后续语句的默认库",
+			Suggestion:  "",
+		},
+		{
+			PolicyID:    "OPE.LOCK.001",
+			Name:        "锁表",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.LockTabs,
+			Level:       comm.High,
+			Special:     false,
+			Priority:    100,
+			Description: "显式锁表会阻塞其他会话对表的读写",
+			Suggestion:  "锁表期间业务对表的访问会被阻塞，请尽量缩短持有锁的时间",
+		},
+		{
+			PolicyID:    "OPE.LOCK.002",
+			Name:        "解锁表",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.UnlockTabs,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    60,
+			Description: "释放当前会话持有的表锁",
+			Suggestion:  "",
+		},
+		{
+			PolicyID:    "OPE.KILL.001",
+			Name:        "终止会话",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.Kill,
+			Level:       comm.High,
+			Special:     false,
+			Priority:    100,
+			Description: "终止数据库会话或正在执行的语句",
+			Suggestion:  "终止会话会导致其未提交的事务回滚，请确认会话可以被终止",
+		},
+		{
+			PolicyID:    "OPE.OPTIMIZE.001",
+			Name:        "优化表",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.OptimizeTab,
+			Level:       comm.High,
+			Special:     false,
+			Priority:    100,
+			Description: "OPTIMIZE TABLE会重建表",
+			Suggestion:  "重建表期间会占用大量IO和磁盘空间，大表请在业务低峰期操作",
+		},
+		{
+			PolicyID:    "OPE.ANALYZE.001",
+			Name:        "分析表",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.AnalyzeTab,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    60,
+			Description: "更新表的索引统计信息",
+			Suggestion:  "",
+		},
+		{
+			PolicyID:    "OPE.LOAD.001",
+			Name:        "导入数据",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.LoadData,
+			Level:       comm.High,
+			Special:     false,
+			Priority:    100,
+			Description: "从文件批量导入数据",
+			Suggestion:  "批量导入会产生大事务，建议分批导入",
+		},
+		// DCL
+		{
+			PolicyID:    "OPE.GRANT.001",
+			Name:        "授权",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.Grant,
+			Level:       comm.High,
+			Special:     false,
+			Priority:    60,
+			Description: "给用户授予权限",
+			Suggestion:  "授权前请确认权限遵循最小化原则",
+		},
+		{
+			PolicyID:    "OPE.REVOKE.001",
+			Name:        "回收权限",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.Revoke,
+			Level:       comm.High,
+			Special:     false,
+			Priority:    60,
+			Description: "回收用户的权限",
+			Suggestion:  "回收权限可能导致业务访问数据库失败，请确认权限已经不再使用",
+		},
+		{
+			PolicyID:    "OPE.USER.001",
+			Name:        "创建用户",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.CreateUser,
+			Level:       comm.High,
+			Special:     false,
+			Priority:    60,
+			Description: "创建数据库用户",
+			Suggestion:  "",
+		},
+		{
+			PolicyID:    "OPE.USER.002",
+			Name:        "删除用户",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.DropUser,
+			Level:       comm.Fatal,
+			Special:     false,
+			Priority:    999,
+			Description: "删除数据库用户",
+			Suggestion:  "删除用户是禁止的操作，如有疑问请联系管理员",
+		},
+		{
+			PolicyID:    "OPE.USER.003",
+			Name:        "修改用户",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.AlterUser,
+			Level:       comm.High,
+			Special:     false,
+			Priority:    60,
+			Description: "修改用户的认证方式、密码或资源限制",
+			Suggestion:  "修改用户可能导致业务访问数据库失败",
+		},
+		{
+			PolicyID:    "OPE.USER.004",
+			Name:        "修改密码",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.SetPassword,
+			Level:       comm.High,
+			Special:     false,
+			Priority:    60,
+			Description: "修改用户密码",
+			Suggestion:  "修改密码后需同步更新业务配置，否则会导致业务访问数据库失败",
+		},
+		{
+			PolicyID:    "OPE.FLUSH.001",
+			Name:        "刷新权限",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      KeyWord.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       KeyWord.V.FlushPrivileges,
+			Level:       comm.Low,
+			Special:     false,
+			Priority:    60,
+			Description: "重新加载权限表",
+			Suggestion:  "",
+		},
+		{
+			PolicyID:    "DCL.GRANT.001",
+			Name:        "授予所有权限",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      GrantAll.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       true,
+			Level:       comm.Fatal,
+			Special:     false,
+			Priority:    999,
+			Description: "授予用户所有权限(ALL PRIVILEGES)",
+			Suggestion:  "授予所有权限是禁止的操作，请按需授予最小权限，如有疑问请联系管理员",
+		},
+		{
+			PolicyID:    "DCL.GRANT.002",
+			Name:        "可转授权限",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      WithGrantOption.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       true,
+			Level:       comm.Fatal,
+			Special:     false,
+			Priority:    999,
+			Description: "授权时带有WITH GRANT OPTION，用户可以将自己的权限授予其他用户",
+			Suggestion:  "WITH GRANT OPTION是禁止的操作，如有疑问请联系管理员",
+		},
+		{
+			PolicyID:    "DCL.GRANT.003",
+			Name:        "全局授权",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      PrivilegeScope.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       comm.PrivilegeScopeGlobal,
+			Level:       comm.High,
+			Special:     false,
+			Priority:    100,
+			Description: "授予全局权限(*.*)",
+			Suggestion:  "全局权限作用于所有库，请尽量按库或表授权",
+		},
+		{
+			PolicyID:    "DCL.HOST.001",
+			Name:        "任意主机",
+			Enable:      true,
 			Type:        BasicRule,
-			RuleID:      AffectRows.ID,
-			Operator:    RuleOperatorLE,
-			Value:       20000,
-			Level:       comm.Low,
+			RuleID:      AnyHost.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       true,
+			Level:       comm.Fatal,
 			Special:     false,
-			Priority:    50,
-			Description: "DELETE或UPDATE操作的影响行数是否小于2w",
-			Suggestion:  "注意：影响行数是通过EXPLAIN得到的结果与最终的数据修改量可能会有偏差",
+			Priority:    999,
+			Description: "授权或创建用户时主机为'%'，允许从任意主机访问",
+			Suggestion:  "请限制用户可访问的主机或网段，如有疑问请联系管理员",
 		},
+		// Online DDL
 		{
-			PolicyID:    "OPE.AFFECTROWS.004",
-			Name:        "影响行数大于2w",
-			Enable:      false,
+			PolicyID:    "DDL.ALGORITHM.001",
+			Name:        "使用COPY算法",
+			Enable:      true,
 			Type:        BasicRule,
-			RuleID:      AffectRows.ID,
-			Operator:    RuleOperatorGT,
-			Value:       20000,
-			Level:       comm.Low,
+			RuleID:      DDLAlgorithm.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       "COPY",
+			Level:       comm.High,
 			Special:     false,
-			Priority:    70,
-			Description: "DELETE或UPDATE操作的影响行数是否大于2w",
-			Suggestion:  "注意：影响行数是通过EXPLAIN得到的结果与最终的数据修改量可能会有偏差",
+			Priority:    100,
+			Description: "DDL需要以COPY方式复制整张表，期间阻塞DML",
+			Suggestion:  "大表建议使用gh-ost或pt-osc执行",
 		},
 		{
-			PolicyID:    "OPE.AFFECTROWS.005",
-			Name:        "影响行数小于等于10w",
+			PolicyID:    "DDL.ALGORITHM.002",
+			Name:        "使用INSTANT算法",
 			Enable:      true,
 			Type:        BasicRule,
-			RuleID:      AffectRows.ID,
-			Operator:    RuleOperatorLE,
-			Value:       100000,
+			RuleID:      DDLAlgorithm.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       "INSTANT",
 			Level:       comm.Low,
 			Special:     false,
-			Priority:    70,
-			Description: "DELETE或UPDATE操作的影响行数是否小于等于10w",
-			Suggestion:  "注意：影响行数是通过EXPLAIN得到的结果与最终的数据修改量可能会有偏差",
+			Priority:    10,
+			Description: "DDL只修改元数据，可以立即完成",
+			Suggestion:  "",
 		},
 		{
-			PolicyID:    "RUN.CAPACITY.001",
-			Name:        "表大小大于2G",
+			PolicyID:    "DDL.LOCK.001",
+			Name:        "阻塞DML",
 			Enable:      true,
 			Type:        BasicRule,
-			RuleID:      TabSize.ID,
-			Operator:    RuleOperatorGT,
-			Value:       2048,
+			RuleID:      DDLLock.ID,
+			Operator:    RuleOperatorNE,
+			Value:       "NONE",
 			Level:       comm.High,
 			Special:     false,
-			Priority:    50,
-			Description: "大表",
-			Suggestion:  "",
+			Priority:    100,
+			Description: "DDL执行期间会阻塞表的写入或读写",
+			Suggestion:  "请在业务低峰期执行",
 		},
 		{
-			PolicyID:    "RUN.CAPACITY.002",
-			Name:        "表大小小于等于2G",
+			PolicyID:    "DDL.REBUILD.001",
+			Name:        "重建表",
 			Enable:      true,
 			Type:        BasicRule,
-			RuleID:      TabSize.ID,
-			Operator:    RuleOperatorLE,
-			Value:       2048,
-			Level:       comm.Low,
+			RuleID:      DDLRebuild.ID,
+			Operator:    RuleOperatorEQ,
+			Value:       true,
+			Level:       comm.High,
 			Special:     false,
-			Priority:    50,
-			Description: "小表",
-			Suggestion:  "",
+			Priority:    60,
+			Description: "DDL需要重建表，耗时与表大小成正比",
+			Suggestion:  "重建表需要额外的磁盘空间，并会导致从库延迟",
 		},
 		{
-			PolicyID:    "RUN.CAPACITY.003",
-			Name:        "表行数小于等于10w",
+			PolicyID:    "DDL.DURATION.001",
+			Name:        "DDL耗时超过30分钟",
 			Enable:      true,
 			Type:        BasicRule,
-			RuleID:      TabRows.ID,
-			Operator:    RuleOperatorLE,
-			Value:       100000,
-			Level:       comm.Low,
+			RuleID:      DDLDuration.ID,
+			Operator:    RuleOperatorGT,
+			Value:       1800,
+			Level:       comm.High,
 			Special:     false,
-			Priority:    10,
-			Description: "表行数小于等于10w",
-			Suggestion:  "",
+			Priority:    100,
+			Description: "预计DDL耗时超过30分钟",
+			Suggestion:  "长时间的DDL会导致从库延迟，建议使用gh-ost或pt-osc在业务低峰期执行",
 		},
 		{
-			PolicyID:    "RUN.CAPACITY.004",
-			Name:        "表行数大于10w",
+			PolicyID:    "REPL.LAG.001",
+			Name:        "从库延迟超过60s",
 			Enable:      true,
 			Type:        BasicRule,
-			RuleID:      TabRows.ID,
+			RuleID:      ReplicaLag.ID,
 			Operator:    RuleOperatorGT,
-			Value:       100000,
-			Level:       comm.Low,
+			Value:       60,
+			Level:       comm.High,
 			Special:     false,
-			Priority:    10,
-			Description: "表行数大于10w",
-			Suggestion:  "",
+			Priority:    100,
+			Description: "从库已经存在较大的复制延迟",
+			Suggestion:  "请等待从库追上主库后再执行",
 		},
 		{
-			PolicyID:    "RUN.CAPACITY.005",
-			Name:        "表行数小于等于2w",
-			Enable:      false,
+			PolicyID:    "REPL.BINLOG.001",
+			Name:        "产生的binlog超过1GB",
+			Enable:      true,
 			Type:        BasicRule,
-			RuleID:      TabRows.ID,
-			Operator:    RuleOperatorLE,
-			Value:       20000,
-			Level:       comm.Low,
+			RuleID:      EstimatedBinlogMB.ID,
+			Operator:    RuleOperatorGT,
+			Value:       1024,
+			Level:       comm.High,
 			Special:     false,
-			Priority:    10,
-			Description: "表行数小于等于2w",
-			Suggestion:  "",
+			Priority:    100,
+			Description: "预计产生大量binlog，会导致从库延迟",
+			Suggestion:  "请分批执行，每批之间留出从库追赶的时间",
 		},
 		{
-			PolicyID:    "RUN.CAPACITY.006",
-			Name:        "磁盘容量充足",
+			PolicyID:    "LOCK.BLOCKED.001",
+			Name:        "存在阻塞的会话",
 			Enable:      true,
 			Type:        BasicRule,
-			RuleID:      DiskSufficient.ID,
+			RuleID:      LockBlocked.ID,
 			Operator:    RuleOperatorEQ,
 			Value:       true,
-			Level:       comm.Low,
+			Level:       comm.High,
 			Special:     false,
-			Priority:    10,
-			Description: "磁盘可用空间大于表大小",
-			Suggestion:  "磁盘可用空间大于表大小，磁盘容量充足可以进行DDL操作",
+			Priority:    100,
+			Description: "表上存在持有锁的会话，SQL执行时需要等待锁释放；DDL等待元数据锁期间会阻塞该表上所有新的读写",
+			Suggestion:  "请确认阻塞会话是否可以结束后再执行",
 		},
 		{
-			PolicyID:    "RUN.CAPACITY.007",
-			Name:        "磁盘容量不充足",
+			PolicyID:    "LOCK.WAIT.001",
+			Name:        "阻塞会话持续超过5分钟",
 			Enable:      true,
 			Type:        BasicRule,
-			RuleID:      DiskSufficient.ID,
+			RuleID:      LockWaitSeconds.ID,
+			Operator:    RuleOperatorGT,
+			Value:       300,
+			Level:       comm.Fatal,
+			Special:     false,
+			Priority:    110,
+			Description: "阻塞会话已经持续超过5分钟，通常是未提交的空闲事务或长查询，SQL可能长时间等待锁",
+			Suggestion:  "请联系阻塞会话的负责人提交事务或结束会话后再执行",
+		},
+		{
+			PolicyID:    "SERVER.RENAMECOL.001",
+			Name:        "不支持RENAME COLUMN",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      RenameColumn.ID,
 			Operator:    RuleOperatorEQ,
 			Value:       false,
-			Level:       comm.Low,
+			Level:       comm.Info,
 			Special:     false,
-			Priority:    10,
-			Description: "磁盘可用空间小于表大小",
-			Suggestion:  "磁盘可用空间小于表大小，此时磁盘容量不足以支持DDL操作",
+			Priority:    1,
+			Description: "数据库版本低于MySQL 8.0，不支持RENAME COLUMN语法",
+			Suggestion:  "",
 		},
 		{
-			PolicyID:    "RUN.TABINFO.001",
-			Name:        "表存在主键",
+			PolicyID:    "SERVER.READONLY.001",
+			Name:        "只读实例",
 			Enable:      true,
 			Type:        BasicRule,
-			RuleID:      PrimaryKeyExist.ID,
+			RuleID:      ReadOnly.ID,
 			Operator:    RuleOperatorEQ,
 			Value:       true,
-			Level:       comm.Low,
+			Level:       comm.Info,
 			Special:     false,
-			Priority:    10,
-			Description: "表存在主键",
+			Priority:    1,
+			Description: "数据源开启了read_only，写操作会失败",
 			Suggestion:  "",
 		},
 		{
-			PolicyID:    "RUN.TABINFO.002",
-			Name:        "表不存在主键",
+			PolicyID:    "TIDB.TXN.001",
+			Name:        "事务超过TiDB大小限制",
 			Enable:      true,
 			Type:        BasicRule,
-			RuleID:      PrimaryKeyExist.ID,
+			RuleID:      TiDBTxnOverLimit.ID,
 			Operator:    RuleOperatorEQ,
-			Value:       false,
+			Value:       true,
 			Level:       comm.Fatal,
 			Special:     false,
-			Priority:    999,
-			Description: "表不存在主键",
-			Suggestion:  "当表没有设置主键时不建议对表进行操作，你可以先给表添加主键再进行其他操作",
+			Priority:    110,
+			Description: "预估的事务大小超过了TiDB的txn-total-size-limit，SQL会因事务过大执行失败",
+			Suggestion:  "请按主键分批执行，或使用非事务DML：BATCH ON id LIMIT 10000 DELETE/UPDATE ...",
 		},
 		{
-			PolicyID:    "RUN.TABINFO.003",
-			Name:        "表存在外键",
+			PolicyID:    "TIDB.DDL.001",
+			Name:        "TiDB存在未完成的DDL任务",
 			Enable:      true,
 			Type:        BasicRule,
-			RuleID:      ForeignKeyExist.ID,
-			Operator:    RuleOperatorEQ,
-			Value:       true,
-			Level:       comm.Low,
+			RuleID:      TiDBPendingDDLJobs.ID,
+			Operator:    RuleOperatorGT,
+			Value:       0,
+			Level:       comm.High,
 			Special:     false,
-			Priority:    10,
-			Description: "表存在外键",
-			Suggestion:  "",
+			Priority:    100,
+			Description: "TiDB的DDL任务串行执行，存在排队或正在执行的DDL任务时需要等待其完成",
+			Suggestion:  "请通过ADMIN SHOW DDL JOBS确认正在执行的DDL任务，待其完成后再执行",
 		},
 		{
-			PolicyID:    "RUN.TABINFO.004",
-			Name:        "表不存在外键",
+			PolicyID:    "RUN.CAPACITY.008",
+			Name:        "执行后磁盘剩余空间不足10%",
 			Enable:      true,
 			Type:        BasicRule,
-			RuleID:      ForeignKeyExist.ID,
-			Operator:    RuleOperatorEQ,
-			Value:       false,
-			Level:       comm.Low,
+			RuleID:      ProjectedFreeDiskPct.ID,
+			Operator:    RuleOperatorLT,
+			Value:       10,
+			Level:       comm.High,
 			Special:     false,
-			Priority:    10,
-			Description: "表不存在外键",
-			Suggestion:  "",
+			Priority:    100,
+			Description: "按磁盘增长趋势和SQL所需的空间预测，执行后磁盘剩余空间不足10%",
+			Suggestion:  "请先扩容磁盘或清理binlog、历史数据，大批量DML请分批执行",
 		},
 		{
-			PolicyID:    "RUN.TABINFO.005",
-			Name:        "表存在触发器",
+			PolicyID:    "RUN.CAPACITY.009",
+			Name:        "执行后磁盘剩余空间不足5%",
 			Enable:      true,
 			Type:        BasicRule,
-			RuleID:      TriggerExist.ID,
+			RuleID:      ProjectedFreeDiskPct.ID,
+			Operator:    RuleOperatorLT,
+			Value:       5,
+			Level:       comm.Fatal,
+			Special:     false,
+			Priority:    110,
+			Description: "按磁盘增长趋势和SQL所需的空间预测，执行后磁盘剩余空间不足5%，执行过程中可能写满磁盘",
+			Suggestion:  "请先扩容磁盘，磁盘写满会导致实例不可用",
+		},
+		{
+			PolicyID:    "RUN.LOAD.001",
+			Name:        "当前处于业务高峰期",
+			Enable:      true,
+			Type:        BasicRule,
+			RuleID:      BusyPeriod.ID,
 			Operator:    RuleOperatorEQ,
 			Value:       true,
 			Level:       comm.Low,
 			Special:     false,
-			Priority:    10,
-			Description: "表存在触发器",
-			Suggestion:  "",
+			Priority:    30,
+			Description: "过去7天同一时段的负载明显高于全天平均负载，执行期间可能影响业务",
+			Suggestion:  "建议在工单推荐的执行窗口内执行",
 		},
 		{
-			PolicyID:    "RUN.TABINFO.006",
-			Name:        "表不存在触发器",
+			PolicyID:    "SEQ.SWAP.001",
+			Name:        "切换影子表",
 			Enable:      true,
 			Type:        BasicRule,
-			RuleID:      TriggerExist.ID,
+			RuleID:      SwapTable.ID,
 			Operator:    RuleOperatorEQ,
-			Value:       false,
-			Level:       comm.Low,
+			Value:       true,
+			Level:       comm.Info,
 			Special:     false,
-			Priority:    10,
-			Description: "表不存在触发器",
+			Priority:    65,
+			Description: "新表在工单中创建并写入数据后与原表切换，原表重命名保留，可以通过再次重命名回滚",
 			Suggestion:  "",
 		},
 		{
-			PolicyID:    "RUN.TABINFO.007",
-			Name:        "where条件中存在索引列",
+			PolicyID:    "SEQ.SWAP.002",
+			Name:        "切换到空表",
 			Enable:      true,
 			Type:        BasicRule,
-			RuleID:      IndexExistInWhere.ID,
+			RuleID:      SwapEmptyTable.ID,
 			Operator:    RuleOperatorEQ,
 			Value:       true,
-			Level:       comm.Low,
+			Level:       comm.Fatal,
 			Special:     false,
-			Priority:    10,
-			Description: "where条件中跟的各列，至少有一列存在索引",
-			Suggestion:  "",
+			Priority:    120,
+			Description: "新表在工单中创建后没有写入数据就与原表切换，切换后业务读取不到原有数据",
+			Suggestion:  "请先通过INSERT ... SELECT将原表的数据写入新表，再重命名切换",
 		},
 		{
-			PolicyID:    "RUN.TABINFO.008",
-			Name:        "where条件中不存在索引列",
+			PolicyID:    "SEQ.ALTER.001",
+			Name:        "新增列后全表回填",
 			Enable:      true,
 			Type:        BasicRule,
-			RuleID:      IndexExistInWhere.ID,
+			RuleID:      BackfillAfterAlter.ID,
 			Operator:    RuleOperatorEQ,
-			Value:       false,
-			Level:       comm.Low,
-			Special:     false,
-			Priority:    10,
-			Description: "where条件中跟的各列，都不存在索引",
-			Suggestion:  "",
+			Value:       true,
+			Level:       comm.High,
+			Special:     true,
+			Priority:    90,
+			Description: "新增列后不带WHERE条件更新新增的列，大表上会长时间锁表并产生大量binlog，导致从库延迟",
+			Suggestion:  "请在新增列时通过DEFAULT设置默认值，或按主键分批回填",
 		},
 		// 聚合策略 - 按优先级
 		{
@@ -1407,6 +2628,62 @@ func GenerateDefaultPolicy() []Policy {
 			Description: "删除表的主键",
 			Suggestion:  "禁止删除表的主键，如有疑问请联系管理员",
 		},
+		{
+			PolicyID:    "AGG.RULEMATCH.056",
+			Name:        "",
+			Enable:      true,
+			Type:        AggRule,
+			RuleID:      RuleMatch.ID,
+			Operator:    RuleOperatorANY,
+			Value:       []string{"OPE.ALTER.021", "OPE.ALTER.022"},
+			Level:       comm.Fatal,
+			Special:     false,
+			Priority:    210,
+			Description: "删除或清空表的分区",
+			Suggestion:  "删除或清空分区会丢失分区内的数据，如有疑问请联系管理员",
+		},
+		{
+			PolicyID:    "AGG.RULEMATCH.057",
+			Name:        "",
+			Enable:      true,
+			Type:        AggRule,
+			RuleID:      RuleMatch.ID,
+			Operator:    RuleOperatorALL,
+			Value:       []string{"DDL.LOCK.001", "RUN.CAPACITY.001"},
+			Level:       comm.Fatal,
+			Special:     false,
+			Priority:    205,
+			Description: "修改大表时阻塞DML",
+			Suggestion:  "大表DDL执行期间会长时间阻塞业务写入，请使用gh-ost或pt-osc执行",
+		},
+		{
+			PolicyID:    "AGG.RULEMATCH.058",
+			Name:        "",
+			Enable:      true,
+			Type:        AggRule,
+			RuleID:      RuleMatch.ID,
+			Operator:    RuleOperatorALL,
+			Value:       []string{"REPL.LAG.001", "REPL.BINLOG.001"},
+			Level:       comm.Fatal,
+			Special:     false,
+			Priority:    205,
+			Description: "从库已经延迟时产生大量binlog",
+			Suggestion:  "从库延迟会进一步扩大，请等待从库追上后分批执行",
+		},
+		{
+			PolicyID:    "AGG.RULEMATCH.059",
+			Name:        "",
+			Enable:      true,
+			Type:        AggRule,
+			RuleID:      RuleMatch.ID,
+			Operator:    RuleOperatorALL,
+			Value:       []string{"OPE.ALTER.005", "SERVER.RENAMECOL.001"},
+			Level:       comm.Fatal,
+			Special:     false,
+			Priority:    210,
+			Description: "数据库版本不支持RENAME COLUMN语法，SQL会执行失败",
+			Suggestion:  "请使用ALTER TABLE ... CHANGE COLUMN old_name new_name column_definition",
+		},
 		// DELETE
 		{
 			PolicyID:    "AGG.RULEMATCH.101",
@@ -1801,7 +3078,14 @@ func queryPolicyNameByPolicyID(id string, policies []Policy) string {
 	return ""
 }
 
+// MatchBasicPolicy 匹配基本策略，评估项缺失时对应的策略不参与匹配
 func MatchBasicPolicy(env map[string]any) (bool, []Policy, error) {
+	return MatchBasicPolicyWithOptional(env, func(string) bool { return true })
+}
+
+// MatchBasicPolicyWithOptional 匹配基本策略，评估项缺失时返回错误。optional返回true的评估项（如离线模式下不连库采集的评估项、
+// 只对部分语句或数据库类型采集的评估项）缺失时对应的策略不参与匹配
+func MatchBasicPolicyWithOptional(env map[string]any, optional func(id string) bool) (bool, []Policy, error) {
 	matched := false
 	matchPolicies := make([]Policy, 0, 1)
 	for _, p := range GetPolicy() {
@@ -1809,7 +3093,11 @@ func MatchBasicPolicy(env map[string]any) (bool, []Policy, error) {
 			continue
 		}
 
-		b, err := Eval(p.Expr, env)
+		if _, ok := env[p.RuleID]; !ok && optional != nil && optional(p.RuleID) {
+			continue
+		}
+
+		b, err := evalBasicPolicy(p, env)
 		if err != nil {
 			return matched, matchPolicies, fmt.Errorf("eval BasicPolicy:%s failed, %s", p.PolicyID, err)
 		}
@@ -1823,6 +3111,56 @@ func MatchBasicPolicy(env map[string]any) (bool, []Policy, error) {
 	return matched, matchPolicies, nil
 }
 
+// evalBasicPolicy 计算基本策略，语句包含多个关键字时关键字策略依次代入每个关键字：
+// "!="要求都不相等，其他运算符匹配任意一个即可
+func evalBasicPolicy(p Policy, env map[string]any) (bool, error) {
+	keywords, ok := env[KeyWords.ID].([]string)
+	if p.RuleID != KeyWord.ID || !ok || len(keywords) == 0 {
+		return Eval(p.Expr, env)
+	}
+
+	kwEnv := make(map[string]any, len(env))
+	for k, v := range env {
+		kwEnv[k] = v
+	}
+	for _, kw := range keywords {
+		kwEnv[KeyWord.ID] = kw
+		b, err := Eval(p.Expr, kwEnv)
+		if err != nil {
+			return false, err
+		}
+		if p.Operator == RuleOperatorNE && !b {
+			return false, nil
+		}
+		if p.Operator != RuleOperatorNE && b {
+			return true, nil
+		}
+	}
+	return p.Operator == RuleOperatorNE, nil
+}
+
+// RiskiestKeyWord 从多个关键字中选出匹配到的关键字策略优先级最高（同优先级时风险等级最高）的一个
+func RiskiestKeyWord(keywords []KeyWordType) KeyWordType {
+	if len(keywords) == 0 {
+		return KeyWord.V.Unknown
+	}
+
+	riskiest, best := keywords[0], Policy{}
+	for _, kw := range keywords {
+		_, policies, err := MatchBasicPolicyWithOptional(map[string]any{KeyWord.ID: string(kw)}, func(id string) bool { return id != KeyWord.ID })
+		if err != nil || len(policies) == 0 {
+			continue
+		}
+		sort.Sort(PoliciesListByPriority(policies))
+		p := policies[0]
+		if best.PolicyID == "" || p.Priority > best.Priority ||
+			p.Priority == best.Priority && comm.LevelMap[p.Level] > comm.LevelMap[best.Level] {
+			riskiest, best = kw, p
+		}
+	}
+	return riskiest
+}
+
 func MatchAggregatePolicy(basicPolicy []Policy) (bool, []Policy, error) {
 	matched := false
 	matchPolicies := make([]Policy, 0, 1)
@@ -1898,6 +3236,34 @@ func generateDefaultBasicPolicy() map[string]any {
 	mm[IndexExistInWhere.ID] = true
 	mm[CpuUsage.ID] = 0
 	mm[BigTransaction.ID] = false
+	mm[DCLUser.ID] = ""
+	mm[PrivilegeScope.ID] = ""
+	mm[GrantAll.ID] = false
+	mm[WithGrantOption.ID] = false
+	mm[AnyHost.ID] = false
+	mm[DDLAlgorithm.ID] = ""
+	mm[DDLLock.ID] = ""
+	mm[DDLRebuild.ID] = false
+	mm[DDLDuration.ID] = 0
+	mm[ReplicaLag.ID] = 0
+	mm[EstimatedBinlogMB.ID] = 0
+	mm[LockBlocked.ID] = false
+	mm[LockWaitSeconds.ID] = 0
+	mm[ServerVersion.ID] = 0
+	mm[ServerFlavor.ID] = ""
+	mm[BinlogFormat.ID] = ""
+	mm[ReadOnly.ID] = false
+	mm[LockWaitTimeout.ID] = 0
+	mm[InstantDDL.ID] = false
+	mm[RenameColumn.ID] = false
+	mm[TiDBTxnSizeMB.ID] = 0
+	mm[TiDBTxnOverLimit.ID] = false
+	mm[TiDBPendingDDLJobs.ID] = 0
+	mm[ProjectedFreeDiskPct.ID] = 100
+	mm[BusyPeriod.ID] = false
+	mm[SwapTable.ID] = false
+	mm[SwapEmptyTable.ID] = false
+	mm[BackfillAfterAlter.ID] = false
 	return mm
 }
 
@@ -1985,6 +3351,11 @@ func parseRuleValue(c *Policy) error {
 		c.Value = KeyWordType(value)
 	default:
 		c.Value = value
+		for _, r := range ruleMeta {
+			if r.ID == c.RuleID && r.ValueType == RuleValueTypeString {
+				return nil
+			}
+		}
 		return fmt.Errorf("unknown rule value type, policy id: %s", c.PolicyID)
 	}
 	return nil
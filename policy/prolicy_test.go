@@ -0,0 +1,33 @@
+package policy
+
+import (
+	"testing"
+)
+
+func TestMatchBasicPolicyMissingItem(t *testing.T) {
+	err := GetStore(MemoryStoreType, nil).Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]any{
+		Operate.ID: string(Operate.V.DML),
+		Action.ID:  string(Action.V.Delete),
+		KeyWord.ID: string(KeyWord.V.DeleteWhere),
+	}
+
+	// 离线模式下未采集的评估项不参与匹配
+	b, _, err := MatchBasicPolicy(env)
+	if err != nil || !b {
+		t.Fatalf("MatchBasicPolicy got %v, %v, want matched", b, err)
+	}
+	b, _, err = MatchBasicPolicyWithOptional(env, func(string) bool { return true })
+	if err != nil || !b {
+		t.Fatalf("MatchBasicPolicyWithOptional offline got %v, %v, want matched", b, err)
+	}
+
+	// 在线时基本的评估项缺失需要返回错误
+	_, _, err = MatchBasicPolicyWithOptional(env, func(id string) bool { return id != AffectRows.ID })
+	if err == nil {
+		t.Fatalf("MatchBasicPolicyWithOptional online got nil error, want missing %s", AffectRows.ID)
+	}
+}
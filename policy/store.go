@@ -3,8 +3,9 @@ package policy
 import "gorm.io/gorm"
 
 const (
-	FileStoreType  = "file"
-	MysqlStoreType = "mysql"
+	FileStoreType   = "file"
+	MysqlStoreType  = "mysql"
+	MemoryStoreType = "memory"
 )
 
 type PolicyReaderWriter interface {
@@ -27,6 +28,8 @@ func GetStore(name string, opt any) PolicyReaderWriter {
 			panic("mysql type for store need opt is *gorm.DB")
 		}
 		return &MysqlStore{db}
+	case MemoryStoreType:
+		return &MemoryStore{}
 	default:
 		panic("unsupported store type:" + name)
 	}
@@ -15,6 +15,7 @@ const (
 	RuleValueTypeKeyWord RuleValueType = "KeyWordType"
 	RuleValueTypeInt     RuleValueType = "INT"
 	RuleValueTypeBool    RuleValueType = "BOOL"
+	RuleValueTypeString  RuleValueType = "STRING"
 	RuleValueTypeBasic   RuleValueType = "BASIC"
 )
 
@@ -52,6 +53,7 @@ type OperateValues struct {
 	DDL     OperateType
 	DML     OperateType
 	DCL     OperateType
+	Admin   OperateType
 }
 
 var Operate = OperateStruct{
@@ -66,6 +68,7 @@ var Operate = OperateStruct{
 		DDL:     "DDL",
 		DML:     "DML",
 		DCL:     "DCL",
+		Admin:   "ADMIN",
 	},
 }
 
@@ -88,6 +91,17 @@ type ActionValues struct {
 	Delete   ActionType
 	Update   ActionType
 	Rename   ActionType
+	Grant    ActionType
+	Revoke   ActionType
+	User     ActionType
+	Flush    ActionType
+	Set      ActionType
+	Use      ActionType
+	Lock     ActionType
+	Kill     ActionType
+	Optimize ActionType
+	Analyze  ActionType
+	Load     ActionType
 }
 
 var Action = ActionStruct{
@@ -107,6 +121,17 @@ var Action = ActionStruct{
 		Delete:   "delete",
 		Update:   "update",
 		Rename:   "rename",
+		Grant:    "grant",
+		Revoke:   "revoke",
+		User:     "user",
+		Flush:    "flush",
+		Set:      "set",
+		Use:      "use",
+		Lock:     "lock",
+		Kill:     "kill",
+		Optimize: "optimize",
+		Analyze:  "analyze",
+		Load:     "load",
 	},
 }
 
@@ -124,9 +149,10 @@ type KeyWordValues struct {
 	DropDB          KeyWordType
 	DropIdx         KeyWordType
 	DropProcedure   KeyWordType
-	DropFun         KeyWordType // 暂时不支持
+	DropFun         KeyWordType
 	DropView        KeyWordType
-	DropTrig        KeyWordType // 暂时不支持
+	DropTrig        KeyWordType
+	DropEvent       KeyWordType
 	TruncateTab     KeyWordType
 	CreateTab       KeyWordType
 	CreateTabAs     KeyWordType
@@ -137,6 +163,7 @@ type KeyWordValues struct {
 	CreateFunc      KeyWordType
 	CreateView      KeyWordType
 	CreateTrig      KeyWordType
+	CreateEvent     KeyWordType
 	AlertAddCol     KeyWordType
 	AlertDropCol    KeyWordType
 	AlertModCol     KeyWordType
@@ -148,7 +175,22 @@ type KeyWordValues struct {
 	AlertAddUni     KeyWordType
 	AlertAddUniIdx  KeyWordType
 	AlertDropIdx    KeyWordType
+	AlertRenameTab  KeyWordType
+	AlertEngine     KeyWordType
+	AlertConvert    KeyWordType
+	AlertCharset    KeyWordType
+	AlertAddPart    KeyWordType
+	AlertDropPart   KeyWordType
+	AlertTruncPart  KeyWordType
+	AlertPartition  KeyWordType
+	AlertAlgorithm  KeyWordType
+	AlertAlgCopy    KeyWordType
+	AlertLock       KeyWordType
+	AlertLockExcl   KeyWordType
 	Alter           KeyWordType
+	AlterProcedure  KeyWordType
+	AlterFunc       KeyWordType
+	AlterEvent      KeyWordType
 	InsertSelect    KeyWordType
 	Insert          KeyWordType
 	Replace         KeyWordType
@@ -157,6 +199,24 @@ type KeyWordValues struct {
 	UpdateWhere     KeyWordType
 	Update          KeyWordType
 	RenameTable     KeyWordType
+	Grant           KeyWordType
+	Revoke          KeyWordType
+	CreateUser      KeyWordType
+	DropUser        KeyWordType
+	AlterUser       KeyWordType
+	SetPassword     KeyWordType
+	FlushPrivileges KeyWordType
+	SetVariable     KeyWordType
+	SetGlobal       KeyWordType
+	DisableFKCheck  KeyWordType
+	DisableBinlog   KeyWordType
+	Use             KeyWordType
+	LockTabs        KeyWordType
+	UnlockTabs      KeyWordType
+	Kill            KeyWordType
+	OptimizeTab     KeyWordType
+	AnalyzeTab      KeyWordType
+	LoadData        KeyWordType
 }
 
 var KeyWord = KeyWordStruct{
@@ -173,9 +233,10 @@ var KeyWord = KeyWordStruct{
 		DropDB:          "drop database",
 		DropIdx:         "drop index",
 		DropProcedure:   "drop procedure",
-		DropFun:         "drop function", // 暂时不支持
+		DropFun:         "drop function",
 		DropView:        "drop view",
-		DropTrig:        "drop trigger", // 暂时不支持
+		DropTrig:        "drop trigger",
+		DropEvent:       "drop event",
 		TruncateTab:     "truncate table",
 		CreateTab:       "create table",
 		CreateTabAs:     "create table as",
@@ -186,6 +247,7 @@ var KeyWord = KeyWordStruct{
 		CreateFunc:      "create function",
 		CreateView:      "create view",
 		CreateTrig:      "create trigger",
+		CreateEvent:     "create event",
 		AlertAddCol:     "alter add column",
 		AlertDropCol:    "alter drop column",
 		AlertModCol:     "alter modify column",
@@ -197,7 +259,22 @@ var KeyWord = KeyWordStruct{
 		AlertAddUni:     "alter add unique",
 		AlertAddUniIdx:  "alter add unique index",
 		AlertDropIdx:    "alter drop index",
+		AlertRenameTab:  "alter rename table",
+		AlertEngine:     "alter engine",
+		AlertConvert:    "alter convert charset",
+		AlertCharset:    "alter default charset",
+		AlertAddPart:    "alter add partition",
+		AlertDropPart:   "alter drop partition",
+		AlertTruncPart:  "alter truncate partition",
+		AlertPartition:  "alter partition",
+		AlertAlgorithm:  "alter algorithm",
+		AlertAlgCopy:    "alter algorithm copy",
+		AlertLock:       "alter lock",
+		AlertLockExcl:   "alter lock exclusive",
 		Alter:           "alter",
+		AlterProcedure:  "alter procedure",
+		AlterFunc:       "alter function",
+		AlterEvent:      "alter event",
 		InsertSelect:    "insert into select",
 		Insert:          "insert",
 		Replace:         "replace into",
@@ -206,9 +283,33 @@ var KeyWord = KeyWordStruct{
 		UpdateWhere:     "update set where",
 		Update:          "update set",
 		RenameTable:     "rename table",
+		Grant:           "grant",
+		Revoke:          "revoke",
+		CreateUser:      "create user",
+		DropUser:        "drop user",
+		AlterUser:       "alter user",
+		SetPassword:     "set password",
+		FlushPrivileges: "flush privileges",
+		SetVariable:     "set variable",
+		SetGlobal:       "set global variable",
+		DisableFKCheck:  "set foreign_key_checks off",
+		DisableBinlog:   "set sql_log_bin off",
+		Use:             "use",
+		LockTabs:        "lock tables",
+		UnlockTabs:      "unlock tables",
+		Kill:            "kill",
+		OptimizeTab:     "optimize table",
+		AnalyzeTab:      "analyze table",
+		LoadData:        "load data",
 	},
 }
 
+// KeyWords 语句包含的所有关键字，如ALTER TABLE的多个子句，KeyWord为其中风险最高的一个
+var KeyWords = Item{
+	Name: "关键字集合",
+	ID:   "KeyWords",
+}
+
 var SQLNum = Item{
 	Name: "SQL数量",
 	ID:   "SQLNum",
@@ -274,6 +375,146 @@ var BigTransaction = Item{
 	ID:   "BigTransaction",
 }
 
+var DCLUser = Item{
+	Name: "授权用户",
+	ID:   "DCLUser",
+}
+
+var PrivilegeScope = Item{
+	Name: "权限范围",
+	ID:   "PrivilegeScope",
+}
+
+var GrantAll = Item{
+	Name: "授予所有权限",
+	ID:   "GrantAll",
+}
+
+var WithGrantOption = Item{
+	Name: "可转授权限",
+	ID:   "WithGrantOption",
+}
+
+var AnyHost = Item{
+	Name: "任意主机",
+	ID:   "AnyHost",
+}
+
+var DDLAlgorithm = Item{
+	Name: "DDL算法",
+	ID:   "DDLAlgorithm",
+}
+
+var DDLLock = Item{
+	Name: "DDL锁级别",
+	ID:   "DDLLock",
+}
+
+var DDLRebuild = Item{
+	Name: "DDL重建表",
+	ID:   "DDLRebuild",
+}
+
+var DDLDuration = Item{
+	Name: "DDL预计耗时",
+	ID:   "DDLDuration",
+}
+
+var ReplicaLag = Item{
+	Name: "从库延迟",
+	ID:   "ReplicaLag",
+}
+
+var EstimatedBinlogMB = Item{
+	Name: "预计产生的binlog大小",
+	ID:   "EstimatedBinlogMB",
+}
+
+var LockBlocked = Item{
+	Name: "存在阻塞的会话",
+	ID:   "LockBlocked",
+}
+
+var LockWaitSeconds = Item{
+	Name: "阻塞会话持续时间",
+	ID:   "LockWaitSeconds",
+}
+
+var ServerVersion = Item{
+	Name: "数据库版本",
+	ID:   "ServerVersion",
+}
+
+var ServerFlavor = Item{
+	Name: "数据库分支",
+	ID:   "ServerFlavor",
+}
+
+var BinlogFormat = Item{
+	Name: "binlog格式",
+	ID:   "BinlogFormat",
+}
+
+var ReadOnly = Item{
+	Name: "只读实例",
+	ID:   "ReadOnly",
+}
+
+var LockWaitTimeout = Item{
+	Name: "元数据锁等待超时时间",
+	ID:   "LockWaitTimeout",
+}
+
+var InstantDDL = Item{
+	Name: "支持INSTANT DDL",
+	ID:   "InstantDDL",
+}
+
+var RenameColumn = Item{
+	Name: "支持RENAME COLUMN",
+	ID:   "RenameColumn",
+}
+
+var TiDBTxnSizeMB = Item{
+	Name: "TiDB事务大小",
+	ID:   "TiDBTxnSizeMB",
+}
+
+var TiDBTxnOverLimit = Item{
+	Name: "TiDB事务超过大小限制",
+	ID:   "TiDBTxnOverLimit",
+}
+
+var TiDBPendingDDLJobs = Item{
+	Name: "TiDB未完成的DDL任务数",
+	ID:   "TiDBPendingDDLJobs",
+}
+
+var ProjectedFreeDiskPct = Item{
+	Name: "执行后预计的磁盘剩余空间百分比",
+	ID:   "ProjectedFreeDiskPct",
+}
+
+var BusyPeriod = Item{
+	Name: "当前是否处于历史业务高峰期",
+	ID:   "BusyPeriod",
+}
+
+var SwapTable = Item{
+	Name: "是否将工单中新建并已写入数据的表切换为原表",
+	ID:   "SwapTable",
+}
+
+var SwapEmptyTable = Item{
+	Name: "是否将工单中新建但未写入数据的表切换为原表",
+	ID:   "SwapEmptyTable",
+}
+
+var BackfillAfterAlter = Item{
+	Name: "是否在工单中新增列后全表更新新增的列",
+	ID:   "BackfillAfterAlter",
+}
+
 var RuleMatch = Item{
 	Name: "匹配规则名称",
 	ID:   "RuleMatch",
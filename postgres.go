@@ -0,0 +1,455 @@
+package sqlrisk
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+
+	_ "github.com/lib/pq"
+	"github.com/sunkaimr/sql-risk/comm"
+	"github.com/sunkaimr/sql-risk/policy"
+)
+
+// PostgresSSLMode 连接PostgreSQL的sslmode: disable, require, verify-ca, verify-full
+var PostgresSSLMode = "disable"
+
+// PGConnector PostgreSQL的连接，实现Dialect
+type PGConnector struct {
+	Addr     string
+	User     string
+	Database string
+	Conn     *sql.DB
+}
+
+func NewPGConnector(host, port, user, passwd, database string) (*PGConnector, error) {
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(user, passwd),
+		Host:     net.JoinHostPort(host, port),
+		Path:     "/" + database,
+		RawQuery: url.Values{"sslmode": {PostgresSSLMode}, "connect_timeout": {"3"}}.Encode(),
+	}
+	conn, err := sql.Open("postgres", dsn.String())
+	if err != nil {
+		return nil, err
+	}
+	return &PGConnector{Addr: dsn.Host, User: user, Database: database, Conn: conn}, nil
+}
+
+func (db *PGConnector) Close() error {
+	if db.Conn != nil {
+		return db.Conn.Close()
+	}
+	return nil
+}
+
+// queryInt 执行只返回一个整数的查询，没有结果时返回0
+func (db *PGConnector) queryInt(sqlQuery string, args ...any) (int64, error) {
+	rows, err := db.Conn.Query(sqlQuery, args...)
+	if err != nil {
+		return 0, fmt.Errorf("exec sql query failed, %s", err)
+	}
+
+	var v int64
+	for rows.Next() {
+		err = rows.Scan(&v)
+		if err != nil {
+			return 0, fmt.Errorf("scan rows failed, %s", err)
+		}
+	}
+	err = rows.Close()
+	if err != nil {
+		return 0, fmt.Errorf("close scan rows failed, %s", err)
+	}
+	return v, nil
+}
+
+// TableExist 表、视图、物化视图、外部表、分区表是否存在
+func (db *PGConnector) TableExist(schema, table string) (bool, error) {
+	n, err := db.queryInt("SELECT count(*) FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace "+
+		"WHERE n.nspname = $1 AND c.relname = $2 AND c.relkind IN ('r', 'p', 'v', 'm', 'f')", schema, table)
+	return n > 0, err
+}
+
+// TableSize 表大小（包括索引和TOAST），单位MB
+func (db *PGConnector) TableSize(schema, table string) (int, error) {
+	size, err := db.queryInt("SELECT coalesce(sum(pg_total_relation_size(c.oid)), 0)::bigint / 1024 / 1024 "+
+		"FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace "+
+		"WHERE n.nspname = $1 AND c.relname = $2", schema, table)
+	return int(size), err
+}
+
+// TableRows 优先使用pg_stat_user_tables中的存活行数，未收集统计信息时使用pg_class.reltuples
+func (db *PGConnector) TableRows(schema, table string) (int, error) {
+	rows, err := db.queryInt("SELECT coalesce(s.n_live_tup, greatest(c.reltuples, 0)::bigint, 0) "+
+		"FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace "+
+		"LEFT JOIN pg_stat_user_tables s ON s.relid = c.oid "+
+		"WHERE n.nspname = $1 AND c.relname = $2", schema, table)
+	return int(rows), err
+}
+
+// pgPlan EXPLAIN (FORMAT JSON) 中的计划节点
+type pgPlan struct {
+	NodeType string   `json:"Node Type"`
+	PlanRows float64  `json:"Plan Rows"`
+	Plans    []pgPlan `json:"Plans"`
+}
+
+func (p pgPlan) maxRows() float64 {
+	rows := p.PlanRows
+	for _, child := range p.Plans {
+		if r := child.maxRows(); r > rows {
+			rows = r
+		}
+	}
+	return rows
+}
+
+// ExplainRows 不带ANALYZE的EXPLAIN不会执行语句，DML可以直接EXPLAIN
+func (db *PGConnector) ExplainRows(sqlText string) (int64, error) {
+	rows, err := db.Conn.Query("EXPLAIN (FORMAT JSON) " + sqlText)
+	if err != nil {
+		return 0, fmt.Errorf("exec sql query failed, %s", err)
+	}
+
+	var plan []byte
+	for rows.Next() {
+		err = rows.Scan(&plan)
+		if err != nil {
+			return 0, fmt.Errorf("scan rows failed, %s", err)
+		}
+	}
+	err = rows.Close()
+	if err != nil {
+		return 0, fmt.Errorf("close scan rows failed, %s", err)
+	}
+
+	var explain []struct {
+		Plan pgPlan `json:"Plan"`
+	}
+	err = json.Unmarshal(plan, &explain)
+	if err != nil {
+		return 0, fmt.Errorf("unmarshal explain failed, %s", err)
+	}
+
+	var max float64
+	for _, e := range explain {
+		if r := e.Plan.maxRows(); r > max {
+			max = r
+		}
+	}
+	return int64(max), nil
+}
+
+// Transactions 从pg_stat_activity查询其他会话正在运行的事务，包括 idle in transaction
+func (db *PGConnector) Transactions() ([]TrxResult, error) {
+	rows, err := db.Conn.Query("SELECT pid::text, coalesce(state, ''), to_char(xact_start, 'YYYY-MM-DD HH24:MI:SS'), " +
+		"pid, coalesce(query, '') FROM pg_stat_activity " +
+		"WHERE xact_start IS NOT NULL AND pid <> pg_backend_pid()")
+	if err != nil {
+		return nil, fmt.Errorf("exec sql query failed, %s", err)
+	}
+
+	var trxs []TrxResult
+	for rows.Next() {
+		t := TrxResult{}
+		err = rows.Scan(&t.ID, &t.State, &t.Started, &t.MysqlThreadID, &t.Query)
+		if err != nil {
+			return trxs, fmt.Errorf("scan rows failed, %s", err)
+		}
+		trxs = append(trxs, t)
+	}
+	err = rows.Close()
+	if err != nil {
+		return trxs, fmt.Errorf("close scan rows failed, %s", err)
+	}
+	return trxs, nil
+}
+
+// LockSessions 从pg_locks查询其他会话在表上持有或等待的表级锁
+func (db *PGConnector) LockSessions(schema, table string) ([]LockSession, error) {
+	rows, err := db.Conn.Query("SELECT "+
+		"a.pid, coalesce(a.usename, ''), coalesce(host(a.client_addr), ''), coalesce(a.datname, ''), "+
+		"coalesce(a.state, ''), coalesce(a.wait_event, ''), "+
+		"coalesce(extract(epoch FROM now() - coalesce(a.xact_start, a.query_start))::int, 0), coalesce(a.query, ''), "+
+		"l.mode, CASE WHEN l.granted THEN 'GRANTED' ELSE 'WAITING' END "+
+		"FROM pg_locks l "+
+		"JOIN pg_class c ON c.oid = l.relation "+
+		"JOIN pg_namespace n ON n.oid = c.relnamespace "+
+		"JOIN pg_stat_activity a ON a.pid = l.pid "+
+		"WHERE l.locktype = 'relation' AND n.nspname = $1 AND c.relname = $2 AND l.pid <> pg_backend_pid()", schema, table)
+	if err != nil {
+		return nil, fmt.Errorf("exec sql query failed, %s", err)
+	}
+
+	sessions := make([]LockSession, 0, 1)
+	for rows.Next() {
+		s := LockSession{Source: LockSourceRelation}
+		err = rows.Scan(&s.ID, &s.User, &s.Host, &s.DB, &s.Command, &s.State, &s.Time, &s.Query, &s.LockType, &s.LockStatus)
+		if err != nil {
+			return nil, fmt.Errorf("scan rows failed, %s", err)
+		}
+		sessions = append(sessions, s)
+	}
+	err = rows.Close()
+	if err != nil {
+		return nil, fmt.Errorf("close scan rows failed, %s", err)
+	}
+	return sessions, nil
+}
+
+// postgres 数据源是否为PostgreSQL，PostgreSQL需要在数据源中显式指定
+func (c *SQLRisk) postgres() bool {
+	return c.Backend == PostgresBackend
+}
+
+func (c *SQLRisk) connectPostgres() (*PGConnector, error) {
+	cred, err := c.primary().credential(c.cache)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := NewPGConnector(c.Addr, c.Port, cred.User, cred.Passwd, c.DataBase)
+	if err != nil {
+		return nil, fmt.Errorf("new postgres connect failed, %s", err)
+	}
+	return conn, nil
+}
+
+// extractingRelatedTableName 提取SQL相关的所有表，PostgreSQL的表格式为 schema.table
+func (c *SQLRisk) extractingRelatedTableName(sql string) ([]string, error) {
+	if !c.postgres() {
+		return comm.ExtractingRelatedTableName(sql, c.DataBase)
+	}
+	stmt, err := comm.ParsePostgres(sql, "")
+	if err != nil {
+		return nil, err
+	}
+	return stmt.RelevantTables, nil
+}
+
+// extractingTableName 提取SQL操作的表，PostgreSQL的表格式为 schema.table
+func (c *SQLRisk) extractingTableName(sql string) ([]string, error) {
+	if !c.postgres() {
+		return comm.ExtractingTableName(sql, c.DataBase)
+	}
+	stmt, err := comm.ParsePostgres(sql, "")
+	if err != nil {
+		return nil, err
+	}
+	return stmt.Tables, nil
+}
+
+// CollectPostgresValues 采集PostgreSQL的风险项，MySQL特有的主从延迟、binlog、Online DDL等不采集
+func (c *SQLRisk) CollectPostgresValues() error {
+	err := c.CollectValueWithCache(policy.TabExist.Name, policy.TabExist.ID, c.Tables, "CollectTableExist", !c.HasKeyWord(
+		policy.KeyWord.V.CreateTab,
+		policy.KeyWord.V.CreateTabAs,
+		policy.KeyWord.V.CreateTmpTab,
+		policy.KeyWord.V.DropTabIfExist))
+	if err != nil {
+		return err
+	}
+
+	err = c.CollectValueWithCache(policy.TabSize.Name, policy.TabSize.ID, c.Tables, "CollectTableSize", true)
+	if err != nil {
+		return err
+	}
+
+	err = c.CollectValueWithCache(policy.TabRows.Name, policy.TabRows.ID, c.Tables, "CollectTableRows", true)
+	if err != nil {
+		return err
+	}
+
+	err = c.CollectValueWithCache(policy.AffectRows.Name, policy.AffectRows.ID, []string{c.SQLID}, "CollectAffectRows", true)
+	if err != nil {
+		return err
+	}
+
+	err = c.CollectBusyPeriod()
+	if err != nil {
+		return err
+	}
+
+	return c.CollectLockContention()
+}
+
+// pgAlterKeyWords ALTER TABLE子句对应的关键字，未列出的子句为 alter
+var pgAlterKeyWords = map[string]policy.KeyWordType{
+	comm.PGAlterAddColumn:     policy.KeyWord.V.AlertAddCol,
+	comm.PGAlterDropColumn:    policy.KeyWord.V.AlertDropCol,
+	comm.PGAlterColumnType:    policy.KeyWord.V.AlertModCol,
+	comm.PGAlterRenameColumn:  policy.KeyWord.V.AlertRenameCol,
+	comm.PGAlterRenameTable:   policy.KeyWord.V.AlertRenameTab,
+	comm.PGAlterAddPrimaryKey: policy.KeyWord.V.AlertAddPriKey,
+	comm.PGAlterAddUnique:     policy.KeyWord.V.AlertAddUni,
+	comm.PGAlterAttachPart:    policy.KeyWord.V.AlertAddPart,
+	comm.PGAlterDetachPart:    policy.KeyWord.V.AlertDropPart,
+}
+
+func postgresAlterKeyWords(stmt *comm.PGStmt) []policy.KeyWordType {
+	keywords := make([]policy.KeyWordType, 0, len(stmt.AlterActions))
+	for _, action := range stmt.AlterActions {
+		kw, ok := pgAlterKeyWords[action]
+		if !ok {
+			kw = policy.KeyWord.V.Alter
+		}
+		keywords = append(keywords, kw)
+	}
+	return keywords
+}
+
+// collectPostgresAction 将PostgreSQL语句映射到与MySQL相同的操作类型、动作和关键字
+func collectPostgresAction(stmt *comm.PGStmt) (policy.OperateType, policy.ActionType, policy.KeyWordType, error) {
+	switch stmt.Verb {
+	case "select":
+		if stmt.TerminateBackend {
+			// 终止会话：SELECT pg_terminate_backend(123);
+			return policy.Operate.V.Admin, policy.Action.V.Kill, policy.KeyWord.V.Kill, nil
+		}
+		return policy.Operate.V.DQL, policy.Action.V.Select, policy.KeyWord.V.Select, nil
+	case "insert":
+		if stmt.Select {
+			return policy.Operate.V.DML, policy.Action.V.Insert, policy.KeyWord.V.InsertSelect, nil
+		}
+		return policy.Operate.V.DML, policy.Action.V.Insert, policy.KeyWord.V.Insert, nil
+	case "update":
+		if stmt.Where {
+			return policy.Operate.V.DML, policy.Action.V.Update, policy.KeyWord.V.UpdateWhere, nil
+		}
+		return policy.Operate.V.DML, policy.Action.V.Update, policy.KeyWord.V.Update, nil
+	case "delete":
+		if stmt.Where {
+			return policy.Operate.V.DML, policy.Action.V.Delete, policy.KeyWord.V.DeleteWhere, nil
+		}
+		return policy.Operate.V.DML, policy.Action.V.Delete, policy.KeyWord.V.Delete, nil
+	case "copy":
+		if stmt.CopyFrom {
+			// 导入数据：COPY mytable FROM '/tmp/data.csv';
+			return policy.Operate.V.DML, policy.Action.V.Load, policy.KeyWord.V.LoadData, nil
+		}
+		return policy.Operate.V.DQL, policy.Action.V.Select, policy.KeyWord.V.Select, nil
+	case "truncate":
+		return policy.Operate.V.DDL, policy.Action.V.Truncate, policy.KeyWord.V.TruncateTab, nil
+	case "lock":
+		return policy.Operate.V.Admin, policy.Action.V.Lock, policy.KeyWord.V.LockTabs, nil
+	case "vacuum", "cluster", "reindex":
+		// VACUUM FULL、CLUSTER、REINDEX 与 OPTIMIZE TABLE 一样会重建表或索引
+		return policy.Operate.V.Admin, policy.Action.V.Optimize, policy.KeyWord.V.OptimizeTab, nil
+	case "analyze":
+		return policy.Operate.V.Admin, policy.Action.V.Analyze, policy.KeyWord.V.AnalyzeTab, nil
+	case "grant":
+		return policy.Operate.V.DCL, policy.Action.V.Grant, policy.KeyWord.V.Grant, nil
+	case "revoke":
+		return policy.Operate.V.DCL, policy.Action.V.Revoke, policy.KeyWord.V.Revoke, nil
+	case "set":
+		switch {
+		case stmt.SetName == "session_replication_role" && stmt.SetValue == "replica":
+			// 不触发外键和触发器，与关闭foreign_key_checks类似
+			return policy.Operate.V.Admin, policy.Action.V.Set, policy.KeyWord.V.DisableFKCheck, nil
+		case stmt.System:
+			return policy.Operate.V.Admin, policy.Action.V.Set, policy.KeyWord.V.SetGlobal, nil
+		}
+		return policy.Operate.V.Admin, policy.Action.V.Set, policy.KeyWord.V.SetVariable, nil
+	case "create":
+		return collectPostgresCreate(stmt)
+	case "drop":
+		return collectPostgresDrop(stmt)
+	case "alter":
+		switch stmt.Object {
+		case "system":
+			return policy.Operate.V.Admin, policy.Action.V.Set, policy.KeyWord.V.SetGlobal, nil
+		case "user":
+			return policy.Operate.V.DCL, policy.Action.V.User, policy.KeyWord.V.AlterUser, nil
+		case "table":
+			return policy.Operate.V.DDL, policy.Action.V.Alter, policy.RiskiestKeyWord(postgresAlterKeyWords(stmt)), nil
+		case "function":
+			return policy.Operate.V.DDL, policy.Action.V.Alter, policy.KeyWord.V.AlterFunc, nil
+		case "procedure":
+			return policy.Operate.V.DDL, policy.Action.V.Alter, policy.KeyWord.V.AlterProcedure, nil
+		}
+		return policy.Operate.V.DDL, policy.Action.V.Alter, policy.KeyWord.V.Alter, nil
+	}
+	return policy.Operate.V.Unknown, policy.Action.V.Unknown, policy.KeyWord.V.Unknown, nil
+}
+
+func collectPostgresCreate(stmt *comm.PGStmt) (policy.OperateType, policy.ActionType, policy.KeyWordType, error) {
+	var keyword policy.KeyWordType
+	switch stmt.Object {
+	case "table":
+		switch {
+		case stmt.Temporary:
+			keyword = policy.KeyWord.V.CreateTmpTab
+		case stmt.Select:
+			keyword = policy.KeyWord.V.CreateTabAs
+		default:
+			keyword = policy.KeyWord.V.CreateTab
+		}
+	case "index":
+		keyword = policy.KeyWord.V.CreateIdx
+		if stmt.Unique {
+			keyword = policy.KeyWord.V.CreateUniIdx
+		}
+	case "view", "materialized view":
+		keyword = policy.KeyWord.V.CreateView
+	case "function":
+		keyword = policy.KeyWord.V.CreateFunc
+	case "procedure":
+		keyword = policy.KeyWord.V.CreateProcedure
+	case "trigger":
+		keyword = policy.KeyWord.V.CreateTrig
+	case "user":
+		return policy.Operate.V.DCL, policy.Action.V.User, policy.KeyWord.V.CreateUser, nil
+	default:
+		return policy.Operate.V.Unknown, policy.Action.V.Unknown, policy.KeyWord.V.Unknown, nil
+	}
+	return policy.Operate.V.DDL, policy.Action.V.Create, keyword, nil
+}
+
+func collectPostgresDrop(stmt *comm.PGStmt) (policy.OperateType, policy.ActionType, policy.KeyWordType, error) {
+	var keyword policy.KeyWordType
+	switch stmt.Object {
+	case "table":
+		keyword = policy.KeyWord.V.DropTab
+		if stmt.IfExists {
+			keyword = policy.KeyWord.V.DropTabIfExist
+		}
+	case "database", "schema":
+		keyword = policy.KeyWord.V.DropDB
+	case "index":
+		keyword = policy.KeyWord.V.DropIdx
+	case "view", "materialized view":
+		keyword = policy.KeyWord.V.DropView
+	case "function":
+		keyword = policy.KeyWord.V.DropFun
+	case "procedure":
+		keyword = policy.KeyWord.V.DropProcedure
+	case "trigger":
+		keyword = policy.KeyWord.V.DropTrig
+	case "user":
+		return policy.Operate.V.DCL, policy.Action.V.User, policy.KeyWord.V.DropUser, nil
+	default:
+		return policy.Operate.V.Unknown, policy.Action.V.Unknown, policy.KeyWord.V.Unknown, nil
+	}
+	return policy.Operate.V.DDL, policy.Action.V.Drop, keyword, nil
+}
+
+// postgresAffectRows 没有WHERE条件的UPDATE、DELETE影响全表，其他DML使用EXPLAIN预估
+func (c *SQLRisk) postgresAffectRows(tabRows int) (int, error) {
+	if c.HasKeyWord(policy.KeyWord.V.Delete, policy.KeyWord.V.Update) {
+		return tabRows, nil
+	}
+
+	conn, err := c.connectPostgres()
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	rows, err := conn.ExplainRows(c.SQLText)
+	if err != nil {
+		return 0, fmt.Errorf("explain(%s) failed, %s", c.SQLText, err)
+	}
+	return int(rows), nil
+}
@@ -0,0 +1,142 @@
+package sqlrisk
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sunkaimr/sql-risk/policy"
+)
+
+func TestCollectPostgresAction(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		act     policy.ActionType
+		keyword policy.KeyWordType
+	}{
+		{"test001", "SELECT * FROM student WHERE id = 2", policy.Action.V.Select, policy.KeyWord.V.Select},
+		{"test002", "WITH t AS (SELECT id FROM student) DELETE FROM student USING t WHERE student.id = t.id", policy.Action.V.Delete, policy.KeyWord.V.DeleteWhere},
+		{"test003", "UPDATE student SET age = 18", policy.Action.V.Update, policy.KeyWord.V.Update},
+		{"test004", "INSERT INTO student SELECT * FROM student_bak", policy.Action.V.Insert, policy.KeyWord.V.InsertSelect},
+		{"test005", "TRUNCATE TABLE student RESTART IDENTITY", policy.Action.V.Truncate, policy.KeyWord.V.TruncateTab},
+		{"test006", "COPY student FROM '/tmp/student.csv' CSV", policy.Action.V.Load, policy.KeyWord.V.LoadData},
+		{"test007", "COPY student TO STDOUT", policy.Action.V.Select, policy.KeyWord.V.Select},
+		{"test008", "SELECT pg_terminate_backend(123)", policy.Action.V.Kill, policy.KeyWord.V.Kill},
+		{"test009", "VACUUM FULL student", policy.Action.V.Optimize, policy.KeyWord.V.OptimizeTab},
+		{"test010", "SET session_replication_role = replica", policy.Action.V.Set, policy.KeyWord.V.DisableFKCheck},
+		{"test011", "ALTER SYSTEM SET max_connections = 500", policy.Action.V.Set, policy.KeyWord.V.SetGlobal},
+		{"test012", "CREATE UNIQUE INDEX CONCURRENTLY idx_id ON student (id)", policy.Action.V.Create, policy.KeyWord.V.CreateUniIdx},
+		{"test013", "CREATE TEMP TABLE t1 (id int)", policy.Action.V.Create, policy.KeyWord.V.CreateTmpTab},
+		{"test014", "DROP TABLE IF EXISTS student", policy.Action.V.Drop, policy.KeyWord.V.DropTabIfExist},
+		{"test015", "ALTER TABLE logs ADD COLUMN a int, DETACH PARTITION logs_2020", policy.Action.V.Alter, policy.KeyWord.V.AlertDropPart},
+		{"test016", "ALTER TABLE student ALTER COLUMN age TYPE bigint", policy.Action.V.Alter, policy.KeyWord.V.AlertModCol},
+		{"test017", "CREATE ROLE app LOGIN PASSWORD 'x'", policy.Action.V.User, policy.KeyWord.V.CreateUser},
+	}
+
+	// ALTER包含多个子句时需要根据策略选出风险最高的关键字
+	store := policy.GetStore(policy.MemoryStoreType, nil)
+	if err := store.Init(); err != nil {
+		t.Fatalf("init policy failed, %s", err)
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := SQLRisk{Backend: PostgresBackend, SQLText: test.sql}
+			_, act, kw, err := c.CollectAction()
+			if err != nil {
+				t.Fatalf("CollectAction('%v') failed, got error: %s", test.sql, err)
+			}
+			if act != test.act || kw != test.keyword {
+				t.Fatalf("CollectAction('%v') failed, got %s:%s, want %s:%s", test.sql, act, kw, test.act, test.keyword)
+			}
+		})
+	}
+}
+
+func TestCollectPostgresKeyWords(t *testing.T) {
+	c := SQLRisk{Backend: PostgresBackend, SQLText: "ALTER TABLE student ADD COLUMN a int, RENAME COLUMN b TO c, SET (fillfactor = 70)"}
+	got, err := c.CollectKeyWords()
+	if err != nil {
+		t.Fatalf("CollectKeyWords failed, got error: %s", err)
+	}
+	want := []policy.KeyWordType{policy.KeyWord.V.AlertAddCol, policy.KeyWord.V.AlertRenameCol, policy.KeyWord.V.Alter}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("CollectKeyWords got %v, want %v", got, want)
+	}
+}
+
+func TestPGConnectorTableRows(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	conn := &PGConnector{Conn: db}
+	defer conn.Close()
+
+	mock.ExpectQuery("FROM pg_class c JOIN pg_namespace n").WithArgs("public", "student").
+		WillReturnRows(mock.NewRows([]string{"rows"}).AddRow(250000))
+	rows, err := conn.TableRows("public", "student")
+	if err != nil || rows != 250000 {
+		t.Fatalf("TableRows got: %d, %v, want: 250000", rows, err)
+	}
+}
+
+func TestPGConnectorExplainRows(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	conn := &PGConnector{Conn: db}
+	defer conn.Close()
+
+	plan := `[{"Plan":{"Node Type":"ModifyTable","Plan Rows":0,"Plans":[{"Node Type":"Seq Scan","Plan Rows":1200}]}}]`
+	mock.ExpectQuery(`^EXPLAIN \(FORMAT JSON\) DELETE FROM student WHERE age > 18`).
+		WillReturnRows(mock.NewRows([]string{"QUERY PLAN"}).AddRow([]byte(plan)))
+	rows, err := conn.ExplainRows("DELETE FROM student WHERE age > 18")
+	if err != nil || rows != 1200 {
+		t.Fatalf("ExplainRows got: %d, %v, want: 1200", rows, err)
+	}
+}
+
+func TestPGConnectorLockSessions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	conn := &PGConnector{Conn: db}
+	defer conn.Close()
+
+	columns := []string{"pid", "usename", "client_addr", "datname", "state", "wait_event", "time", "query", "mode", "granted"}
+	mock.ExpectQuery("FROM pg_locks l").WithArgs("public", "student").WillReturnRows(mock.NewRows(columns).
+		AddRow(101, "app", "10.0.0.5", "school", "idle in transaction", "ClientRead", 120, "update student set age = 1", "RowExclusiveLock", "GRANTED").
+		AddRow(102, "dba", "10.0.0.6", "school", "active", "relation", 30, "alter table student add c int", "AccessExclusiveLock", "WAITING"))
+	sessions, err := conn.LockSessions("public", "student")
+	if err != nil {
+		t.Fatalf("LockSessions failed, got error: %s", err)
+	}
+
+	// DML只会被与RowExclusiveLock冲突的表锁阻塞，DDL需要的AccessExclusiveLock与所有锁冲突
+	tests := []struct {
+		name string
+		ddl  bool
+		want []int64
+	}{
+		{"test001", true, []int64{101, 102}},
+		{"test002", false, []int64{102}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ids := make([]int64, 0)
+			for _, s := range BlockingSessions(test.ddl, sessions) {
+				if s.Source != LockSourceRelation {
+					t.Fatalf("LockSessions got source %s, want %s", s.Source, LockSourceRelation)
+				}
+				ids = append(ids, s.ID)
+			}
+			if !reflect.DeepEqual(ids, test.want) {
+				t.Fatalf("BlockingSessions(%v) got %v, want %v", test.ddl, ids, test.want)
+			}
+		})
+	}
+}
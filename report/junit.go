@@ -0,0 +1,127 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/sunkaimr/sql-risk"
+	"github.com/sunkaimr/sql-risk/comm"
+)
+
+// JUnitTestSuites JUnit XML 报告
+type JUnitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Suites   []JUnitTestSuite `xml:"testsuite"`
+}
+
+type JUnitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []JUnitTestCase `xml:"testcase"`
+}
+
+type JUnitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *JUnitFailure `xml:"failure,omitempty"`
+	Error     *JUnitFailure `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type JUnitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// JUnit 将工单的识别结果转换为JUnit XML格式，每个工单对应一个testsuite，每个SQL对应一个testcase
+// SQL的风险等级达到failLevel时记为failure，识别过程中出现错误时记为error
+func JUnit(failLevel comm.Level, works ...*sqlrisk.WorkRisk) *JUnitTestSuites {
+	suites := &JUnitTestSuites{}
+	for _, work := range works {
+		suite := JUnitTestSuite{
+			Name: work.WorkID,
+			Time: seconds(work.Cost),
+		}
+
+		// 工单级别的错误（如SQL拆分失败）单独作为一个testcase
+		if len(work.Errors) != 0 {
+			suite.TestCases = append(suite.TestCases, JUnitTestCase{
+				Name:      work.WorkID,
+				ClassName: work.WorkID,
+				Time:      seconds(0),
+				Error:     errorsToFailure(work.Errors),
+			})
+			suite.Errors++
+		}
+
+		for i, r := range work.SQLRisks {
+			tc := JUnitTestCase{
+				Name:      fmt.Sprintf("#%d line %d: %s", i+1, r.Position.Line, abbreviate(r.SQLText, 80)),
+				ClassName: work.WorkID,
+				Time:      seconds(r.Cost),
+				SystemOut: r.SQLText,
+			}
+
+			policies := EffectivePolicies(r)
+			if len(r.Errors) != 0 {
+				tc.Error = errorsToFailure(r.Errors)
+				suite.Errors++
+			} else if len(policies) != 0 && comm.LevelMap[r.PreResult.Level] >= comm.LevelMap[failLevel] {
+				lines := make([]string, 0, len(policies))
+				for _, p := range policies {
+					lines = append(lines, fmt.Sprintf("%s %s %s", p.Level, p.PolicyID, Message(p)))
+				}
+				tc.Failure = &JUnitFailure{
+					Message: fmt.Sprintf("%s %s", policies[0].PolicyID, policies[0].Name),
+					Type:    string(r.PreResult.Level),
+					Text:    strings.Join(lines, "\n"),
+				}
+				suite.Failures++
+			}
+			suite.TestCases = append(suite.TestCases, tc)
+		}
+
+		suite.Tests = len(suite.TestCases)
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Errors += suite.Errors
+		suites.Suites = append(suites.Suites, suite)
+	}
+	return suites
+}
+
+func errorsToFailure(errs []sqlrisk.ErrorResult) *JUnitFailure {
+	lines := make([]string, 0, len(errs))
+	for _, e := range errs {
+		lines = append(lines, fmt.Sprintf("[%s] %s", e.Type, e.Error))
+	}
+	return &JUnitFailure{
+		Message: lines[0],
+		Type:    ErrorRuleID,
+		Text:    strings.Join(lines, "\n"),
+	}
+}
+
+// seconds 毫秒转为秒
+func seconds(ms int) string {
+	return fmt.Sprintf("%.3f", float64(ms)/1000)
+}
+
+// abbreviate 截断过长的SQL，按字符计算
+func abbreviate(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "..."
+}
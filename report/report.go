@@ -0,0 +1,33 @@
+// Package report 将工单的风险识别结果转换为CI等外部系统使用的格式
+package report
+
+import (
+	"strings"
+
+	"github.com/sunkaimr/sql-risk"
+	"github.com/sunkaimr/sql-risk/policy"
+)
+
+// EffectivePolicies SQL最终生效的策略，按风险等级从高到低
+func EffectivePolicies(r *sqlrisk.SQLRisk) []policy.Policy {
+	ps := make([]policy.Policy, 0, 1)
+	ps = append(ps, r.FatalPolicy...)
+	ps = append(ps, r.HighPolicy...)
+	ps = append(ps, r.LowPolicy...)
+	ps = append(ps, r.InfoPolicy...)
+	return ps
+}
+
+// Message 策略的提示信息，由描述和建议组成
+func Message(p policy.Policy) string {
+	msg := make([]string, 0, 2)
+	if p.Description != "" {
+		msg = append(msg, p.Description)
+	} else if p.Name != "" {
+		msg = append(msg, p.Name)
+	}
+	if p.Suggestion != "" {
+		msg = append(msg, p.Suggestion)
+	}
+	return strings.Join(msg, ": ")
+}
@@ -0,0 +1,97 @@
+package report
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/sunkaimr/sql-risk"
+	"github.com/sunkaimr/sql-risk/comm"
+	"github.com/sunkaimr/sql-risk/policy"
+)
+
+func newWork() *sqlrisk.WorkRisk {
+	return &sqlrisk.WorkRisk{
+		WorkID: "a.sql",
+		SQLRisks: []*sqlrisk.SQLRisk{
+			{
+				SQLText:    "select * from student where id=1",
+				Position:   comm.Position{Offset: 0, Line: 1, Column: 1},
+				PreResult:  sqlrisk.PreResult{Level: comm.Low},
+				LowPolicy:  []policy.Policy{{PolicyID: "OPE.SELECT.001", Name: "查询", Level: comm.Low, Suggestion: "可以执行"}},
+				InfoPolicy: []policy.Policy{},
+			},
+			{
+				SQLText:     "delete from student",
+				Position:    comm.Position{Offset: 34, Line: 2, Column: 1},
+				PreResult:   sqlrisk.PreResult{Level: comm.Fatal},
+				FatalPolicy: []policy.Policy{{PolicyID: "AGG.RULEMATCH.101", Name: "全表删除", Level: comm.Fatal, Description: "删除全表数据"}},
+			},
+			{
+				SQLText:   "selec 1",
+				Position:  comm.Position{Offset: 55, Line: 3, Column: 1},
+				PreResult: sqlrisk.PreResult{Level: comm.Fatal},
+				Errors:    []sqlrisk.ErrorResult{{Type: "SQL解析", Error: "syntax error"}},
+			},
+		},
+	}
+}
+
+func TestSARIF(t *testing.T) {
+	log := SARIF(newWork())
+	if len(log.Runs) != 1 {
+		t.Fatalf("got %d runs, want 1", len(log.Runs))
+	}
+
+	run := log.Runs[0]
+	tests := []struct {
+		ruleID string
+		level  string
+		line   int
+	}{
+		{"OPE.SELECT.001", "warning", 1},
+		{"AGG.RULEMATCH.101", "error", 2},
+		{ErrorRuleID, "error", 3},
+	}
+	if len(run.Results) != len(tests) {
+		t.Fatalf("got %d results, want %d", len(run.Results), len(tests))
+	}
+	for i, test := range tests {
+		r := run.Results[i]
+		if r.RuleID != test.ruleID || r.Level != test.level {
+			t.Fatalf("results[%d] got %s/%s, want %s/%s", i, r.RuleID, r.Level, test.ruleID, test.level)
+		}
+		if r.Locations[0].PhysicalLocation.ArtifactLocation.URI != "a.sql" || r.Locations[0].PhysicalLocation.Region.StartLine != test.line {
+			t.Fatalf("results[%d] got location %+v, want a.sql line %d", i, r.Locations[0].PhysicalLocation, test.line)
+		}
+	}
+
+	if len(run.Tool.Driver.Rules) != 3 || run.Tool.Driver.Rules[0].ID != "AGG.RULEMATCH.101" {
+		t.Fatalf("got rules %+v", run.Tool.Driver.Rules)
+	}
+}
+
+func TestJUnit(t *testing.T) {
+	tests := []struct {
+		name      string
+		failLevel comm.Level
+		failures  int
+	}{
+		{"test001", comm.Fatal, 1},
+		{"test002", comm.Low, 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			suites := JUnit(test.failLevel, newWork())
+			if suites.Tests != 3 || suites.Failures != test.failures || suites.Errors != 1 {
+				t.Fatalf("got tests:%d failures:%d errors:%d, want 3/%d/1", suites.Tests, suites.Failures, suites.Errors, test.failures)
+			}
+			if suites.Suites[0].TestCases[2].Error == nil {
+				t.Fatalf("testcase #3 should be error")
+			}
+			if _, err := xml.Marshal(suites); err != nil {
+				t.Fatalf("marshal junit failed, %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/sunkaimr/sql-risk"
+	"github.com/sunkaimr/sql-risk/comm"
+	"github.com/sunkaimr/sql-risk/policy"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	toolName     = "sqlrisk"
+	toolURI      = "https://github.com/sunkaimr/sql-risk"
+
+	// ErrorRuleID 识别过程中出现错误时使用的规则ID
+	ErrorRuleID = "SQLRISK.ERROR"
+)
+
+// SarifLog SARIF 2.1.0 日志，只包含本项目用到的字段
+type SarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []SarifRun `json:"runs"`
+}
+
+type SarifRun struct {
+	Tool    SarifTool     `json:"tool"`
+	Results []SarifResult `json:"results"`
+}
+
+type SarifTool struct {
+	Driver SarifDriver `json:"driver"`
+}
+
+type SarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []SarifRule `json:"rules"`
+}
+
+type SarifRule struct {
+	ID                   string             `json:"id"`
+	Name                 string             `json:"name,omitempty"`
+	ShortDescription     SarifMessage       `json:"shortDescription"`
+	Help                 *SarifMessage      `json:"help,omitempty"`
+	DefaultConfiguration SarifConfiguration `json:"defaultConfiguration"`
+}
+
+type SarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type SarifMessage struct {
+	Text string `json:"text"`
+}
+
+type SarifResult struct {
+	RuleID     string          `json:"ruleId"`
+	Level      string          `json:"level"`
+	Message    SarifMessage    `json:"message"`
+	Locations  []SarifLocation `json:"locations,omitempty"`
+	Properties map[string]any  `json:"properties,omitempty"`
+}
+
+type SarifLocation struct {
+	PhysicalLocation SarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type SarifPhysicalLocation struct {
+	ArtifactLocation SarifArtifactLocation `json:"artifactLocation"`
+	Region           *SarifRegion          `json:"region,omitempty"`
+}
+
+type SarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// SarifRegion 语句在工单中的位置，列号按字符计算。Position.Offset是字节偏移，
+// 和SARIF按字符计算的charOffset不一致，因此只输出行列号
+type SarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+// SARIF 将工单的识别结果转换为SARIF格式，工单ID作为结果所在的文件路径
+// 每个SQL最终生效的策略对应一条结果，规则ID为策略ID
+func SARIF(works ...*sqlrisk.WorkRisk) *SarifLog {
+	rules := make(map[string]SarifRule, 10)
+	results := make([]SarifResult, 0, 10)
+
+	for _, work := range works {
+		for _, e := range work.Errors {
+			results = append(results, SarifResult{
+				RuleID:    ErrorRuleID,
+				Level:     "error",
+				Message:   SarifMessage{Text: fmt.Sprintf("[%s] %s", e.Type, e.Error)},
+				Locations: []SarifLocation{{PhysicalLocation: SarifPhysicalLocation{ArtifactLocation: SarifArtifactLocation{URI: work.WorkID}}}},
+			})
+			rules[ErrorRuleID] = errorRule()
+		}
+
+		for _, r := range work.SQLRisks {
+			location := SarifLocation{
+				PhysicalLocation: SarifPhysicalLocation{
+					ArtifactLocation: SarifArtifactLocation{URI: work.WorkID},
+					Region: &SarifRegion{
+						StartLine:   r.Position.Line,
+						StartColumn: r.Position.Column,
+						EndLine:     r.EndPosition.Line,
+						EndColumn:   r.EndPosition.Column,
+					},
+				},
+			}
+
+			for _, p := range EffectivePolicies(r) {
+				if _, ok := rules[p.PolicyID]; !ok {
+					rules[p.PolicyID] = policyRule(p)
+				}
+				results = append(results, SarifResult{
+					RuleID:    p.PolicyID,
+					Level:     SarifLevel(p.Level),
+					Message:   SarifMessage{Text: Message(p)},
+					Locations: []SarifLocation{location},
+					Properties: map[string]any{
+						"level":   p.Level,
+						"special": p.Special,
+						"sql":     r.SQLText,
+					},
+				})
+			}
+
+			for _, e := range r.Errors {
+				rules[ErrorRuleID] = errorRule()
+				results = append(results, SarifResult{
+					RuleID:    ErrorRuleID,
+					Level:     "error",
+					Message:   SarifMessage{Text: fmt.Sprintf("[%s] %s", e.Type, e.Error)},
+					Locations: []SarifLocation{location},
+				})
+			}
+		}
+	}
+
+	ruleList := make([]SarifRule, 0, len(rules))
+	for _, rule := range rules {
+		ruleList = append(ruleList, rule)
+	}
+	sort.Slice(ruleList, func(i, j int) bool {
+		return ruleList[i].ID < ruleList[j].ID
+	})
+
+	return &SarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs: []SarifRun{
+			{
+				Tool: SarifTool{
+					Driver: SarifDriver{
+						Name:           toolName,
+						InformationURI: toolURI,
+						Rules:          ruleList,
+					},
+				},
+				Results: results,
+			},
+		},
+	}
+}
+
+// SarifLevel 将风险等级转换为SARIF的level
+func SarifLevel(level comm.Level) string {
+	switch level {
+	case comm.Fatal, comm.High:
+		return "error"
+	case comm.Low:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+func policyRule(p policy.Policy) SarifRule {
+	rule := SarifRule{
+		ID:                   p.PolicyID,
+		Name:                 p.Name,
+		ShortDescription:     SarifMessage{Text: p.Description},
+		DefaultConfiguration: SarifConfiguration{Level: SarifLevel(p.Level)},
+	}
+	if rule.ShortDescription.Text == "" {
+		rule.ShortDescription.Text = p.Name
+	}
+	if p.Suggestion != "" {
+		rule.Help = &SarifMessage{Text: p.Suggestion}
+	}
+	return rule
+}
+
+func errorRule() SarifRule {
+	return SarifRule{
+		ID:                   ErrorRuleID,
+		Name:                 "识别错误",
+		ShortDescription:     SarifMessage{Text: "SQL解析、权限校验或风险识别过程中出现错误"},
+		DefaultConfiguration: SarifConfiguration{Level: "error"},
+	}
+}
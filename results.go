@@ -0,0 +1,206 @@
+package sqlrisk
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/sunkaimr/sql-risk/comm"
+	"github.com/sunkaimr/sql-risk/policy"
+	"gorm.io/gorm"
+)
+
+// ResultStore 保存工单及其SQL的识别结果，用于重新打开工单和事后审计
+type ResultStore struct {
+	*gorm.DB
+}
+
+// ResultQuery 查询条件，为空的条件不参与过滤
+type ResultQuery struct {
+	WorkID   string
+	FingerID string
+	// 库.表，匹配SQL操作的表
+	Table string
+	// 前置风险识别的等级
+	Level comm.Level
+	// 识别时间的范围[Start, End)
+	Start time.Time
+	End   time.Time
+	// 按识别时间倒序分页，Limit为0时不限制
+	Limit  int
+	Offset int
+}
+
+func NewResultStore(db *gorm.DB) *ResultStore {
+	return &ResultStore{db}
+}
+
+// Init 创建或更新结果表
+func (c *ResultStore) Init() error {
+	err := c.AutoMigrate(&WorkRisk{}, &SQLRisk{})
+	if err != nil {
+		return fmt.Errorf("AutoMigrate result failed, %s", err)
+	}
+	return nil
+}
+
+// SaveWorkRisk 保存工单及其所有SQL，重复保存时覆盖之前的SQL
+func (c *ResultStore) SaveWorkRisk(w *WorkRisk) error {
+	return c.Transaction(func(tx *gorm.DB) error {
+		err := tx.Omit("SQLRisks").Save(w).Error
+		if err != nil {
+			return fmt.Errorf("save work risk %s failed, %s", w.WorkID, err)
+		}
+
+		err = tx.Where("work_risk_id = ?", w.ID).Delete(&SQLRisk{}).Error
+		if err != nil {
+			return fmt.Errorf("delete SQL risks of work %s failed, %s", w.WorkID, err)
+		}
+		if len(w.SQLRisks) == 0 {
+			return nil
+		}
+
+		for _, r := range w.SQLRisks {
+			r.ID = 0
+			r.WorkRiskID = w.ID
+		}
+		err = tx.CreateInBatches(w.SQLRisks, 100).Error
+		if err != nil {
+			return fmt.Errorf("save SQL risks of work %s failed, %s", w.WorkID, err)
+		}
+		return nil
+	})
+}
+
+// LoadWorkRisk 按ID加载工单及其所有SQL
+func (c *ResultStore) LoadWorkRisk(id uint) (*WorkRisk, error) {
+	w := &WorkRisk{}
+	err := c.Preload("SQLRisks", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).First(w, id).Error
+	if err != nil {
+		return nil, fmt.Errorf("load work risk %d failed, %s", id, err)
+	}
+	w.cache = NewMemoryCache(0)
+	for _, r := range w.SQLRisks {
+		r.cache = w.cache
+	}
+	return w, nil
+}
+
+// FindWorkRisks 查询工单，不加载工单中的SQL。FingerID、Table匹配工单中任意一条SQL
+func (c *ResultStore) FindWorkRisks(q ResultQuery) ([]WorkRisk, error) {
+	db := c.Model(&WorkRisk{})
+	if q.WorkID != "" {
+		db = db.Where("work_id = ?", q.WorkID)
+	}
+	if q.FingerID != "" || q.Table != "" {
+		db = db.Where("id IN (?)", q.sqlRiskFilter(c.Model(&SQLRisk{})).Select("work_risk_id"))
+	}
+	db = q.filter(db)
+
+	works := make([]WorkRisk, 0)
+	err := db.Find(&works).Error
+	if err != nil {
+		return nil, fmt.Errorf("find work risks failed, %s", err)
+	}
+	return works, nil
+}
+
+// FindSQLRisks 查询SQL，Level为SQL自身的风险等级
+func (c *ResultStore) FindSQLRisks(q ResultQuery) ([]SQLRisk, error) {
+	db := c.Model(&SQLRisk{})
+	if q.WorkID != "" {
+		db = db.Where("work_id = ?", q.WorkID)
+	}
+	db = q.filter(q.sqlRiskFilter(db))
+
+	risks := make([]SQLRisk, 0)
+	err := db.Find(&risks).Error
+	if err != nil {
+		return nil, fmt.Errorf("find SQL risks failed, %s", err)
+	}
+	return risks, nil
+}
+
+// Cleanup 清理before之前识别的工单及其SQL，返回清理的工单数
+func (c *ResultStore) Cleanup(before time.Time) (int64, error) {
+	var deleted int64
+	err := c.Transaction(func(tx *gorm.DB) error {
+		expired := tx.Model(&WorkRisk{}).Select("id").Where("created_at < ?", before)
+		err := tx.Where("work_risk_id IN (?) OR created_at < ?", expired, before).Delete(&SQLRisk{}).Error
+		if err != nil {
+			return fmt.Errorf("delete expired SQL risks failed, %s", err)
+		}
+
+		res := tx.Where("created_at < ?", before).Delete(&WorkRisk{})
+		if res.Error != nil {
+			return fmt.Errorf("delete expired work risks failed, %s", res.Error)
+		}
+		deleted = res.RowsAffected
+		return nil
+	})
+	return deleted, err
+}
+
+func (q ResultQuery) sqlRiskFilter(db *gorm.DB) *gorm.DB {
+	if q.FingerID != "" {
+		db = db.Where("finger_id = ?", q.FingerID)
+	}
+	if q.Table != "" {
+		db = db.Where("JSON_CONTAINS(tables, JSON_QUOTE(?))", q.Table)
+	}
+	return db
+}
+
+func (q ResultQuery) filter(db *gorm.DB) *gorm.DB {
+	if q.Level != "" {
+		db = db.Where("JSON_UNQUOTE(JSON_EXTRACT(pre_result, '$.level')) = ?", q.Level)
+	}
+	if !q.Start.IsZero() {
+		db = db.Where("created_at >= ?", q.Start)
+	}
+	if !q.End.IsZero() {
+		db = db.Where("created_at < ?", q.End)
+	}
+	db = db.Order("created_at DESC").Order("id DESC")
+	if q.Limit > 0 {
+		db = db.Limit(q.Limit).Offset(q.Offset)
+	}
+	return db
+}
+
+// AfterFind 评估项的值以JSON保存，加载后还原为识别时的类型
+func (c *SQLRisk) AfterFind(tx *gorm.DB) error {
+	for i := range c.ItemValues {
+		c.ItemValues[i].Value = restoreItemValue(c.ItemValues[i].ID, c.ItemValues[i].Value)
+	}
+	return nil
+}
+
+// restoreItemValue JSON中的数字会被解析为float64，操作类型、关键字等会被解析为string，还原为识别时的类型
+func restoreItemValue(id string, value any) any {
+	switch v := value.(type) {
+	case float64:
+		if v == math.Trunc(v) {
+			return int(v)
+		}
+	case string:
+		switch id {
+		case policy.Operate.ID:
+			return policy.OperateType(v)
+		case policy.Action.ID:
+			return policy.ActionType(v)
+		case policy.KeyWord.ID:
+			return policy.KeyWordType(v)
+		}
+	case []any:
+		if id == policy.KeyWords.ID {
+			keywords := make([]policy.KeyWordType, 0, len(v))
+			for _, k := range v {
+				s, _ := k.(string)
+				keywords = append(keywords, policy.KeyWordType(s))
+			}
+			return keywords
+		}
+	}
+	return value
+}
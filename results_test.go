@@ -0,0 +1,150 @@
+package sqlrisk
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sunkaimr/sql-risk/comm"
+	"github.com/sunkaimr/sql-risk/policy"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func mockResultStore() (*ResultStore, sqlmock.Sqlmock, error) {
+	dbMock, mock, err := sqlmock.New()
+	if err != nil {
+		return nil, nil, err
+	}
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: dbMock, SkipInitializeWithVersion: true}), &gorm.Config{})
+	if err != nil {
+		return nil, nil, err
+	}
+	return NewResultStore(db), mock, nil
+}
+
+func TestResultStoreSaveAndLoad(t *testing.T) {
+	store, mock, err := mockResultStore()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	w := &WorkRisk{
+		WorkID:    "1001",
+		PreResult: PreResult{Level: comm.High},
+		SQLRisks:  []*SQLRisk{{ID: 3, SQLText: "delete from t1"}, {SQLText: "delete from t2"}},
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `work_risks`").WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectExec("DELETE FROM `sql_risks` WHERE work_risk_id = ?").WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("INSERT INTO `sql_risks`").WillReturnResult(sqlmock.NewResult(1, 2))
+	mock.ExpectCommit()
+	err = store.SaveWorkRisk(w)
+	if err != nil {
+		t.Fatalf("SaveWorkRisk failed, got error: %s", err)
+	}
+	if w.ID != 7 || w.SQLRisks[0].WorkRiskID != 7 || w.SQLRisks[1].WorkRiskID != 7 {
+		t.Fatalf("SaveWorkRisk got work id %d, SQL work ids %d %d", w.ID, w.SQLRisks[0].WorkRiskID, w.SQLRisks[1].WorkRiskID)
+	}
+
+	mock.ExpectQuery("SELECT \\* FROM `work_risks` WHERE `work_risks`.`id` = \\?").WithArgs(7).
+		WillReturnRows(mock.NewRows([]string{"id", "work_id", "pre_result"}).AddRow(7, "1001", `{"level":"high"}`))
+	mock.ExpectQuery("SELECT \\* FROM `sql_risks` WHERE `sql_risks`.`work_risk_id` = \\? ORDER BY id").WithArgs(7).
+		WillReturnRows(mock.NewRows([]string{"id", "work_risk_id", "tables", "item_values"}).
+			AddRow(1, 7, `["test.t1"]`, `[{"id":"Operate","value":"DML"},{"id":"AffectRows","value":100},{"id":"DDLAlgorithm","value":"INPLACE"}]`))
+	got, err := store.LoadWorkRisk(7)
+	if err != nil {
+		t.Fatalf("LoadWorkRisk failed, got error: %s", err)
+	}
+	if got.PreResult.Level != comm.High || len(got.SQLRisks) != 1 || len(got.SQLRisks[0].Tables) != 1 {
+		t.Fatalf("LoadWorkRisk got %+v", got)
+	}
+	r := got.SQLRisks[0]
+	if operate, err := r.GetItemValueWithOperateType(policy.Operate.ID); err != nil || operate != policy.Operate.V.DML {
+		t.Fatalf("LoadWorkRisk got Operate %v, %v", operate, err)
+	}
+	if rows, err := r.GetItemValueWithInt(policy.AffectRows.ID); err != nil || rows != 100 {
+		t.Fatalf("LoadWorkRisk got AffectRows %v, %v", rows, err)
+	}
+	if algorithm, err := r.GetItemValueWithString(policy.DDLAlgorithm.ID); err != nil || algorithm != DDLAlgorithmInplace {
+		t.Fatalf("LoadWorkRisk got DDLAlgorithm %v, %v", algorithm, err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResultStoreFind(t *testing.T) {
+	start := time.Date(2023, 7, 1, 0, 0, 0, 0, time.Local)
+	end := start.AddDate(0, 0, 7)
+
+	tests := []struct {
+		name  string
+		work  bool
+		query ResultQuery
+		sql   string
+		args  []driver.Value
+	}{
+		{"test001", true, ResultQuery{WorkID: "1001"},
+			"SELECT \\* FROM `work_risks` WHERE work_id = \\? ORDER BY created_at DESC,id DESC$", []driver.Value{"1001"}},
+		{"test002", true, ResultQuery{FingerID: "abc", Table: "test.t1", Limit: 10, Offset: 20},
+			"SELECT \\* FROM `work_risks` WHERE id IN \\(SELECT `work_risk_id` FROM `sql_risks` WHERE finger_id = \\? AND JSON_CONTAINS\\(tables, JSON_QUOTE\\(\\?\\)\\)\\) ORDER BY created_at DESC,id DESC LIMIT 10 OFFSET 20",
+			[]driver.Value{"abc", "test.t1"}},
+		{"test003", true, ResultQuery{Level: comm.Fatal, Start: start, End: end},
+			"SELECT \\* FROM `work_risks` WHERE JSON_UNQUOTE\\(JSON_EXTRACT\\(pre_result, '\\$.level'\\)\\) = \\? AND created_at >= \\? AND created_at < \\?",
+			[]driver.Value{string(comm.Fatal), start, end}},
+		{"test004", false, ResultQuery{WorkID: "1001", Table: "test.t1", Level: comm.High},
+			"SELECT \\* FROM `sql_risks` WHERE work_id = \\? AND JSON_CONTAINS\\(tables, JSON_QUOTE\\(\\?\\)\\) AND JSON_UNQUOTE\\(JSON_EXTRACT\\(pre_result, '\\$.level'\\)\\) = \\?",
+			[]driver.Value{"1001", "test.t1", string(comm.High)}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store, mock, err := mockResultStore()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			mock.ExpectQuery(test.sql).WithArgs(test.args...).
+				WillReturnRows(mock.NewRows([]string{"id", "work_id"}).AddRow(1, "1001"))
+
+			n := 0
+			if test.work {
+				var works []WorkRisk
+				works, err = store.FindWorkRisks(test.query)
+				n = len(works)
+			} else {
+				var risks []SQLRisk
+				risks, err = store.FindSQLRisks(test.query)
+				n = len(risks)
+			}
+			if err != nil || n != 1 {
+				t.Fatalf("find got %d results, error: %v", n, err)
+			}
+			if err = mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestResultStoreCleanup(t *testing.T) {
+	store, mock, err := mockResultStore()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	before := time.Now().AddDate(0, 0, -90)
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM `sql_risks` WHERE work_risk_id IN \\(SELECT `id` FROM `work_risks` WHERE created_at < \\?\\) OR created_at < \\?").
+		WithArgs(before, before).WillReturnResult(sqlmock.NewResult(0, 10))
+	mock.ExpectExec("DELETE FROM `work_risks` WHERE created_at < \\?").WithArgs(before).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	deleted, err := store.Cleanup(before)
+	if err != nil || deleted != 3 {
+		t.Fatalf("Cleanup got %d, %v, want 3", deleted, err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
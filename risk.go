@@ -3,6 +3,7 @@ package sqlrisk
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 	"sort"
@@ -17,33 +18,48 @@ import (
 )
 
 type SQLRisk struct {
-	ID                 uint            `gorm:"primary_key;AUTO_INCREMENT;" json:"id"`
-	WorkID             string          `gorm:"type:varchar(64);index:work_id_idx;column:work_id;comment:工单ID" json:"work_id"`
-	Addr               string          `gorm:"type:varchar(64);not null;column:addr;comment:数据源地址" json:"addr"`                        // 此地址对应是集群的vip，自建集群无法根据vip查询到监控信息，所以需要配置读写库的地址
-	ReadWriteAddr      string          `gorm:"type:varchar(64);not null;column:read_write_addr;comment:读写库的地址" json:"read_write_addr"` // 此地址对应是集群读写库的地址，主要用来查询监控信息
-	Port               string          `gorm:"type:varchar(64);not null;column:port;comment:数据源端口" json:"port"`
-	User               string          `gorm:"type:varchar(64);not null;column:user;comment:用户名" json:"user"`
-	Passwd             string          `gorm:"-" json:"-"`
-	DataBase           string          `gorm:"type:varchar(1024);not null;column:data_base;comment:数据库名称" json:"database"`
-	RelevantTables     []string        `gorm:"type:json;column:relevant_tables;comment:SQL语句中涉及到所有库、表" json:"relevant_tables"`
-	Tables             []string        `gorm:"type:json;column:tables;comment:SQL语句中操作的（增、删、改，查）所有库、表" json:"tables"`
-	SQLText            string          `gorm:"type:longtext;column:sql_text;comment:SQL" json:"sql_text"`
-	SQLID              string          `gorm:"type:varchar(64);column:sql_id;comment:MD5" json:"sql_id"`
-	Finger             string          `gorm:"type:varchar(1024);column:finger;comment:Finger" json:"finger"`
-	FingerID           string          `gorm:"type:varchar(64);column:finger_id;comment:FingerID" json:"finger_id"`
-	ItemValues         []ItemValue     `gorm:"type:json;column:item_values;comment:风险评估项结果" json:"item_values"`
-	MatchedBasicPolicy []policy.Policy `gorm:"type:json;column:matched_basic_policy;comment:匹配到的基本策略" json:"matched_basic_policy"`
-	MatchedAggPolicy   policy.Policy   `gorm:"type:json;column:matched_agg_policy;comment:匹配到的聚合策略" json:"matched_agg_policy"`
-	InfoPolicy         []policy.Policy `gorm:"type:json;column:info_policy;comment:最终生效的info级别的策略" json:"info_policy"`
-	LowPolicy          []policy.Policy `gorm:"type:json;column:low_policy;comment:最终生效的low级别的策略" json:"low_policy"`
-	HighPolicy         []policy.Policy `gorm:"type:json;column:high_policy;comment:最终生效的high级别的策略" json:"high_policy"`
-	FatalPolicy        []policy.Policy `gorm:"type:json;column:fatal_policy;comment:最终生效的fatal级别的策略" json:"fatal_policy"`
-	PreResult          PreResult       `gorm:"type:json;column:pre_result;comment:前置风险识别结果" json:"pre_result"`
-	PostResult         PostResult      `gorm:"type:json;column:post_result;comment:后置风险识别结果" json:"post_result"`
-	Errors             []ErrorResult   `gorm:"type:json;column:errors;comment:错误信息" json:"errors"`
-	Config             *Config         `gorm:"type:json;column:config;comment:相关配置信息" json:"config"`
-	Cost               int             `gorm:"type:int;column:cost;comment:识别SQL风险花费时间" json:"cost"`
-	cache              map[string]any
+	ID                 uint               `gorm:"primary_key;AUTO_INCREMENT;" json:"id"`
+	WorkID             string             `gorm:"type:varchar(64);index:work_id_idx;column:work_id;comment:工单ID" json:"work_id"`
+	WorkRiskID         uint               `gorm:"index:work_risk_id_idx;column:work_risk_id;comment:所属工单识别结果的ID" json:"work_risk_id"`
+	DataSourceID       string             `gorm:"type:varchar(64);column:data_source_id;comment:数据源ID" json:"data_source_id"`
+	Backend            BackendType        `gorm:"type:varchar(16);column:backend;comment:数据库类型" json:"backend"`                           // 为空时根据数据库版本识别
+	MetricsClass       string             `gorm:"type:varchar(32);column:metrics_class;comment:监控类别" json:"metrics_class"`                // 为空时依次尝试所有监控模板
+	Addr               string             `gorm:"type:varchar(64);not null;column:addr;comment:数据源地址" json:"addr"`                        // 此地址对应是集群的vip，自建集群无法根据vip查询到监控信息，所以需要配置读写库的地址
+	ReadWriteAddr      string             `gorm:"type:varchar(64);not null;column:read_write_addr;comment:读写库的地址" json:"read_write_addr"` // 此地址对应是集群读写库的地址，主要用来查询监控信息
+	Port               string             `gorm:"type:varchar(64);not null;column:port;comment:数据源端口" json:"port"`
+	User               string             `gorm:"type:varchar(64);not null;column:user;comment:用户名" json:"user"`
+	Passwd             string             `gorm:"-" json:"-"`
+	Credential         CredentialProvider `gorm:"-" json:"-"` // 未配置时使用Passwd
+	Replicas           []Endpoint         `gorm:"-" json:"-"` // 只读库，表元数据、COUNT(*)等查询优先发往只读库
+	DataBase           string             `gorm:"type:varchar(1024);not null;column:data_base;comment:数据库名称" json:"database"`
+	RelevantTables     []string           `gorm:"type:json;serializer:json;column:relevant_tables;comment:SQL语句中涉及到所有库、表" json:"relevant_tables"`
+	Tables             []string           `gorm:"type:json;serializer:json;column:tables;comment:SQL语句中操作的（增、删、改，查）所有库、表" json:"tables"`
+	SQLText            string             `gorm:"type:longtext;column:sql_text;comment:SQL" json:"sql_text"`
+	Position           comm.Position      `gorm:"type:json;serializer:json;column:position;comment:SQL在工单中的起始位置" json:"position"`
+	EndPosition        comm.Position      `gorm:"type:json;serializer:json;column:end_position;comment:SQL在工单中的结束位置" json:"end_position"`
+	SQLID              string             `gorm:"type:varchar(64);column:sql_id;comment:MD5" json:"sql_id"`
+	Finger             string             `gorm:"type:varchar(1024);column:finger;comment:Finger" json:"finger"`
+	FingerID           string             `gorm:"type:varchar(64);column:finger_id;comment:FingerID" json:"finger_id"`
+	ItemValues         []ItemValue        `gorm:"type:json;serializer:json;column:item_values;comment:风险评估项结果" json:"item_values"`
+	MatchedBasicPolicy []policy.Policy    `gorm:"type:json;serializer:json;column:matched_basic_policy;comment:匹配到的基本策略" json:"matched_basic_policy"`
+	MatchedAggPolicy   policy.Policy      `gorm:"type:json;serializer:json;column:matched_agg_policy;comment:匹配到的聚合策略" json:"matched_agg_policy"`
+	InfoPolicy         []policy.Policy    `gorm:"type:json;serializer:json;column:info_policy;comment:最终生效的info级别的策略" json:"info_policy"`
+	LowPolicy          []policy.Policy    `gorm:"type:json;serializer:json;column:low_policy;comment:最终生效的low级别的策略" json:"low_policy"`
+	HighPolicy         []policy.Policy    `gorm:"type:json;serializer:json;column:high_policy;comment:最终生效的high级别的策略" json:"high_policy"`
+	FatalPolicy        []policy.Policy    `gorm:"type:json;serializer:json;column:fatal_policy;comment:最终生效的fatal级别的策略" json:"fatal_policy"`
+	PreResult          PreResult          `gorm:"type:json;serializer:json;column:pre_result;comment:前置风险识别结果" json:"pre_result"`
+	PostResult         PostResult         `gorm:"type:json;serializer:json;column:post_result;comment:后置风险识别结果" json:"post_result"`
+	OSCPlan            *OSCPlan           `gorm:"type:json;serializer:json;column:osc_plan;comment:在线改表方案" json:"osc_plan,omitempty"`
+	BlockingSessions   []LockSession      `gorm:"type:json;serializer:json;column:blocking_sessions;comment:阻塞SQL执行的会话" json:"blocking_sessions,omitempty"`
+	UnknownSessions    []LockSession      `gorm:"type:json;serializer:json;column:unknown_sessions;comment:无法判断是否阻塞SQL执行的会话" json:"unknown_sessions,omitempty"`
+	RepresentedBy      int                `gorm:"type:int;column:represented_by;comment:抽样检测时代表此SQL的SQL序号" json:"represented_by,omitempty"` // 从1开始，为0时此SQL经过检测
+	Errors             []ErrorResult      `gorm:"type:json;serializer:json;column:errors;comment:错误信息" json:"errors"`
+	Config             *Config            `gorm:"type:json;serializer:json;column:config;comment:相关配置信息" json:"config"`
+	Cost               int                `gorm:"type:int;column:cost;comment:识别SQL风险花费时间" json:"cost"`
+	CreatedAt          time.Time          `gorm:"index:created_at_idx;column:created_at;comment:创建时间" json:"created_at"`
+	cache              *MemoryCache
+	// 工单中前面的语句新建、删除或重命名过的表在此语句执行前的状态
+	ticketTables map[string]SimulatedTable
 }
 
 type ErrorResult struct {
@@ -84,6 +100,12 @@ type RiskConfig struct {
 	// 否则使用Explain获取影响行数
 	TabRowsThreshold int `json:"tab_rows_threshold"`
 	TabSizeThreshold int `json:"tab_size_threshold"`
+	// 离线模式：不连接数据库和监控，只根据SQL本身（操作类型、动作、关键字）识别风险
+	Offline bool `json:"offline"`
+	// 按磁盘增长趋势预测执行后剩余空间的时长（小时），为0时不考虑增长趋势
+	DiskForecastHours int `json:"disk_forecast_hours"`
+	// 抽样检测策略，为nil时使用DefaultSamplingStrategies，为空时不抽样
+	Sampling []SamplingStrategy `json:"sampling"`
 }
 
 func NewSqlRisk(workID, addr, rwAddr, port, user, passwd, database, sql string, config *Config) *SQLRisk {
@@ -100,7 +122,7 @@ func NewSqlRisk(workID, addr, rwAddr, port, user, passwd, database, sql string,
 		DataBase:      database,
 		SQLText:       sql,
 		Config:        config,
-		cache:         make(map[string]any, 1),
+		cache:         NewMemoryCache(0),
 	}
 }
 
@@ -116,9 +138,11 @@ func (c *SQLRisk) IdentifyPreRisk() error {
 		return err
 	}
 
-	err = c.CollectPreRiskValues()
-	if err != nil {
-		return fmt.Errorf("collect risk values failed, %s", err)
+	if c.Config == nil || !c.Config.RiskConfig.Offline {
+		err = c.CollectPreRiskValues()
+		if err != nil {
+			return fmt.Errorf("collect risk values failed, %s", err)
+		}
 	}
 
 	env := make(map[string]any, 5)
@@ -126,13 +150,19 @@ func (c *SQLRisk) IdentifyPreRisk() error {
 		switch v.Value.(type) {
 		case policy.OperateType, policy.ActionType, policy.KeyWordType:
 			env[v.ID] = fmt.Sprintf("%v", v.Value)
+		case []policy.KeyWordType:
+			keywords := make([]string, 0, len(v.Value.([]policy.KeyWordType)))
+			for _, kw := range v.Value.([]policy.KeyWordType) {
+				keywords = append(keywords, string(kw))
+			}
+			env[v.ID] = keywords
 		default:
 			env[v.ID] = v.Value
 		}
 	}
 
 	// 先匹配basic策略
-	b, matchBasicPolicy, err := policy.MatchBasicPolicy(env)
+	b, matchBasicPolicy, err := policy.MatchBasicPolicyWithOptional(env, c.optionalItem)
 	if err != nil {
 		return fmt.Errorf("match basic policy failed, %s", err)
 	}
@@ -177,6 +207,37 @@ func (c *SQLRisk) IdentifyPreRisk() error {
 	return nil
 }
 
+// optionalItem 评估项未采集时是否跳过对应的策略：离线模式下只根据SQL本身识别，跳过所有连库采集的评估项；
+// 在线时只跳过按语句、数据库类型或监控采集的评估项，基本的评估项缺失时（采集失败或被跳过）返回错误
+func (c *SQLRisk) optionalItem(id string) bool {
+	if c.Config != nil && c.Config.RiskConfig.Offline {
+		return true
+	}
+	return !comm.EleExist(id, c.requiredItems())
+}
+
+// requiredItems 在线识别时每条SQL都会采集的评估项
+func (c *SQLRisk) requiredItems() []string {
+	items := []string{
+		policy.Operate.ID,
+		policy.Action.ID,
+		policy.KeyWord.ID,
+		policy.TabExist.ID,
+		policy.TabSize.ID,
+		policy.TabRows.ID,
+		policy.AffectRows.ID,
+	}
+	// PostgreSQL不采集主键、外键、触发器和where条件索引
+	if c.postgres() {
+		return items
+	}
+	return append(items,
+		policy.PrimaryKeyExist.ID,
+		policy.ForeignKeyExist.ID,
+		policy.TriggerExist.ID,
+		policy.IndexExistInWhere.ID)
+}
+
 // SetSQLBasicInfo 设置SQL的基本信息
 func (c *SQLRisk) SetSQLBasicInfo() error {
 	var err error
@@ -194,14 +255,14 @@ func (c *SQLRisk) SetSQLBasicInfo() error {
 	}
 
 	if len(c.RelevantTables) == 0 {
-		c.RelevantTables, err = comm.ExtractingRelatedTableName(c.SQLText, c.DataBase)
+		c.RelevantTables, err = c.extractingRelatedTableName(c.SQLText)
 		if err != nil {
 			return fmt.Errorf("extracting related table name failed, %s", err)
 		}
 	}
 
 	if len(c.Tables) == 0 {
-		c.Tables, err = comm.ExtractingTableName(c.SQLText, c.DataBase)
+		c.Tables, err = c.extractingTableName(c.SQLText)
 		if err != nil {
 			return fmt.Errorf("extracting table name failed, %s", err)
 		}
@@ -209,19 +270,62 @@ func (c *SQLRisk) SetSQLBasicInfo() error {
 
 	if len(c.ItemValues) == 0 {
 		start := time.Now()
-		ope, act, keyword, err := c.CollectAction()
+		ope, act, keyword, keywords, ok := c.cachedAction()
+		if !ok {
+			ope, act, keyword, err = c.CollectAction()
+			if err != nil {
+				cost := int(time.Now().Sub(start).Milliseconds())
+				c.SetItemValue(policy.Operate.Name, policy.Operate.ID, ope, cost)
+				c.SetItemValue(policy.Action.Name, policy.Action.ID, act, cost)
+				c.SetItemValue(policy.KeyWord.Name, policy.KeyWord.ID, keyword, cost)
+				c.SetItemError(policy.Action.Name, err)
+				return err
+			}
+			keywords, _ = c.CollectKeyWords()
+			c.cacheAction(ope, act, keyword, keywords)
+		}
 		cost := int(time.Now().Sub(start).Milliseconds())
 		c.SetItemValue(policy.Operate.Name, policy.Operate.ID, ope, cost)
 		c.SetItemValue(policy.Action.Name, policy.Action.ID, act, cost)
 		c.SetItemValue(policy.KeyWord.Name, policy.KeyWord.ID, keyword, cost)
-		if err != nil {
-			c.SetItemError(policy.Action.Name, err)
-			return err
+
+		// 包含多个关键字时（如ALTER TABLE的多个子句）关键字策略需要匹配每一个关键字
+		if len(keywords) > 1 {
+			c.SetItemValue(policy.KeyWords.Name, policy.KeyWords.ID, keywords, cost)
+		}
+
+		// 授权和用户管理语句只需解析SQL，离线模式下也能识别
+		if ope == policy.Operate.V.DCL && !c.postgres() {
+			err = c.CollectDCLValues()
+			if err != nil {
+				return err
+			}
 		}
 	}
 	return nil
 }
 
+// CollectDCLValues 提取授权和用户管理语句中的用户和权限范围
+func (c *SQLRisk) CollectDCLValues() error {
+	start := time.Now()
+	info, err := comm.ExtractingDCLInfo(c.SQLText, c.DataBase)
+	if err != nil {
+		c.SetItemError(policy.DCLUser.Name, err)
+		return fmt.Errorf("extracting dcl info failed, %s", err)
+	}
+	cost := int(time.Now().Sub(start).Milliseconds())
+
+	c.SetItemValue(policy.DCLUser.Name, policy.DCLUser.ID, strings.Join(info.Users, ","), cost)
+	// 授权相关的评估项只对GRANT语句采集，REVOKE回收权限不会扩大用户的权限
+	if info.Grant && info.Scope != "" {
+		c.SetItemValue(policy.PrivilegeScope.Name, policy.PrivilegeScope.ID, info.Scope, cost)
+		c.SetItemValue(policy.GrantAll.Name, policy.GrantAll.ID, info.AllPrivileges, cost)
+		c.SetItemValue(policy.WithGrantOption.Name, policy.WithGrantOption.ID, info.WithGrantOption, cost)
+	}
+	c.SetItemValue(policy.AnyHost.Name, policy.AnyHost.ID, info.AnyHost, cost)
+	return nil
+}
+
 // String 以json格式输出
 func (c *SQLRisk) String() string {
 	buf := bytes.NewBuffer([]byte{})
@@ -239,9 +343,22 @@ func (c *SQLRisk) CollectValueWithCache(name, id string, keys []string, method s
 
 	start := time.Now()
 	key := strings.Join([]string{id, strings.Join(keys, "|")}, "|")
-
-	v, ok := c.cache[key]
-	if !ok || !useCache {
+	verdictKey := c.verdictKey(id, keys)
+	// 涉及工单中新建的表时评估项是模拟的值，与数据库不一致，不能缓存
+	useCache = useCache && len(c.ticketTables) == 0
+
+	var v any
+	ok := false
+	if useCache {
+		v, ok = c.cache.Get(key)
+		if !ok {
+			// 同一个工单内没有缓存时查询跨工单的缓存
+			if v, ok = c.verdicts().Get(verdictKey, id); ok {
+				c.cache.Set(key, v, 0)
+			}
+		}
+	}
+	if !ok {
 		m, ok := reflect.TypeOf(c).MethodByName(method)
 		if !ok {
 			return fmt.Errorf("method %s undefined in %T", method, c)
@@ -269,7 +386,8 @@ func (c *SQLRisk) CollectValueWithCache(name, id string, keys []string, method s
 		}
 
 		if useCache {
-			c.cache[key] = v
+			c.cache.Set(key, v, 0)
+			c.verdicts().Set(verdictKey, id, v)
 		}
 	}
 	c.SetItemValue(name, id, v, int(time.Now().Sub(start).Milliseconds()))
@@ -277,6 +395,17 @@ func (c *SQLRisk) CollectValueWithCache(name, id string, keys []string, method s
 }
 
 func (c *SQLRisk) CollectPreRiskValues() error {
+	if c.postgres() {
+		return c.CollectPostgresValues()
+	}
+	// 表与数据库不一致时按工单中模拟的表结构采集
+	if len(c.ticketTables) != 0 {
+		return c.CollectTicketSchemaValues()
+	}
+	return c.collectMySQLValues()
+}
+
+func (c *SQLRisk) collectMySQLValues() error {
 	var err error
 	keyword, err := c.GetItemValueWithKeyWordType(policy.KeyWord.ID)
 	if err != nil {
@@ -313,23 +442,13 @@ func (c *SQLRisk) CollectPreRiskValues() error {
 		return err
 	}
 
-	err = c.CollectValueWithCache(policy.FreeDisk.Name, policy.FreeDisk.ID, []string{c.Addr}, "CollectFreeDisk", true)
-	if err != nil {
-		return err
-	}
-
-	err = c.CollectValueWithCache(policy.DiskSufficient.Name, policy.DiskSufficient.ID, c.Tables, "CollectDiskSufficient", true)
-	if err != nil {
-		return err
-	}
-
 	err = c.CollectValueWithCache(policy.PrimaryKeyExist.Name, policy.PrimaryKeyExist.ID, c.Tables, "CollectPrimaryKeyExist", func() bool {
 		//  以下情况不能缓存主键的结果，以防止影响后续的判断
-		if comm.EleExist(keyword, []policy.KeyWordType{
+		if c.HasKeyWord(
 			policy.KeyWord.V.DropTabIfExist,
 			policy.KeyWord.V.DropTab,
 			policy.KeyWord.V.DropDB,
-			policy.KeyWord.V.AlertAddPriKey}) {
+			policy.KeyWord.V.AlertAddPriKey) {
 			return false
 		}
 		return true
@@ -352,14 +471,225 @@ func (c *SQLRisk) CollectPreRiskValues() error {
 	if err != nil {
 		return err
 	}
+
+	err = c.CollectValueWithCache(policy.EstimatedBinlogMB.Name, policy.EstimatedBinlogMB.ID, []string{c.SQLID}, "CollectEstimatedBinlogMB", true)
+	if err != nil {
+		return err
+	}
+
+	// 从库延迟只影响写操作，同一个数据源只查询一次
+	operate, err := c.GetItemValueWithOperateType(policy.Operate.ID)
+	if err != nil {
+		return err
+	}
+	tidb := c.backendType() == TiDBBackend
+	if !tidb && (operate == policy.Operate.V.DML || operate == policy.Operate.V.DDL) {
+		err = c.CollectValueWithCache(policy.ReplicaLag.Name, policy.ReplicaLag.ID, []string{c.Addr, c.Port}, "CollectReplicaLag", true)
+		// 从库都无法查询时已记录评估项错误，不影响其他评估项的识别
+		if err != nil && !errors.Is(err, NoReplicaLagError) {
+			return err
+		}
+	}
+
+	err = c.CollectServerInfoValues()
+	if err != nil {
+		return err
+	}
+
+	err = c.CollectBusyPeriod()
+	if err != nil {
+		return err
+	}
+
+	// TiDB的DDL均为在线执行，没有主从复制和元数据锁排队，改为采集事务大小限制和DDL任务队列
+	if tidb {
+		err = c.CollectDiskValues()
+		if err != nil {
+			return err
+		}
+		return c.CollectTiDBValues()
+	}
+
+	err = c.CollectOnlineDDL()
+	if err != nil {
+		return err
+	}
+
+	// 依赖DDL的执行算法和耗时，需要在CollectOnlineDDL之后采集
+	err = c.CollectDiskValues()
+	if err != nil {
+		return err
+	}
+
+	err = c.CollectProjectedFreeDisk()
+	if err != nil {
+		return err
+	}
+
+	err = c.CollectLockContention()
+	if err != nil {
+		return err
+	}
+
+	c.OSCPlan = c.GenerateOSCPlan()
+	return nil
+}
+
+// CollectOnlineDDL 预测ALTER TABLE、CREATE INDEX、DROP INDEX的执行算法、锁级别、是否重建表和耗时，其他语句不采集
+func (c *SQLRisk) CollectOnlineDDL() error {
+	stmt, err := parser.New().ParseOneStmt(c.SQLText, "", "")
+	if err != nil {
+		return nil
+	}
+	switch stmt.(type) {
+	case *ast.AlterTableStmt, *ast.CreateIndexStmt, *ast.DropIndexStmt:
+	default:
+		return nil
+	}
+
+	start := time.Now()
+	server, err := c.CollectServerInfo()
+	if err != nil {
+		c.SetItemError(policy.DDLAlgorithm.Name, err)
+		return fmt.Errorf("collect server info failed, %s", err)
+	}
+
+	ddl, ok := AnalyzeOnlineDDL(stmt, server, c.ddlTableMeta())
+	if !ok {
+		return nil
+	}
+	cost := int(time.Now().Sub(start).Milliseconds())
+	c.SetItemValue(policy.DDLAlgorithm.Name, policy.DDLAlgorithm.ID, ddl.Algorithm, cost)
+	c.SetItemValue(policy.DDLLock.Name, policy.DDLLock.ID, ddl.Lock, cost)
+	c.SetItemValue(policy.DDLRebuild.Name, policy.DDLRebuild.ID, ddl.Rebuild, cost)
+	c.SetItemValue(policy.DDLDuration.Name, policy.DDLDuration.ID, ddl.Duration, cost)
+	return nil
+}
+
+// CollectLockContention 查询表上持有或等待锁的会话，判断DML、DDL执行时是否需要排队等待，以及DDL排队时是否会阻塞其他会话。
+// 锁信息是实时的，不做缓存；未开启mdl监控时，以未提交的空闲事务近似代替元数据锁的持有者
+func (c *SQLRisk) CollectLockContention() error {
+	operate, err := c.GetItemValueWithOperateType(policy.Operate.ID)
+	if err != nil {
+		return err
+	}
+	if operate != policy.Operate.V.DML && operate != policy.Operate.V.DDL {
+		return nil
+	}
+
+	start := time.Now()
+	sessions, err := c.lockSessions()
+	if err != nil {
+		// 没有performance_schema等权限时不影响其他风险项的识别
+		c.SetItemError(policy.LockBlocked.Name, err)
+		return nil
+	}
+
+	c.BlockingSessions = BlockingSessions(operate == policy.Operate.V.DDL, sessions)
+	c.UnknownSessions = UnknownSessions(operate == policy.Operate.V.DDL, sessions)
+	waitSeconds := 0
+	for _, s := range c.BlockingSessions {
+		if s.Time > waitSeconds {
+			waitSeconds = s.Time
+		}
+	}
+	cost := int(time.Now().Sub(start).Milliseconds())
+	c.SetItemValue(policy.LockBlocked.Name, policy.LockBlocked.ID, len(c.BlockingSessions) != 0, cost)
+	c.SetItemValue(policy.LockWaitSeconds.Name, policy.LockWaitSeconds.ID, waitSeconds, cost)
 	return nil
 }
 
+func (c *SQLRisk) lockSessions() ([]LockSession, error) {
+	conn, err := c.dialect(c.DataBase, false)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	sessions := make([]LockSession, 0, 1)
+	for _, t := range c.Tables {
+		// 工单中新建的表上没有其他会话
+		if _, ok := c.createdTable(t); ok {
+			continue
+		}
+
+		db, tabName := comm.SplitDataBaseAndTable(t)
+		if db == "" || tabName == "" {
+			continue
+		}
+
+		locks, err := conn.LockSessions(db, tabName)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, locks...)
+	}
+	return sessions, nil
+}
+
+// GenerateOSCPlan ALTER TABLE操作大表（超过TabSizeThreshold）或需要COPY算法时生成gh-ost或pt-online-schema-change的改表方案
+func (c *SQLRisk) GenerateOSCPlan() *OSCPlan {
+	stmt, err := parser.New().ParseOneStmt(c.SQLText, "", "")
+	if err != nil {
+		return nil
+	}
+	st, ok := stmt.(*ast.AlterTableStmt)
+	if !ok || st.Table == nil {
+		return nil
+	}
+
+	meta := c.ddlTableMeta()
+	reason := ""
+	algorithm, _ := c.GetItemValueWithString(policy.DDLAlgorithm.ID)
+	switch {
+	case algorithm == DDLAlgorithmCopy:
+		reason = "DDL需要以COPY算法执行，期间阻塞DML"
+	case c.Config != nil && meta.Size > c.Config.RiskConfig.TabSizeThreshold:
+		reason = fmt.Sprintf("表大小%dMB超过%dMB", meta.Size, c.Config.RiskConfig.TabSizeThreshold)
+	default:
+		return nil
+	}
+
+	target := OSCTarget{Host: c.Addr, Port: c.Port, User: c.User, Database: st.Table.Schema.O, Table: st.Table.Name.O}
+	if target.Database == "" {
+		target.Database = c.DataBase
+	}
+	return NewOSCPlan(st, target, meta, reason)
+}
+
+// ddlTableMeta 表信息在此之前已经采集，采集失败时按有主键、无外键和触发器处理
+func (c *SQLRisk) ddlTableMeta() DDLTableMeta {
+	meta := DDLTableMeta{PrimaryKeyExist: true}
+	meta.Size, _ = c.GetItemValueWithInt(policy.TabSize.ID)
+	meta.Rows, _ = c.GetItemValueWithInt(policy.TabRows.ID)
+	if pk, err := c.GetItemValueWithBool(policy.PrimaryKeyExist.ID); err == nil {
+		meta.PrimaryKeyExist = pk
+	}
+	meta.ForeignKeyExist, _ = c.GetItemValueWithBool(policy.ForeignKeyExist.ID)
+	meta.TriggerExist, _ = c.GetItemValueWithBool(policy.TriggerExist.ID)
+	return meta
+}
+
 // CollectAction 解析SQL的action
-// TODO tidb/parser目前还不支持触发器、存储过程、自定义函数、外键
+// tidb/parser目前还不支持触发器、存储过程、自定义函数、事件，解析失败时通过分词识别这些语句
 func (c *SQLRisk) CollectAction() (policy.OperateType, policy.ActionType, policy.KeyWordType, error) {
+	if c.postgres() {
+		stmt, err := comm.ParsePostgres(c.SQLText, "")
+		if err != nil {
+			return policy.Operate.V.Unknown, policy.Action.V.Unknown, policy.KeyWord.V.Unknown, fmt.Errorf("parse sql failed, %s", err)
+		}
+		return collectPostgresAction(stmt)
+	}
+
 	stmt, err := parser.New().ParseOneStmt(c.SQLText, "", "")
 	if err != nil {
+		if routine, ok := comm.ParseRoutine(c.SQLText); ok {
+			return collectRoutineAction(routine)
+		}
+		if _, ok := comm.ParseOptimizeTable(c.SQLText, c.DataBase); ok {
+			// 优化表：OPTIMIZE TABLE mytable;
+			return policy.Operate.V.Admin, policy.Action.V.Optimize, policy.KeyWord.V.OptimizeTab, nil
+		}
 		return policy.Operate.V.Unknown, policy.Action.V.Unknown, policy.KeyWord.V.Unknown, fmt.Errorf("parse sql failed, %s", err)
 	}
 
@@ -419,49 +749,8 @@ func (c *SQLRisk) CollectAction() (policy.OperateType, policy.ActionType, policy
 
 	/* alert相关操作 */
 	case *ast.AlterTableStmt:
-		st := stmt.(*ast.AlterTableStmt)
-		for _, spec := range st.Specs {
-			switch spec.Tp {
-			case ast.AlterTableAddColumns:
-				// 添加列：ALTER TABLE students ADD COLUMN score DECIMAL(5,2);
-				return policy.Operate.V.DDL, policy.Action.V.Alter, policy.KeyWord.V.AlertAddCol, nil
-			case ast.AlterTableDropColumn:
-				// 删除列：ALTER TABLE students DROP COLUMN score;
-				return policy.Operate.V.DDL, policy.Action.V.Alter, policy.KeyWord.V.AlertDropCol, nil
-			case ast.AlterTableModifyColumn:
-				// 修改列的数据类型或属性：ALTER TABLE students MODIFY COLUMN age INT;
-				return policy.Operate.V.DDL, policy.Action.V.Alter, policy.KeyWord.V.AlertModCol, nil
-			case ast.AlterTableChangeColumn:
-				// 修改表中某个列的名称、数据类型或属性，还可改变列的位置
-				// ALTER TABLE table_name CHANGE old_column_name new_column_name column_definition FIRST|AFTER column_name;
-				return policy.Operate.V.DDL, policy.Action.V.Alter, policy.KeyWord.V.AlertChgCol, nil
-			case ast.AlterTableRenameColumn:
-				// 修改表或列的名称: ALTER TABLE students RENAME COLUMN student_name TO full_name;
-				return policy.Operate.V.DDL, policy.Action.V.Alter, policy.KeyWord.V.AlertRenameCol, nil
-			case ast.AlterTableAddConstraint:
-				// 添加约束
-				switch spec.Constraint.Tp {
-				case ast.ConstraintPrimaryKey:
-					// 添加主键约束 ：ALTER TABLE students ADD CONSTRAINT pk_students PRIMARY KEY (id);
-					return policy.Operate.V.DDL, policy.Action.V.Alter, policy.KeyWord.V.AlertAddPriKey, nil
-				case ast.ConstraintIndex:
-					// 添加索引 ALTER TABLE my_table ADD INDEX idx_name (column_name);
-					return policy.Operate.V.DDL, policy.Action.V.Alter, policy.KeyWord.V.AlertAddIdx, nil
-				case ast.ConstraintUniq, ast.ConstraintUniqIndex:
-					// TODO: 添加唯一索引, tidb识别错误会被识别为 ConstraintUniq
-					//return Operate.V.DDL, Alter, AlertAddUniIdx, nil
-					return policy.Operate.V.DDL, policy.Action.V.Alter, policy.KeyWord.V.AlertAddUni, nil
-				}
-			case ast.AlterTableDropPrimaryKey:
-				// 删除主键：ALTER TABLE my_table DROP PRIMARY KEY;
-				return policy.Operate.V.DDL, policy.Action.V.Alter, policy.KeyWord.V.AlertDropPriKey, nil
-			case ast.AlterTableDropIndex:
-				// 删除索引：ALTER TABLE my_table DROP INDEX idx_nam
-				return policy.Operate.V.DDL, policy.Action.V.Alter, policy.KeyWord.V.AlertDropIdx, nil
-			}
-		}
-		// alert的其他操作
-		return policy.Operate.V.DDL, policy.Action.V.Alter, policy.KeyWord.V.Alter, nil
+		// 一条ALTER可能包含多个子句，取风险最高的子句作为关键字
+		return policy.Operate.V.DDL, policy.Action.V.Alter, policy.RiskiestKeyWord(alterKeyWords(stmt.(*ast.AlterTableStmt))), nil
 	case *ast.InsertStmt:
 		st := stmt.(*ast.InsertStmt)
 		if st.IsReplace {
@@ -492,10 +781,253 @@ func (c *SQLRisk) CollectAction() (policy.OperateType, policy.ActionType, policy
 		return policy.Operate.V.DML, policy.Action.V.Update, policy.KeyWord.V.Update, nil
 	case *ast.RenameTableStmt:
 		return policy.Operate.V.DDL, policy.Action.V.Rename, policy.KeyWord.V.RenameTable, nil
+
+	/* 授权和用户管理 */
+	case *ast.GrantStmt:
+		// 授权：GRANT SELECT ON db.* TO 'user'@'%';
+		return policy.Operate.V.DCL, policy.Action.V.Grant, policy.KeyWord.V.Grant, nil
+	case *ast.RevokeStmt:
+		// 回收权限：REVOKE SELECT ON db.* FROM 'user'@'%';
+		return policy.Operate.V.DCL, policy.Action.V.Revoke, policy.KeyWord.V.Revoke, nil
+	case *ast.CreateUserStmt:
+		// 创建用户：CREATE USER 'user'@'10.%' IDENTIFIED BY 'password';
+		return policy.Operate.V.DCL, policy.Action.V.User, policy.KeyWord.V.CreateUser, nil
+	case *ast.DropUserStmt:
+		// 删除用户：DROP USER 'user'@'10.%';
+		return policy.Operate.V.DCL, policy.Action.V.User, policy.KeyWord.V.DropUser, nil
+	case *ast.AlterUserStmt:
+		// 修改用户：ALTER USER 'user'@'10.%' IDENTIFIED BY 'password';
+		return policy.Operate.V.DCL, policy.Action.V.User, policy.KeyWord.V.AlterUser, nil
+	case *ast.SetPwdStmt:
+		// 修改密码：SET PASSWORD FOR 'user'@'10.%' = 'password';
+		return policy.Operate.V.DCL, policy.Action.V.User, policy.KeyWord.V.SetPassword, nil
+	case *ast.FlushStmt:
+		st := stmt.(*ast.FlushStmt)
+		if st.Tp == ast.FlushPrivileges {
+			// 刷新权限：FLUSH PRIVILEGES;
+			return policy.Operate.V.DCL, policy.Action.V.Flush, policy.KeyWord.V.FlushPrivileges, nil
+		}
+
+	/* 会话和管理语句 */
+	case *ast.SetStmt:
+		return policy.Operate.V.Admin, policy.Action.V.Set, setKeyWord(stmt.(*ast.SetStmt)), nil
+	case *ast.UseStmt:
+		// 切换库：USE mydatabase;
+		return policy.Operate.V.Admin, policy.Action.V.Use, policy.KeyWord.V.Use, nil
+	case *ast.LockTablesStmt:
+		// 锁表：LOCK TABLES mytable WRITE;
+		return policy.Operate.V.Admin, policy.Action.V.Lock, policy.KeyWord.V.LockTabs, nil
+	case *ast.UnlockTablesStmt:
+		// 解锁：UNLOCK TABLES;
+		return policy.Operate.V.Admin, policy.Action.V.Lock, policy.KeyWord.V.UnlockTabs, nil
+	case *ast.KillStmt:
+		// 终止会话：KILL 123;
+		return policy.Operate.V.Admin, policy.Action.V.Kill, policy.KeyWord.V.Kill, nil
+	case *ast.AnalyzeTableStmt:
+		// 分析表：ANALYZE TABLE mytable;
+		return policy.Operate.V.Admin, policy.Action.V.Analyze, policy.KeyWord.V.AnalyzeTab, nil
+	case *ast.LoadDataStmt:
+		// 导入数据：LOAD DATA INFILE 'data.txt' INTO TABLE mytable;
+		return policy.Operate.V.DML, policy.Action.V.Load, policy.KeyWord.V.LoadData, nil
 	}
 	return policy.Operate.V.Unknown, policy.Action.V.Unknown, policy.KeyWord.V.Unknown, nil
 }
 
+// setKeyWord SET语句的关键字，多个变量时取风险最高的
+// 关闭binlog > 关闭外键检查 > 设置全局变量 > 设置会话变量
+func setKeyWord(st *ast.SetStmt) policy.KeyWordType {
+	keyword := policy.KeyWord.V.SetVariable
+	for _, v := range st.Variables {
+		if !v.IsSystem {
+			continue
+		}
+
+		off := comm.EleExist(strings.Trim(strings.ToLower(comm.RestoreExpr(v.Value)), "`'\""), []string{"0", "off", "false"})
+		switch {
+		case strings.EqualFold(v.Name, "sql_log_bin") && off:
+			return policy.KeyWord.V.DisableBinlog
+		case strings.EqualFold(v.Name, "foreign_key_checks") && off:
+			keyword = policy.KeyWord.V.DisableFKCheck
+		case v.IsGlobal && keyword == policy.KeyWord.V.SetVariable:
+			keyword = policy.KeyWord.V.SetGlobal
+		}
+	}
+	return keyword
+}
+
+// CollectKeyWords 解析SQL包含的所有关键字，目前只有ALTER TABLE会包含多个子句，其他语句返回nil
+func (c *SQLRisk) CollectKeyWords() ([]policy.KeyWordType, error) {
+	if c.postgres() {
+		stmt, err := comm.ParsePostgres(c.SQLText, "")
+		if err != nil || stmt.Verb != "alter" || stmt.Object != "table" {
+			return nil, nil
+		}
+		return postgresAlterKeyWords(stmt), nil
+	}
+
+	stmt, err := parser.New().ParseOneStmt(c.SQLText, "", "")
+	if err != nil {
+		return nil, nil
+	}
+	if st, ok := stmt.(*ast.AlterTableStmt); ok {
+		return alterKeyWords(st), nil
+	}
+	return nil, nil
+}
+
+// alterKeyWords ALTER TABLE每个子句对应的关键字，去重后按子句顺序返回
+func alterKeyWords(st *ast.AlterTableStmt) []policy.KeyWordType {
+	keywords := make([]policy.KeyWordType, 0, len(st.Specs))
+	add := func(kw policy.KeyWordType) {
+		if !comm.EleExist(kw, keywords) {
+			keywords = append(keywords, kw)
+		}
+	}
+
+	for _, spec := range st.Specs {
+		switch spec.Tp {
+		case ast.AlterTableAddColumns:
+			// 添加列：ALTER TABLE students ADD COLUMN score DECIMAL(5,2);
+			add(policy.KeyWord.V.AlertAddCol)
+		case ast.AlterTableDropColumn:
+			// 删除列：ALTER TABLE students DROP COLUMN score;
+			add(policy.KeyWord.V.AlertDropCol)
+		case ast.AlterTableModifyColumn:
+			// 修改列的数据类型或属性：ALTER TABLE students MODIFY COLUMN age INT;
+			add(policy.KeyWord.V.AlertModCol)
+		case ast.AlterTableChangeColumn:
+			// 修改表中某个列的名称、数据类型或属性，还可改变列的位置
+			// ALTER TABLE table_name CHANGE old_column_name new_column_name column_definition FIRST|AFTER column_name;
+			add(policy.KeyWord.V.AlertChgCol)
+		case ast.AlterTableRenameColumn:
+			// 修改表或列的名称: ALTER TABLE students RENAME COLUMN student_name TO full_name;
+			add(policy.KeyWord.V.AlertRenameCol)
+		case ast.AlterTableAddConstraint:
+			// 添加约束
+			switch spec.Constraint.Tp {
+			case ast.ConstraintPrimaryKey:
+				// 添加主键约束 ：ALTER TABLE students ADD CONSTRAINT pk_students PRIMARY KEY (id);
+				add(policy.KeyWord.V.AlertAddPriKey)
+			case ast.ConstraintIndex:
+				// 添加索引 ALTER TABLE my_table ADD INDEX idx_name (column_name);
+				add(policy.KeyWord.V.AlertAddIdx)
+			case ast.ConstraintUniq, ast.ConstraintUniqIndex:
+				// TODO: 添加唯一索引, tidb识别错误会被识别为 ConstraintUniq
+				add(policy.KeyWord.V.AlertAddUni)
+			default:
+				add(policy.KeyWord.V.Alter)
+			}
+		case ast.AlterTableDropPrimaryKey:
+			// 删除主键：ALTER TABLE my_table DROP PRIMARY KEY;
+			add(policy.KeyWord.V.AlertDropPriKey)
+		case ast.AlterTableDropIndex:
+			// 删除索引：ALTER TABLE my_table DROP INDEX idx_nam
+			add(policy.KeyWord.V.AlertDropIdx)
+		case ast.AlterTableRenameTable:
+			// 重命名表：ALTER TABLE my_table RENAME TO new_table;
+			add(policy.KeyWord.V.AlertRenameTab)
+		case ast.AlterTableOption:
+			// 表选项：ENGINE=InnoDB、CONVERT TO CHARACTER SET utf8mb4、DEFAULT CHARSET=utf8mb4
+			add(alterOptionKeyWord(spec.Options))
+		case ast.AlterTableAddPartitions:
+			// 添加分区：ALTER TABLE my_table ADD PARTITION (PARTITION p3 VALUES LESS THAN (2024));
+			add(policy.KeyWord.V.AlertAddPart)
+		case ast.AlterTableDropPartition:
+			// 删除分区：ALTER TABLE my_table DROP PARTITION p1;
+			add(policy.KeyWord.V.AlertDropPart)
+		case ast.AlterTableTruncatePartition:
+			// 清空分区：ALTER TABLE my_table TRUNCATE PARTITION p1;
+			add(policy.KeyWord.V.AlertTruncPart)
+		case ast.AlterTablePartition, ast.AlterTableCoalescePartitions, ast.AlterTableReorganizePartition,
+			ast.AlterTableExchangePartition, ast.AlterTableRemovePartitioning, ast.AlterTableRebuildPartition:
+			// 修改分区方式、合并、重组、交换、重建分区：ALTER TABLE my_table REORGANIZE PARTITION p1 INTO (...);
+			add(policy.KeyWord.V.AlertPartition)
+		case ast.AlterTableAlgorithm:
+			// 指定DDL算法：ALTER TABLE my_table ADD INDEX idx_name (name), ALGORITHM=INPLACE;
+			if spec.Algorithm == ast.AlgorithmTypeCopy {
+				add(policy.KeyWord.V.AlertAlgCopy)
+			} else {
+				add(policy.KeyWord.V.AlertAlgorithm)
+			}
+		case ast.AlterTableLock:
+			// 指定锁级别：ALTER TABLE my_table ADD INDEX idx_name (name), LOCK=NONE;
+			if spec.LockType == ast.LockTypeExclusive {
+				add(policy.KeyWord.V.AlertLockExcl)
+			} else {
+				add(policy.KeyWord.V.AlertLock)
+			}
+		default:
+			// alert的其他操作
+			add(policy.KeyWord.V.Alter)
+		}
+	}
+
+	if len(keywords) == 0 {
+		keywords = append(keywords, policy.KeyWord.V.Alter)
+	}
+	return keywords
+}
+
+// alterOptionKeyWord 表选项对应的关键字，CONVERT TO CHARACTER SET会改写数据，风险高于修改存储引擎
+func alterOptionKeyWord(options []*ast.TableOption) policy.KeyWordType {
+	keyword := policy.KeyWord.V.Alter
+	for _, opt := range options {
+		switch opt.Tp {
+		case ast.TableOptionCharset, ast.TableOptionCollate:
+			if opt.UintValue == ast.TableOptionCharsetWithConvertTo {
+				return policy.KeyWord.V.AlertConvert
+			}
+			if keyword == policy.KeyWord.V.Alter {
+				keyword = policy.KeyWord.V.AlertCharset
+			}
+		case ast.TableOptionEngine:
+			keyword = policy.KeyWord.V.AlertEngine
+		}
+	}
+	return keyword
+}
+
+// routineKeyWords 存储过程、函数、触发器、事件的DDL对应的关键字
+var routineKeyWords = map[string]map[string]policy.KeyWordType{
+	comm.RoutineCreate: {
+		comm.RoutineProcedure: policy.KeyWord.V.CreateProcedure,
+		comm.RoutineFunction:  policy.KeyWord.V.CreateFunc,
+		comm.RoutineTrigger:   policy.KeyWord.V.CreateTrig,
+		comm.RoutineEvent:     policy.KeyWord.V.CreateEvent,
+	},
+	comm.RoutineDrop: {
+		comm.RoutineProcedure: policy.KeyWord.V.DropProcedure,
+		comm.RoutineFunction:  policy.KeyWord.V.DropFun,
+		comm.RoutineTrigger:   policy.KeyWord.V.DropTrig,
+		comm.RoutineEvent:     policy.KeyWord.V.DropEvent,
+	},
+	comm.RoutineAlter: {
+		comm.RoutineProcedure: policy.KeyWord.V.AlterProcedure,
+		comm.RoutineFunction:  policy.KeyWord.V.AlterFunc,
+		comm.RoutineEvent:     policy.KeyWord.V.AlterEvent,
+	},
+}
+
+// collectRoutineAction 存储过程、函数、触发器、事件的action
+func collectRoutineAction(routine *comm.RoutineStmt) (policy.OperateType, policy.ActionType, policy.KeyWordType, error) {
+	keyword, ok := routineKeyWords[routine.Action][routine.Type]
+	if !ok {
+		return policy.Operate.V.Unknown, policy.Action.V.Unknown, policy.KeyWord.V.Unknown, nil
+	}
+
+	switch routine.Action {
+	case comm.RoutineCreate:
+		// 创建存储过程：CREATE PROCEDURE p1() BEGIN ... END
+		return policy.Operate.V.DDL, policy.Action.V.Create, keyword, nil
+	case comm.RoutineDrop:
+		// 删除触发器：DROP TRIGGER IF EXISTS tr1
+		return policy.Operate.V.DDL, policy.Action.V.Drop, keyword, nil
+	default:
+		// 修改事件：ALTER EVENT e1 DISABLE
+		return policy.Operate.V.DDL, policy.Action.V.Alter, keyword, nil
+	}
+}
+
 // CollectAffectRows 获取SQL的响应行数，依赖CollectAction先执行
 // 1, 非DML操作直接返回0
 // 2, delete和update没有where条件的属于全表更新直接返回表行数
@@ -511,6 +1043,15 @@ func (c *SQLRisk) CollectAffectRows() (int, error) {
 		return 0, nil
 	}
 
+	kw, err := c.GetItemValueWithKeyWordType(policy.KeyWord.ID)
+	if err != nil {
+		return 0, fmt.Errorf("attempt to query KeyWord for collecting AffectRows failed, %s", err)
+	}
+	// LOAD DATA的影响行数取决于文件内容，无法预估
+	if kw == policy.KeyWord.V.LoadData {
+		return 0, nil
+	}
+
 	// 获取表行数
 	tabRows, err := c.GetItemValueWithInt(policy.TabRows.ID)
 	if err != nil {
@@ -539,10 +1080,10 @@ func (c *SQLRisk) CollectAffectRows() (int, error) {
 		}
 	}
 
-	kw, err := c.GetItemValueWithKeyWordType(policy.KeyWord.ID)
-	if err != nil {
-		return 0, fmt.Errorf("attempt to query KeyWord for collecting AffectRows failed, %s", err)
+	if c.postgres() {
+		return c.postgresAffectRows(tabRows)
 	}
+
 	// 没有where条件相当于全表更新,直接返回表行数
 	if kw == policy.KeyWord.V.Delete || kw == policy.KeyWord.V.Update {
 		return tabRows, nil
@@ -560,7 +1101,12 @@ func (c *SQLRisk) CollectAffectRows() (int, error) {
 		return rows, nil
 	}
 
-	conn, err := NewConnector(NewDSN(c.Addr, c.Port, c.User, c.Passwd, c.DataBase))
+	// 查询的表在工单中新建、数据库中还不存在时无法执行，按表的行数估算
+	if tables, err := comm.ExtractingRelatedTableName(selectSQL, c.DataBase); err == nil && c.hasCreatedTable(tables) {
+		return c.tableRows(tables)
+	}
+
+	conn, err := c.connectReplica(c.DataBase)
 	if err != nil {
 		return 0, fmt.Errorf("new mysql connect failed, %s", err)
 	}
@@ -605,19 +1151,28 @@ func (c *SQLRisk) CollectTableExist() (bool, error) {
 		policy.KeyWord.V.DropTabIfExist}) {
 		return false, nil
 	}
+	return c.tableExist(c.Tables)
+}
+
+// tableExist 查询表是否都存在，任意一个表不存在时返回错误
+func (c *SQLRisk) tableExist(tables []string) (bool, error) {
+	for _, t := range tables {
+		// 工单中新建的表在数据库中还不存在
+		if _, ok := c.createdTable(t); ok {
+			continue
+		}
 
-	for _, t := range c.Tables {
 		db, tabName := comm.SplitDataBaseAndTable(t)
 		if db == "" || tabName == "" {
 			continue
 		}
 
-		conn, err := NewConnector(NewDSN(c.Addr, c.Port, c.User, c.Passwd, db))
+		conn, err := c.dialect(db, true)
 		if err != nil {
-			return false, fmt.Errorf("new mysql connect failed, %s", err)
+			return false, err
 		}
 
-		// 查询表大小
+		// 查询表是否存在
 		b, err := conn.TableExist(db, tabName)
 		if err != nil {
 			return false, err
@@ -637,17 +1192,34 @@ func (c *SQLRisk) CollectTableExist() (bool, error) {
 
 // CollectTableSize 获取表大小
 func (c *SQLRisk) CollectTableSize() (int, error) {
+	return c.tableSize(c.Tables)
+}
+
+// tableSize 查询多个表中最大的表大小
+func (c *SQLRisk) tableSize(tables []string) (int, error) {
 	maxSize := 0
 
-	for _, t := range c.Tables {
+	for _, t := range tables {
+		// 工单中新建的表按写入数据的源表估算
+		if st, ok := c.createdTable(t); ok {
+			size, err := c.tableSize(st.Sources)
+			if err != nil {
+				return 0, err
+			}
+			if size > maxSize {
+				maxSize = size
+			}
+			continue
+		}
+
 		db, tabName := comm.SplitDataBaseAndTable(t)
 		if db == "" || tabName == "" {
 			continue
 		}
 
-		conn, err := NewConnector(NewDSN(c.Addr, c.Port, c.User, c.Passwd, db))
+		conn, err := c.dialect(db, true)
 		if err != nil {
-			return 0, fmt.Errorf("new mysql connect failed, %s", err)
+			return 0, err
 		}
 
 		// 查询表大小
@@ -670,21 +1242,38 @@ func (c *SQLRisk) CollectTableSize() (int, error) {
 
 // CollectTableRows 获取表的行数
 func (c *SQLRisk) CollectTableRows() (int, error) {
+	return c.tableRows(c.Tables)
+}
+
+// tableRows 查询多个表中最大的行数
+func (c *SQLRisk) tableRows(tables []string) (int, error) {
 	maxRows := 0
 
-	for _, t := range c.Tables {
+	for _, t := range tables {
+		// 工单中新建的表按写入数据的源表和写入的行数估算
+		if st, ok := c.createdTable(t); ok {
+			rows, err := c.tableRows(st.Sources)
+			if err != nil {
+				return 0, err
+			}
+			if rows+st.Rows > maxRows {
+				maxRows = rows + st.Rows
+			}
+			continue
+		}
+
 		db, tabName := comm.SplitDataBaseAndTable(t)
 		if db == "" || tabName == "" {
 			continue
 		}
 
-		conn, err := NewConnector(NewDSN(c.Addr, c.Port, c.User, c.Passwd, db))
+		conn, err := c.dialect(db, true)
 		if err != nil {
-			return 0, fmt.Errorf("new mysql connect failed, %s", err)
+			return 0, err
 		}
 
-		// 查询表大小
-		rows, err := conn.TableRows(tabName)
+		// 查询表行数
+		rows, err := conn.TableRows(db, tabName)
 
 		if closeErr := conn.Close(); closeErr != nil {
 			return 0, fmt.Errorf("close connect failed, %s", closeErr)
@@ -703,27 +1292,42 @@ func (c *SQLRisk) CollectTableRows() (int, error) {
 
 // CollectFreeDisk 剩余磁盘空间
 func (c *SQLRisk) CollectFreeDisk() (int, error) {
-	addr := c.Addr
-	if c.ReadWriteAddr != "" {
-		addr = c.ReadWriteAddr
+	if c.backendType() == TiDBBackend {
+		return c.CollectTiDBFreeDisk()
 	}
 
-	disk, err := NewClient(c.Config.Runtime.Url).DiskFree(addr, time.Now())
+	metrics := NewMetricsProvider(c.Config.Runtime)
+	disk, err := metrics.DiskFree(c.metricsTarget(), time.Now())
 	if err == nil {
 		return int(disk), nil
 	}
 
-	if strings.Contains(err.Error(), NoDataPointError.Error()) {
-		// 找不到数据，向前提5min再试一次
-		disk, err = NewClient(c.Config.Runtime.Url).DiskFree(addr, time.Now().Add(-5*time.Minute))
-		if err != nil {
-			return 0, err
-		}
+	if !strings.Contains(err.Error(), NoDataPointError.Error()) {
+		return 0, err
+	}
+
+	// 找不到数据，向前提5min再试一次
+	disk, err = metrics.DiskFree(c.metricsTarget(), time.Now().Add(-5*time.Minute))
+	if err != nil {
+		return 0, err
 	}
 	return int(disk), nil
 }
 
-// CollectDiskSufficient 磁盘是否充足
+// CollectDiskValues 采集剩余磁盘空间和磁盘是否充足，未配置监控时跳过（TiDB从PD查询，不依赖监控），
+// 对应的策略不参与匹配，也不作为错误记录
+func (c *SQLRisk) CollectDiskValues() error {
+	if !c.Config.Runtime.configured() && c.backendType() != TiDBBackend {
+		return nil
+	}
+	err := c.CollectValueWithCache(policy.FreeDisk.Name, policy.FreeDisk.ID, []string{c.Addr}, "CollectFreeDisk", true)
+	if err != nil {
+		return err
+	}
+	return c.CollectValueWithCache(policy.DiskSufficient.Name, policy.DiskSufficient.ID, []string{c.SQLID}, "CollectDiskSufficient", true)
+}
+
+// CollectDiskSufficient 磁盘是否充足：扣除预测时长内磁盘的消耗后，剩余空间能否容纳SQL执行过程中需要的空间
 func (c *SQLRisk) CollectDiskSufficient() (bool, error) {
 	freeDisk, err := c.GetItemValueWithInt(policy.FreeDisk.ID)
 	if err != nil {
@@ -738,52 +1342,52 @@ func (c *SQLRisk) CollectDiskSufficient() (bool, error) {
 		}
 	}
 
-	tabSize, err := c.GetItemValueWithInt(policy.TabSize.ID)
-	if err != nil {
-		err = c.CollectValueWithCache(policy.TabSize.Name, policy.TabSize.ID, nil, "CollectTableSize", true)
-		if err != nil {
-			return false, fmt.Errorf("attempt to collect TabSize for collecting DiskSufficient failed, %s", err)
-		}
-
-		tabSize, err = c.GetItemValueWithInt(policy.TabSize.ID)
-		if err != nil {
-			return false, fmt.Errorf("attempt to query TabSize for collecting DiskSufficient failed, %s", err)
-		}
-	}
-
-	return freeDisk > tabSize, nil
+	projected := float64(freeDisk) - c.diskConsumedMB(NewMetricsProvider(c.Config.Runtime), time.Now())
+	return projected > float64(c.RequiredDiskMB()), nil
 }
 
 // CollectCpuUsage CPU使用率
 func (c *SQLRisk) CollectCpuUsage() (int, error) {
-	addr := c.Addr
-	if c.ReadWriteAddr != "" {
-		addr = c.ReadWriteAddr
-	}
-	cpu, err := NewClient(c.Config.Runtime.Url).CpuUsage(addr, time.Now())
+	metrics := NewMetricsProvider(c.Config.Runtime)
+	cpu, err := metrics.CpuUsage(c.metricsTarget(), time.Now())
 	if err == nil {
 		return int(cpu), nil
 	}
 
-	if strings.Contains(err.Error(), NoDataPointError.Error()) {
-		// 找不到数据，向前提5min再试一次
-		cpu, err = NewClient(c.Config.Runtime.Url).CpuUsage(addr, time.Now().Add(time.Minute*-5))
-		if err != nil {
-			return 0, err
-		}
+	if !strings.Contains(err.Error(), NoDataPointError.Error()) {
+		return 0, err
+	}
+
+	// 找不到数据，向前提5min再试一次
+	cpu, err = metrics.CpuUsage(c.metricsTarget(), time.Now().Add(time.Minute*-5))
+	if err != nil {
+		return 0, err
 	}
 	return int(cpu), nil
 }
 
+// metricsTarget 自建集群无法根据vip查询到监控信息，配置了读写库地址时按读写库查询
+func (c *SQLRisk) metricsTarget() MetricsTarget {
+	addr := c.Addr
+	if c.ReadWriteAddr != "" {
+		addr = c.ReadWriteAddr
+	}
+	target := MetricsTarget{Addr: addr, Port: c.Port, Class: c.MetricsClass}
+	if !c.postgres() {
+		target.Connect = func() (*Connector, error) { return c.connect(c.DataBase) }
+	}
+	return target
+}
+
 // CollectTranRelated 事务是否与表相关
 func (c *SQLRisk) CollectTranRelated() (bool, error) {
-	conn, err := NewConnector(NewDSN(c.Addr, c.Port, c.User, c.Passwd, c.DataBase))
+	conn, err := c.dialect(c.DataBase, false)
 	if err != nil {
-		return false, fmt.Errorf("new mysql connect failed, %s", err)
+		return false, err
 	}
 
 	// 查询事务
-	trxs, err := conn.TableTransaction()
+	trxs, err := conn.Transactions()
 	if err != nil {
 		return false, fmt.Errorf("query table transaction failed, %s", err)
 	}
@@ -797,7 +1401,7 @@ func (c *SQLRisk) CollectTranRelated() (bool, error) {
 	// 事务中涉及的库和表
 	var trxTables []string
 
-	riskTables, err = comm.ExtractingTableName(c.SQLText, c.DataBase)
+	riskTables, err = c.extractingTableName(c.SQLText)
 	if err != nil {
 		return false, fmt.Errorf("extracting SQL(%s) table name failed, %s", c.SQLText, err)
 	}
@@ -817,7 +1421,7 @@ func (c *SQLRisk) CollectTranRelated() (bool, error) {
 			continue
 		}
 
-		trxTables, err = comm.ExtractingTableName(trx.Query, c.DataBase)
+		trxTables, err = c.extractingTableName(trx.Query)
 		if err != nil {
 			return false, fmt.Errorf("extracting trx.Query(%s) table name failed, %s", trx.Query, err)
 		}
@@ -840,13 +1444,13 @@ func (c *SQLRisk) CollectPrimaryKeyExist() (bool, error) {
 		return false, err
 	}
 	// 不需要判断主键的情况
-	if comm.EleExist(keyword, []policy.KeyWordType{
+	if c.HasKeyWord(
 		policy.KeyWord.V.DropTabIfExist,
 		policy.KeyWord.V.DropTab,
 		policy.KeyWord.V.DropDB,
 		policy.KeyWord.V.CreateView,
 		policy.KeyWord.V.DropView,
-		policy.KeyWord.V.AlertAddPriKey}) {
+		policy.KeyWord.V.AlertAddPriKey) {
 		return true, nil
 	}
 
@@ -867,12 +1471,23 @@ func (c *SQLRisk) CollectPrimaryKeyExist() (bool, error) {
 
 	// 需要连库判断主键是否存在
 	for _, t := range c.Tables {
+		// 工单中新建的表按建表语句判断，LIKE建表时与原表相同
+		if st, ok := c.createdTable(t); ok {
+			if st.PrimaryKey != nil || st.Like == "" {
+				if st.PrimaryKey != nil && *st.PrimaryKey {
+					return true, nil
+				}
+				continue
+			}
+			t = st.Like
+		}
+
 		db, tabName := comm.SplitDataBaseAndTable(t)
 		if db == "" || tabName == "" {
 			continue
 		}
 
-		conn, err := NewConnector(NewDSN(c.Addr, c.Port, c.User, c.Passwd, db))
+		conn, err := c.connectReplica(db)
 		if err != nil {
 			return false, fmt.Errorf("new mysql connect failed, %s", err)
 		}
@@ -917,12 +1532,20 @@ func (c *SQLRisk) CollectForeignKeyExist() (bool, error) {
 	}
 
 	for _, t := range c.Tables {
+		// 工单中新建的表按建表语句判断
+		if st, ok := c.createdTable(t); ok {
+			if st.ForeignKey {
+				return true, nil
+			}
+			continue
+		}
+
 		db, tabName := comm.SplitDataBaseAndTable(t)
 		if db == "" || tabName == "" {
 			continue
 		}
 
-		conn, err := NewConnector(NewDSN(c.Addr, c.Port, c.User, c.Passwd, db))
+		conn, err := c.connectReplica(db)
 		if err != nil {
 			return false, fmt.Errorf("new mysql connect failed, %s", err)
 		}
@@ -966,12 +1589,17 @@ func (c *SQLRisk) CollectTriggerExist() (bool, error) {
 	}
 
 	for _, t := range c.Tables {
+		// 工单中新建的表还没有触发器
+		if _, ok := c.createdTable(t); ok {
+			continue
+		}
+
 		db, tabName := comm.SplitDataBaseAndTable(t)
 		if db == "" || tabName == "" {
 			continue
 		}
 
-		conn, err := NewConnector(NewDSN(c.Addr, c.Port, c.User, c.Passwd, db))
+		conn, err := c.connectReplica(db)
 		if err != nil {
 			return false, fmt.Errorf("new mysql connect failed, %s", err)
 		}
@@ -1014,12 +1642,30 @@ func (c *SQLRisk) CollectIndexExistInWhere() (bool, error) {
 	}
 
 	for _, t := range c.Tables {
-		db, tabName := comm.SplitDataBaseAndTable(t)
+		// 工单中新建的表按建表语句中的索引判断，LIKE建表时与原表相同
+		table := t
+		if st, ok := c.createdTable(t); ok {
+			if st.Like == "" {
+				tabCols, ok := columns[t]
+				if !ok {
+					tabCols = columns[""]
+				}
+				for _, col := range tabCols {
+					if comm.EleExist(strings.ToLower(col), st.Indexes) {
+						return true, nil
+					}
+				}
+				continue
+			}
+			table = st.Like
+		}
+
+		db, tabName := comm.SplitDataBaseAndTable(table)
 		if db == "" || tabName == "" {
 			continue
 		}
 
-		conn, err := NewConnector(NewDSN(c.Addr, c.Port, c.User, c.Passwd, db))
+		conn, err := c.connectReplica(db)
 		if err != nil {
 			return false, fmt.Errorf("new mysql connect failed, %s", err)
 		}
@@ -1053,6 +1699,155 @@ func (c *SQLRisk) CollectIndexExistInWhere() (bool, error) {
 	return false, nil
 }
 
+// CollectReplicaLag 查询所有从库中最大的复制延迟，单位s。从库包括数据源中配置的只读库和在主库上发现的从库，
+// 发现的从库使用主库的账号密码连接，无法连接或复制线程已停止的从库不参与计算，存在从库但都无法查询时返回NoReplicaLagError
+func (c *SQLRisk) CollectReplicaLag() (int, error) {
+	// 查询失败的结果不进入评估项缓存，同一个工单内不再重复连接无法查询的从库
+	key := strings.Join([]string{"ReplicaLagError", c.Addr, c.Port}, "|")
+	if v, ok := c.cache.Get(key); ok {
+		return 0, v.(error)
+	}
+
+	endpoints := make([]Endpoint, 0, len(c.Replicas)+2)
+	endpoints = append(endpoints, c.Replicas...)
+
+	conn, err := c.connect(c.DataBase)
+	if err != nil {
+		return 0, fmt.Errorf("new mysql connect failed, %s", err)
+	}
+	hosts, err := conn.ReplicaHosts()
+	if closeErr := conn.Close(); closeErr != nil {
+		return 0, fmt.Errorf("close connect failed, %s", closeErr)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("discover replicas failed, %s", err)
+	}
+	for _, h := range hosts {
+		port := h.Port
+		if port == "" || port == "0" {
+			port = c.Port
+		}
+		endpoints = append(endpoints, Endpoint{Addr: h.Host, Port: port, User: c.User, Passwd: c.Passwd, Credential: c.Credential})
+	}
+
+	lag, err := maxReplicaLag(endpoints, func(e Endpoint) (int, bool, error) {
+		conn, err := e.connect(c.cache, "")
+		if err != nil {
+			return 0, false, err
+		}
+		defer conn.Close()
+		return conn.ReplicationLag()
+	})
+	if err != nil {
+		c.cache.Set(key, err, 0)
+	}
+	return lag, err
+}
+
+// maxReplicaLag 依次查询每个从库的复制延迟并返回最大值，同一个地址只查询一次
+func maxReplicaLag(endpoints []Endpoint, lagOf func(e Endpoint) (int, bool, error)) (int, error) {
+	maxLag, read := 0, 0
+	var lastErr error
+	visited := make(map[string]bool, len(endpoints))
+	for _, e := range endpoints {
+		addr := fmt.Sprintf("%s:%s", e.Addr, e.Port)
+		if visited[addr] {
+			continue
+		}
+		visited[addr] = true
+
+		lag, ok, err := lagOf(e)
+		if err != nil {
+			lastErr = fmt.Errorf("%s: %s", addr, err)
+			continue
+		}
+		if !ok {
+			lastErr = fmt.Errorf("%s: replication is not running", addr)
+			continue
+		}
+		read++
+		if lag > maxLag {
+			maxLag = lag
+		}
+	}
+	if read == 0 && lastErr != nil {
+		return 0, fmt.Errorf("%w from %d replicas, %s", NoReplicaLagError, len(visited), lastErr)
+	}
+	return maxLag, nil
+}
+
+// CollectEstimatedBinlogMB 按 影响行数 × 表的平均行长度 预估DML产生的binlog大小，单位MB。
+// 按binlog_row_image=FULL估算，UPDATE同时记录前后镜像，按2倍计算
+func (c *SQLRisk) CollectEstimatedBinlogMB() (int, error) {
+	action, err := c.GetItemValueWithActionType(policy.Action.ID)
+	if err != nil {
+		return 0, fmt.Errorf("attempt to query Action for collecting EstimatedBinlogMB failed, %s", err)
+	}
+	images := 1
+	switch action {
+	case policy.Action.V.Insert, policy.Action.V.Delete, policy.Action.V.Replace:
+	case policy.Action.V.Update:
+		images = 2
+	default:
+		return 0, nil
+	}
+
+	affectRows, err := c.GetItemValueWithInt(policy.AffectRows.ID)
+	if err != nil {
+		return 0, fmt.Errorf("attempt to query AffectRows for collecting EstimatedBinlogMB failed, %s", err)
+	}
+	if affectRows == 0 {
+		return 0, nil
+	}
+
+	// 多表时取最大的平均行长度，工单中新建的表按源表和LIKE的表计算
+	tables := make([]string, 0, len(c.Tables))
+	for _, t := range c.Tables {
+		st, ok := c.createdTable(t)
+		if !ok {
+			tables = append(tables, t)
+			continue
+		}
+		tables = append(tables, st.Sources...)
+		if st.Like != "" {
+			tables = append(tables, st.Like)
+		}
+	}
+	rowLength := 0
+	for _, t := range tables {
+		db, tabName := comm.SplitDataBaseAndTable(t)
+		if db == "" || tabName == "" {
+			continue
+		}
+
+		conn, err := c.connectReplica(db)
+		if err != nil {
+			return 0, fmt.Errorf("new mysql connect failed, %s", err)
+		}
+		length, err := conn.AvgRowLength(db, tabName)
+		if closeErr := conn.Close(); closeErr != nil {
+			return 0, fmt.Errorf("close connect failed, %s", closeErr)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("get avg row length failed, %s", err)
+		}
+		if length > rowLength {
+			rowLength = length
+		}
+	}
+
+	return EstimateBinlogMB(affectRows, rowLength, images), nil
+}
+
+// EstimateBinlogMB 估算binlog大小，不足1MB时向上取整
+func EstimateBinlogMB(rows, rowLength, images int) int {
+	bytes := int64(rows) * int64(rowLength) * int64(images)
+	if bytes <= 0 {
+		return 0
+	}
+	return int((bytes + 1024*1024 - 1) / (1024 * 1024))
+}
+
 // GetItemValue 根据风险ID获取对应的结果
 func (c *SQLRisk) GetItemValue(id string) any {
 	for _, item := range c.ItemValues {
@@ -1099,6 +1894,19 @@ func (c *SQLRisk) GetItemValueWithKeyWordType(id string) (policy.KeyWordType, er
 	return "", fmt.Errorf("%s item value not found", id)
 }
 
+// HasKeyWord 语句的关键字是否为指定关键字之一，包含多个关键字时任意一个满足即可
+func (c *SQLRisk) HasKeyWord(keywords ...policy.KeyWordType) bool {
+	if v, ok := c.GetItemValue(policy.KeyWords.ID).([]policy.KeyWordType); ok {
+		for _, kw := range v {
+			if comm.EleExist(kw, keywords) {
+				return true
+			}
+		}
+	}
+	keyword, err := c.GetItemValueWithKeyWordType(policy.KeyWord.ID)
+	return err == nil && comm.EleExist(keyword, keywords)
+}
+
 func (c *SQLRisk) GetItemValueWithString(id string) (string, error) {
 	for _, item := range c.ItemValues {
 		if item.ID == id {
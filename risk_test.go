@@ -1,9 +1,15 @@
 package sqlrisk
 
 import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
 	"github.com/sunkaimr/sql-risk/comm"
 	"github.com/sunkaimr/sql-risk/policy"
-	"testing"
 )
 
 //func TestSoarVersion(t *testing.T) {
@@ -53,9 +59,11 @@ func TestCollectAction(t *testing.T) {
 		{"test000", SQLRisk{SQLText: "SELECT * FROM student WHERE id=2;"}, policy.Action.V.Select, policy.KeyWord.V.Select},
 		{"test000", SQLRisk{SQLText: "DROP DATABASE IF EXISTS mydatabase;"}, policy.Action.V.Drop, policy.KeyWord.V.DropDB},
 		{"test000", SQLRisk{SQLText: "DROP TABLE IF EXISTS mytable;"}, policy.Action.V.Drop, policy.KeyWord.V.DropTab},
-		//{"test000", SQLRisk{SQLText: "DROP PROCEDURE IF EXISTS myprocedure;"}, Drop, KeyWord.V.DropProcedure},
+		{"test000", SQLRisk{SQLText: "DROP PROCEDURE IF EXISTS myprocedure;"}, policy.Action.V.Drop, policy.KeyWord.V.DropProcedure},
 		{"test000", SQLRisk{SQLText: "DROP VIEW IF EXISTS myview;"}, policy.Action.V.Drop, policy.KeyWord.V.DropView},
-		//{"test000", SQLRisk{SQLText: "DROP TRIGGER IF EXISTS mytrigger;"}, Drop, KeyWord.V.DropTrig},
+		{"test000", SQLRisk{SQLText: "DROP TRIGGER IF EXISTS mytrigger;"}, policy.Action.V.Drop, policy.KeyWord.V.DropTrig},
+		{"test000", SQLRisk{SQLText: "DROP FUNCTION test.myfunc;"}, policy.Action.V.Drop, policy.KeyWord.V.DropFun},
+		{"test000", SQLRisk{SQLText: "DROP EVENT IF EXISTS myevent;"}, policy.Action.V.Drop, policy.KeyWord.V.DropEvent},
 		{"test000", SQLRisk{SQLText: "TRUNCATE TABLE mytable;"}, policy.Action.V.Truncate, policy.KeyWord.V.TruncateTab},
 
 		{"test000", SQLRisk{SQLText: "CREATE TABLE students ( id INT PRIMARY KEY, name VARCHAR(50), age INT, gender VARCHAR(10), grade VARCHAR(10) );"}, policy.Action.V.Create, policy.KeyWord.V.CreateTab},
@@ -63,6 +71,12 @@ func TestCollectAction(t *testing.T) {
 		{"test000", SQLRisk{SQLText: "CREATE TEMPORARY TABLE students ( id INT PRIMARY KEY, name VARCHAR(50), age INT, gender VARCHAR(10), grade VARCHAR(10) );"}, policy.Action.V.Create, policy.KeyWord.V.CreateTmpTab},
 		{"test000", SQLRisk{SQLText: "CREATE INDEX idx_students_name ON students (name);"}, policy.Action.V.Create, policy.KeyWord.V.CreateIdx},
 		{"test000", SQLRisk{SQLText: "CREATE UNIQUE INDEX idx_students_id ON students (id);"}, policy.Action.V.Create, policy.KeyWord.V.CreateUniIdx},
+		{"test000", SQLRisk{SQLText: "CREATE DEFINER=`root`@`%` PROCEDURE p1(IN id INT) BEGIN DELETE FROM student WHERE student.id = id; END"}, policy.Action.V.Create, policy.KeyWord.V.CreateProcedure},
+		{"test000", SQLRisk{SQLText: "CREATE FUNCTION f1(a INT) RETURNS INT DETERMINISTIC RETURN a + 1"}, policy.Action.V.Create, policy.KeyWord.V.CreateFunc},
+		{"test000", SQLRisk{SQLText: "CREATE TRIGGER tr1 BEFORE INSERT ON student FOR EACH ROW SET NEW.age = 18"}, policy.Action.V.Create, policy.KeyWord.V.CreateTrig},
+		{"test000", SQLRisk{SQLText: "CREATE EVENT e1 ON SCHEDULE EVERY 1 DAY DO DELETE FROM log WHERE ts < NOW()"}, policy.Action.V.Create, policy.KeyWord.V.CreateEvent},
+		{"test000", SQLRisk{SQLText: "ALTER PROCEDURE p1 COMMENT 'test'"}, policy.Action.V.Alter, policy.KeyWord.V.AlterProcedure},
+		{"test000", SQLRisk{SQLText: "ALTER DEFINER = CURRENT_USER EVENT e1 DISABLE"}, policy.Action.V.Alter, policy.KeyWord.V.AlterEvent},
 		{"test000", SQLRisk{SQLText: "CREATE VIEW customer_order_total AS SELECT customer_id, SUM(total_amount) AS order_total FROM orders GROUP BY customer_id;"}, policy.Action.V.Create, policy.KeyWord.V.CreateView},
 
 		{"test000", SQLRisk{SQLText: "ALTER TABLE students ADD COLUMN score DECIMAL(5,2);"}, policy.Action.V.Alter, policy.KeyWord.V.AlertAddCol},
@@ -76,6 +90,18 @@ func TestCollectAction(t *testing.T) {
 		{"test000", SQLRisk{SQLText: "ALTER TABLE my_table ADD UNIQUE INDEX idx_name (column_name);"}, policy.Action.V.Alter, policy.KeyWord.V.AlertAddUni},
 		{"test000", SQLRisk{SQLText: "ALTER TABLE my_table ADD INDEX idx_name (column_name);"}, policy.Action.V.Alter, policy.KeyWord.V.AlertAddIdx},
 		{"test000", SQLRisk{SQLText: "ALTER TABLE my_table DROP INDEX idx_nam;"}, policy.Action.V.Alter, policy.KeyWord.V.AlertDropIdx},
+		{"test000", SQLRisk{SQLText: "ALTER TABLE students ADD COLUMN a INT, DROP COLUMN b, DROP PRIMARY KEY;"}, policy.Action.V.Alter, policy.KeyWord.V.AlertDropPriKey},
+		{"test000", SQLRisk{SQLText: "ALTER TABLE students RENAME TO pupils;"}, policy.Action.V.Alter, policy.KeyWord.V.AlertRenameTab},
+		{"test000", SQLRisk{SQLText: "ALTER TABLE students ENGINE=InnoDB;"}, policy.Action.V.Alter, policy.KeyWord.V.AlertEngine},
+		{"test000", SQLRisk{SQLText: "ALTER TABLE students CONVERT TO CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;"}, policy.Action.V.Alter, policy.KeyWord.V.AlertConvert},
+		{"test000", SQLRisk{SQLText: "ALTER TABLE students DEFAULT CHARSET=utf8mb4;"}, policy.Action.V.Alter, policy.KeyWord.V.AlertCharset},
+		{"test000", SQLRisk{SQLText: "ALTER TABLE logs ADD PARTITION (PARTITION p2024 VALUES LESS THAN (2025));"}, policy.Action.V.Alter, policy.KeyWord.V.AlertAddPart},
+		{"test000", SQLRisk{SQLText: "ALTER TABLE logs DROP PARTITION p2020;"}, policy.Action.V.Alter, policy.KeyWord.V.AlertDropPart},
+		{"test000", SQLRisk{SQLText: "ALTER TABLE logs TRUNCATE PARTITION p2020;"}, policy.Action.V.Alter, policy.KeyWord.V.AlertTruncPart},
+		{"test000", SQLRisk{SQLText: "ALTER TABLE logs REORGANIZE PARTITION p1 INTO (PARTITION p2 VALUES LESS THAN (5));"}, policy.Action.V.Alter, policy.KeyWord.V.AlertPartition},
+		{"test000", SQLRisk{SQLText: "ALTER TABLE students ADD INDEX idx_name (name), ALGORITHM=INPLACE, LOCK=NONE;"}, policy.Action.V.Alter, policy.KeyWord.V.AlertAddIdx},
+		{"test000", SQLRisk{SQLText: "ALTER TABLE students DEFAULT CHARSET=utf8mb4, ALGORITHM=COPY;"}, policy.Action.V.Alter, policy.KeyWord.V.AlertAlgCopy},
+		{"test000", SQLRisk{SQLText: "ALTER TABLE students DEFAULT CHARSET=utf8mb4, LOCK=EXCLUSIVE;"}, policy.Action.V.Alter, policy.KeyWord.V.AlertLockExcl},
 
 		{"test000", SQLRisk{SQLText: "INSERT INTO table2 (col1, col2) SELECT col1, col2 FROM table1 WHERE id<100;"}, policy.Action.V.Insert, policy.KeyWord.V.InsertSelect},
 		{"test000", SQLRisk{SQLText: "INSERT INTO my_table (col1, col2) VALUES ('Value1', 'Value2');"}, policy.Action.V.Insert, policy.KeyWord.V.Insert},
@@ -87,6 +113,32 @@ func TestCollectAction(t *testing.T) {
 
 		{"test000", SQLRisk{SQLText: "UPDATE my_table SET col1 = v1;"}, policy.Action.V.Update, policy.KeyWord.V.Update},
 		{"test000", SQLRisk{SQLText: "UPDATE my_table SET col1 = v1, col2 = v2 WHERE id=123;"}, policy.Action.V.Update, policy.KeyWord.V.UpdateWhere},
+
+		{"test000", SQLRisk{SQLText: "GRANT SELECT ON db.* TO 'user'@'10.%';"}, policy.Action.V.Grant, policy.KeyWord.V.Grant},
+		{"test000", SQLRisk{SQLText: "REVOKE SELECT ON db.* FROM 'user'@'10.%';"}, policy.Action.V.Revoke, policy.KeyWord.V.Revoke},
+		{"test000", SQLRisk{SQLText: "CREATE USER 'user'@'10.%' IDENTIFIED BY 'password';"}, policy.Action.V.User, policy.KeyWord.V.CreateUser},
+		{"test000", SQLRisk{SQLText: "DROP USER 'user'@'10.%';"}, policy.Action.V.User, policy.KeyWord.V.DropUser},
+		{"test000", SQLRisk{SQLText: "ALTER USER 'user'@'10.%' IDENTIFIED BY 'password';"}, policy.Action.V.User, policy.KeyWord.V.AlterUser},
+		{"test000", SQLRisk{SQLText: "SET PASSWORD FOR 'user'@'10.%' = 'password';"}, policy.Action.V.User, policy.KeyWord.V.SetPassword},
+		{"test000", SQLRisk{SQLText: "FLUSH PRIVILEGES;"}, policy.Action.V.Flush, policy.KeyWord.V.FlushPrivileges},
+
+		{"test000", SQLRisk{SQLText: "SET NAMES utf8mb4;"}, policy.Action.V.Set, policy.KeyWord.V.SetVariable},
+		{"test000", SQLRisk{SQLText: "SET GLOBAL max_connections = 1000;"}, policy.Action.V.Set, policy.KeyWord.V.SetGlobal},
+		{"test000", SQLRisk{SQLText: "SET foreign_key_checks = 0;"}, policy.Action.V.Set, policy.KeyWord.V.DisableFKCheck},
+		{"test000", SQLRisk{SQLText: "SET @@session.sql_log_bin = OFF, foreign_key_checks = 0;"}, policy.Action.V.Set, policy.KeyWord.V.DisableBinlog},
+		{"test000", SQLRisk{SQLText: "USE mydatabase;"}, policy.Action.V.Use, policy.KeyWord.V.Use},
+		{"test000", SQLRisk{SQLText: "LOCK TABLES my_table WRITE;"}, policy.Action.V.Lock, policy.KeyWord.V.LockTabs},
+		{"test000", SQLRisk{SQLText: "UNLOCK TABLES;"}, policy.Action.V.Lock, policy.KeyWord.V.UnlockTabs},
+		{"test000", SQLRisk{SQLText: "KILL 123;"}, policy.Action.V.Kill, policy.KeyWord.V.Kill},
+		{"test000", SQLRisk{SQLText: "OPTIMIZE TABLE my_table;"}, policy.Action.V.Optimize, policy.KeyWord.V.OptimizeTab},
+		{"test000", SQLRisk{SQLText: "ANALYZE TABLE my_table;"}, policy.Action.V.Analyze, policy.KeyWord.V.AnalyzeTab},
+		{"test000", SQLRisk{SQLText: "LOAD DATA INFILE 'data.txt' INTO TABLE my_table;"}, policy.Action.V.Load, policy.KeyWord.V.LoadData},
+	}
+
+	// ALTER包含多个子句时需要根据策略选出风险最高的关键字
+	store := policy.GetStore(policy.MemoryStoreType, nil)
+	if err := store.Init(); err != nil {
+		t.Fatalf("init policy failed, %s", err)
 	}
 
 	for _, test := range tests {
@@ -104,6 +156,144 @@ func TestCollectAction(t *testing.T) {
 	}
 }
 
+func TestCollectKeyWords(t *testing.T) {
+	tests := []struct {
+		name  string
+		input SQLRisk
+		want  []policy.KeyWordType
+	}{
+		{"test001", SQLRisk{SQLText: "SELECT * FROM student WHERE id=2;"}, nil},
+		{"test002", SQLRisk{SQLText: "ALTER TABLE students ADD COLUMN a INT, ADD COLUMN b INT, DROP PRIMARY KEY;"},
+			[]policy.KeyWordType{policy.KeyWord.V.AlertAddCol, policy.KeyWord.V.AlertDropPriKey}},
+		{"test003", SQLRisk{SQLText: "ALTER TABLE students ENGINE=InnoDB, ALGORITHM=COPY, LOCK=SHARED;"},
+			[]policy.KeyWordType{policy.KeyWord.V.AlertEngine, policy.KeyWord.V.AlertAlgCopy, policy.KeyWord.V.AlertLock}},
+		{"test004", SQLRisk{SQLText: "ALTER TABLE students DROP FOREIGN KEY fk_1, COMMENT='x';"},
+			[]policy.KeyWordType{policy.KeyWord.V.Alter}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.input.CollectKeyWords()
+			if err != nil {
+				t.Fatalf("CollectKeyWords('%v') failed, got error: %s", test.input.SQLText, err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("CollectKeyWords('%v') failed, got %v, want %v", test.input.SQLText, got, test.want)
+			}
+		})
+	}
+}
+
+func TestMatchMultiKeyWordPolicy(t *testing.T) {
+	tests := []struct {
+		name  string
+		sql   string
+		level comm.Level
+	}{
+		{"test001", "ALTER TABLE students ADD COLUMN a INT;", comm.High},
+		{"test002", "ALTER TABLE students ADD COLUMN a INT, DROP COLUMN b, DROP PRIMARY KEY;", comm.Fatal},
+		{"test003", "ALTER TABLE logs ADD INDEX idx_a (a), TRUNCATE PARTITION p2020;", comm.Fatal},
+		{"test004", "ALTER TABLE students DEFAULT CHARSET=utf8mb4, ALGORITHM=INPLACE;", comm.Low},
+	}
+
+	store := policy.GetStore(policy.MemoryStoreType, nil)
+	if err := store.Init(); err != nil {
+		t.Fatalf("init policy failed, %s", err)
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := NewSqlRisk("", "", "", "", "", "", "test", test.sql, &Config{RiskConfig: RiskConfig{Offline: true}})
+			if err := r.IdentifyPreRisk(); err != nil {
+				t.Fatalf("IdentifyPreRisk('%v') failed, got error: %s", test.sql, err)
+			}
+			if r.PreResult.Level != test.level {
+				t.Fatalf("IdentifyPreRisk('%v') failed, got level %s, want %s", test.sql, r.PreResult.Level, test.level)
+			}
+		})
+	}
+}
+
+func TestMatchDCLPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		policies []string
+	}{
+		{"test001", "GRANT ALL PRIVILEGES ON *.* TO 'u1'@'10.%' WITH GRANT OPTION", []string{"DCL.GRANT.001", "DCL.GRANT.002", "DCL.GRANT.003"}},
+		{"test002", "GRANT SELECT ON test.* TO 'u1'@'10.%'", nil},
+		{"test003", "REVOKE ALL PRIVILEGES ON test.* FROM 'u1'@'10.%'", nil},
+		{"test004", "REVOKE SELECT ON *.* FROM 'u1'@'10.%'", nil},
+	}
+
+	store := policy.GetStore(policy.MemoryStoreType, nil)
+	if err := store.Init(); err != nil {
+		t.Fatalf("init policy failed, %s", err)
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := NewSqlRisk("", "", "", "", "", "", "test", test.sql, &Config{RiskConfig: RiskConfig{Offline: true}})
+			if err := r.IdentifyPreRisk(); err != nil {
+				t.Fatalf("IdentifyPreRisk('%v') failed, got error: %s", test.sql, err)
+			}
+			var got []string
+			for _, p := range r.MatchedBasicPolicy {
+				if strings.HasPrefix(p.PolicyID, "DCL.GRANT.") {
+					got = append(got, p.PolicyID)
+				}
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, test.policies) {
+				t.Fatalf("IdentifyPreRisk('%v') failed, got grant policies %v, want %v", test.sql, got, test.policies)
+			}
+		})
+	}
+}
+
+func TestMaxReplicaLag(t *testing.T) {
+	type lag struct {
+		lag int
+		ok  bool
+		err error
+	}
+	tests := []struct {
+		name      string
+		endpoints []Endpoint
+		lags      map[string]lag
+		want      int
+		wantErr   bool
+	}{
+		{"test001", nil, nil, 0, false},
+		{"test002", []Endpoint{{Addr: "10.0.0.2", Port: "3306"}, {Addr: "10.0.0.3", Port: "3306"}},
+			map[string]lag{"10.0.0.2": {lag: 5, ok: true}, "10.0.0.3": {lag: 30, ok: true}}, 30, false},
+		// 部分从库无法连接时按可查询的从库计算
+		{"test003", []Endpoint{{Addr: "10.0.0.2", Port: "3306"}, {Addr: "10.0.0.3", Port: "3306"}},
+			map[string]lag{"10.0.0.2": {err: fmt.Errorf("connection refused")}, "10.0.0.3": {lag: 30, ok: true}}, 30, false},
+		// 从库都无法连接
+		{"test004", []Endpoint{{Addr: "10.0.0.2", Port: "3306"}, {Addr: "10.0.0.2", Port: "3306"}},
+			map[string]lag{"10.0.0.2": {err: fmt.Errorf("connection refused")}}, 0, true},
+		// 从库的复制线程都已停止
+		{"test005", []Endpoint{{Addr: "10.0.0.2", Port: "3306"}},
+			map[string]lag{"10.0.0.2": {}}, 0, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := maxReplicaLag(test.endpoints, func(e Endpoint) (int, bool, error) {
+				l := test.lags[e.Addr]
+				return l.lag, l.ok, l.err
+			})
+			if (err != nil) != test.wantErr || (err != nil && !errors.Is(err, NoReplicaLagError)) {
+				t.Fatalf("maxReplicaLag got error %v, want error %v", err, test.wantErr)
+			}
+			if got != test.want {
+				t.Fatalf("maxReplicaLag got %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
 func TestCollectAffectRows(t *testing.T) {
 	var err error
 	tests := []struct {
@@ -156,3 +346,27 @@ func TestCollectAffectRows(t *testing.T) {
 		})
 	}
 }
+
+func TestEstimateBinlogMB(t *testing.T) {
+	tests := []struct {
+		name      string
+		rows      int
+		rowLength int
+		images    int
+		want      int
+	}{
+		{"test001", 0, 100, 1, 0},
+		{"test002", 10, 100, 1, 1},
+		{"test003", 1024 * 1024, 100, 1, 100},
+		{"test004", 1024 * 1024, 100, 2, 200},
+		{"test005", 50000000, 300, 1, 14306},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := EstimateBinlogMB(test.rows, test.rowLength, test.images)
+			if got != test.want {
+				t.Fatalf("EstimateBinlogMB(%d, %d, %d) got %d, want %d", test.rows, test.rowLength, test.images, got, test.want)
+			}
+		})
+	}
+}
@@ -1,93 +1,177 @@
 package sqlrisk
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/sunkaimr/sql-risk/comm"
+	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
+// 数据源的监控类别，对应不同的PromQL模板
 const (
-	// CPU 5min内的使用率(腾讯云Mysql)
-	tencentCpuUsagePromQL = "avg_over_time(qce_cdb_cpuuserate_max{vip='%s'}[5m])"
-	/*
-		https://cloud.tencent.com/document/product/248/50350#.E6.8C.87.E6.A0.87.E8.AF.B4.E6.98.8E
-		qce_cdb_volumerate_max      磁盘利用率：磁盘使用空间/实例购买空间
-		qce_cdb_capacity_max        磁盘占用空间：包括 MySQL 数据目录和  binlog、relaylog、undolog、errorlog、slowlog 日志空间
-		qce_cdb_realcapacity_max    磁盘使用空间：仅包括 MySQL 数据目录，不含 binlog、relaylog、undolog、errorlog、slowlog 日志空间
-	*/
-	// 磁盘的使用率(腾讯云Mysql)
-	tencentDiskUsagePromQL = "qce_cdb_volumerate_max{vip='%s'}"
-	// 磁盘的总大小(腾讯云Mysql)
-	tencentDiskTotalPromQL = "(qce_cdb_realcapacity_max{vip='%s'}*100)/qce_cdb_volumerate_max{vip='%s'}"
-	// 磁盘的使用空间(数据)(腾讯云Mysql)
-	tencentDiskUsedPromQL = "qce_cdb_realcapacity_max{vip='%s'}"
-	// 磁盘的剩余空间(腾讯云Mysql)
-	tencentDiskFreePromQL = "(qce_cdb_realcapacity_max{vip='%s'}*100)/qce_cdb_volumerate_max{vip='%s'}-qce_cdb_realcapacity_max{vip='%s'}"
-
-	// 自建Mysql集群
-	// CPU 5min内的使用率
-	cpuUsagePromQL = "100-(avg(rate(node_cpu_seconds_total{instance_ip='%s',mode='idle'}[5m])))*100"
-
-	// 磁盘的使用率（/data目录）
-	diskUsagePromQL0 = "100-(node_filesystem_free_bytes{instance_ip='%s',mountpoint='/data',fstype=~'ext4|xfs'}/node_filesystem_size_bytes{instance_ip='%s',mountpoint='/data',fstype=~'ext4|xfs'})*100"
-	// 磁盘的总大小（/data目录）
-	diskTotalPromQL0 = "node_filesystem_size_bytes{instance_ip='%s',mountpoint='/data',fstype=~'ext4|xfs'}/1024/1024"
-	// 磁盘的使用空间（/data目录）
-	diskUsedPromQL0 = "(node_filesystem_size_bytes{instance_ip='%s',mountpoint='/data',fstype=~'ext4|xfs'}-node_filesystem_free_bytes{instance_ip='%s',mountpoint='/data',fstype=~'ext4|xfs'})/1024/1024"
-	// 磁盘的剩余空间（/data目录）
-	diskFreePromQL0 = "node_filesystem_free_bytes{instance_ip='%s',mountpoint='/data',fstype=~'ext4|xfs'}/1024/1024"
-
-	// linux内核版本差异导致获取磁盘相关指标有差异
-	// 磁盘的使用率（/data目录）
-	//diskUsagePromQL1 = "100-node_filesystem_avail_bytes{instance_ip='%s',mountpoint='/data',fstype=~'ext4|xfs'} / node_filesystem_size_bytes{instance_ip='%s',mountpoint='/data',fstype=~'ext4|xfs'}*100"
-	//// 磁盘的总大小（/data目录）
-	//diskTotalPromQL1 = "node_filesystem_size_bytes{instance_ip='%s',mountpoint='/data',fstype=~'ext4|xfs'}/1024/1024"
-	//// 磁盘的使用空间（/data目录）
-	//diskUsedPromQL1 = "(node_filesystem_size_bytes{instance_ip='%s',mountpoint='/data',fstype=~'ext4|xfs'}-node_filesystem_avail_bytes{instance_ip='%s',mountpoint='/data',fstype=~'ext4|xfs'})/1024/1024"
-	//// 磁盘的剩余空间（/data目录）
-	//diskFreePromQL1 = "node_filesystem_avail_bytes{instance_ip='%s',mountpoint='/data',fstype=~'ext4|xfs'}/1024/1024"
-	// END
-
-	// 磁盘的使用率（/目录）
-	diskUsagePromQL2 = "100-(node_filesystem_free_bytes{instance_ip='%s',mountpoint='/',fstype=~'ext4|xfs'}/node_filesystem_size_bytes{instance_ip='%s',mountpoint='/',fstype=~'ext4|xfs'})*100"
-	// 磁盘的总大小 （/目录）
-	diskTotalPromQL2 = "node_filesystem_size_bytes{instance_ip='%s',mountpoint='/',fstype=~'ext4|xfs'}/1024/1024"
-	// 磁盘的使用空间（/目录）
-	diskUsedPromQL2 = "(node_filesystem_size_bytes{instance_ip='%s',mountpoint='/',fstype=~'ext4|xfs'}-node_filesystem_free_bytes{instance_ip='%s',mountpoint='/',fstype=~'ext4|xfs'})/1024/1024"
-	// 磁盘的剩余空间（/目录）
-	diskFreePromQL2 = "node_filesystem_free_bytes{instance_ip='%s',mountpoint='/',fstype=~'ext4|xfs'}/1024/1024"
-
-	// linux内核版本差异导致获取磁盘相关指标有差异
-	// 磁盘的使用率（/目录）
-	//diskUsagePromQL3 = "100-node_filesystem_avail_bytes{instance_ip='%s',mountpoint='/',fstype=~'ext4|xfs'} / node_filesystem_size_bytes{instance_ip='%s',mountpoint='/',fstype=~'ext4|xfs'}*100"
-	//// 磁盘的总大小（/data）
-	//diskTotalPromQL3 = "node_filesystem_size_bytes{instance_ip='%s',mountpoint='/',fstype=~'ext4|xfs'}/1024/1024"
-	//// 磁盘的使用空间（/data目录）
-	//diskUsedPromQL3 = "(node_filesystem_size_bytes{instance_ip='%s',mountpoint='/',fstype=~'ext4|xfs'}-node_filesystem_avail_bytes{instance_ip='%s',mountpoint='/',fstype=~'ext4|xfs'})/1024/1024"
-	//// 磁盘的剩余空间（/data目录）
-	//diskFreePromQL3 = "node_filesystem_avail_bytes{instance_ip='%s',mountpoint='/',fstype=~'ext4|xfs'}/1024/1024"
-	// END
-
-	// windows系统的监控采集方式
-	// 磁盘的使用率（C:盘）
-	diskUsagePromQL4 = "100-(windows_logical_disk_free_bytes{instance='%s:9182',volume='C:'}/windows_logical_disk_size_bytes{instance='%s:9182',volume='C:'})*100"
-	// 磁盘的总大小 （C:盘）
-	diskTotalPromQL4 = "windows_logical_disk_size_bytes{instance='%s:9182',volume='C:'}/1024/1024"
-	// 磁盘的使用空间（C:盘）
-	diskUsedPromQL4 = "(windows_logical_disk_size_bytes{instance='%s:9182',volume='C:'}-windows_logical_disk_free_bytes{instance='%s:9182',volume='C:'})/1024/1024"
-	// 磁盘的剩余空间（C:盘）
-	diskFreePromQL4 = "(windows_logical_disk_free_bytes{instance='%s:9182',volume='C:'})/1024/1024"
+	// MetricsClassTencentCDB 腾讯云Mysql
+	MetricsClassTencentCDB = "tencent-cdb"
+	// MetricsClassNode 自建Mysql集群，node_exporter采集
+	MetricsClassNode = "node"
+	// MetricsClassWindows windows_exporter采集
+	MetricsClassWindows = "windows"
 )
 
+// MetricsTemplate 一类数据源的PromQL模板，$ip、$port替换为数据源的地址和端口，同一指标配置多个时依次查询直到有数据
+type MetricsTemplate struct {
+	Class string `json:"class"`
+	// CPU 5min内的使用率
+	CpuUsage []string `json:"cpu_usage"`
+	// 磁盘的使用率
+	DiskUsage []string `json:"disk_usage"`
+	// 磁盘的总大小(MB)
+	DiskTotal []string `json:"disk_total"`
+	// 磁盘的使用空间(MB)
+	DiskUsed []string `json:"disk_used"`
+	// 磁盘的剩余空间(MB)
+	DiskFree []string `json:"disk_free"`
+	// 每秒的查询数
+	QPS []string `json:"qps"`
+	// 磁盘IO，只用于比较不同时段的负载高低，单位不限
+	DiskIO []string `json:"disk_io"`
+}
+
+// DefaultMetricsTemplates 未配置模板时使用，数据源未指定监控类别时按顺序依次尝试
+var DefaultMetricsTemplates = []MetricsTemplate{
+	{
+		/*
+			https://cloud.tencent.com/document/product/248/50350#.E6.8C.87.E6.A0.87.E8.AF.B4.E6.98.8E
+			qce_cdb_volumerate_max      磁盘利用率：磁盘使用空间/实例购买空间
+			qce_cdb_capacity_max        磁盘占用空间：包括 MySQL 数据目录和  binlog、relaylog、undolog、errorlog、slowlog 日志空间
+			qce_cdb_realcapacity_max    磁盘使用空间：仅包括 MySQL 数据目录，不含 binlog、relaylog、undolog、errorlog、slowlog 日志空间
+		*/
+		Class:     MetricsClassTencentCDB,
+		CpuUsage:  []string{"avg_over_time(qce_cdb_cpuuserate_max{vip='$ip'}[5m])"},
+		DiskUsage: []string{"qce_cdb_volumerate_max{vip='$ip'}"},
+		DiskTotal: []string{"(qce_cdb_realcapacity_max{vip='$ip'}*100)/qce_cdb_volumerate_max{vip='$ip'}"},
+		DiskUsed:  []string{"qce_cdb_realcapacity_max{vip='$ip'}"},
+		DiskFree:  []string{"(qce_cdb_realcapacity_max{vip='$ip'}*100)/qce_cdb_volumerate_max{vip='$ip'}-qce_cdb_realcapacity_max{vip='$ip'}"},
+		QPS:       []string{"qce_cdb_qps_max{vip='$ip'}"},
+		DiskIO:    []string{"qce_cdb_iops_max{vip='$ip'}"},
+	},
+	{
+		// 优先查询/data目录，没有单独挂载时查询/目录
+		Class:    MetricsClassNode,
+		CpuUsage: []string{"100-(avg(rate(node_cpu_seconds_total{instance_ip='$ip',mode='idle'}[5m])))*100"},
+		DiskUsage: []string{
+			"100-(node_filesystem_free_bytes{instance_ip='$ip',mountpoint='/data',fstype=~'ext4|xfs'}/node_filesystem_size_bytes{instance_ip='$ip',mountpoint='/data',fstype=~'ext4|xfs'})*100",
+			"100-(node_filesystem_free_bytes{instance_ip='$ip',mountpoint='/',fstype=~'ext4|xfs'}/node_filesystem_size_bytes{instance_ip='$ip',mountpoint='/',fstype=~'ext4|xfs'})*100",
+		},
+		DiskTotal: []string{
+			"node_filesystem_size_bytes{instance_ip='$ip',mountpoint='/data',fstype=~'ext4|xfs'}/1024/1024",
+			"node_filesystem_size_bytes{instance_ip='$ip',mountpoint='/',fstype=~'ext4|xfs'}/1024/1024",
+		},
+		DiskUsed: []string{
+			"(node_filesystem_size_bytes{instance_ip='$ip',mountpoint='/data',fstype=~'ext4|xfs'}-node_filesystem_free_bytes{instance_ip='$ip',mountpoint='/data',fstype=~'ext4|xfs'})/1024/1024",
+			"(node_filesystem_size_bytes{instance_ip='$ip',mountpoint='/',fstype=~'ext4|xfs'}-node_filesystem_free_bytes{instance_ip='$ip',mountpoint='/',fstype=~'ext4|xfs'})/1024/1024",
+		},
+		DiskFree: []string{
+			"node_filesystem_free_bytes{instance_ip='$ip',mountpoint='/data',fstype=~'ext4|xfs'}/1024/1024",
+			"node_filesystem_free_bytes{instance_ip='$ip',mountpoint='/',fstype=~'ext4|xfs'}/1024/1024",
+		},
+		QPS:    []string{"sum(rate(mysql_global_status_queries{instance_ip='$ip'}[5m]))"},
+		DiskIO: []string{"sum(rate(node_disk_io_time_seconds_total{instance_ip='$ip'}[5m]))*100"},
+	},
+	{
+		// C:盘
+		Class:     MetricsClassWindows,
+		DiskUsage: []string{"100-(windows_logical_disk_free_bytes{instance='$ip:9182',volume='C:'}/windows_logical_disk_size_bytes{instance='$ip:9182',volume='C:'})*100"},
+		DiskTotal: []string{"windows_logical_disk_size_bytes{instance='$ip:9182',volume='C:'}/1024/1024"},
+		DiskUsed:  []string{"(windows_logical_disk_size_bytes{instance='$ip:9182',volume='C:'}-windows_logical_disk_free_bytes{instance='$ip:9182',volume='C:'})/1024/1024"},
+		DiskFree:  []string{"(windows_logical_disk_free_bytes{instance='$ip:9182',volume='C:'})/1024/1024"},
+	},
+}
+
 var NoDataPointError = errors.New("no data points found")
 
+// NoMetricsProviderError 未配置任何监控，依赖监控的评估项不采集
+var NoMetricsProviderError = errors.New("no metrics provider configured")
+
+// NoRangeMetricsProviderError 没有支持区间查询的监控，依赖历史数据的评估项不采集
+var NoRangeMetricsProviderError = errors.New("no metrics provider supports range query")
+
+// NoReplicaLagError 存在从库但都无法查询到复制延迟，作为评估项的错误记录，不中断识别
+var NoReplicaLagError = errors.New("no replica lag could be read")
+
+// DefaultQueryTimeout 单次查询的默认超时时间
+const DefaultQueryTimeout = 10 * time.Second
+
+// APIError Prometheus返回的错误，Type为 bad_data、execution、timeout、canceled、unavailable 等
+type APIError struct {
+	Type    string
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("prometheus %s error: %s", e.Type, e.Message)
+}
+
+// HTTPError 响应不是Prometheus的格式，如网关、认证失败
+type HTTPError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("unexpected http status %d: %s", e.StatusCode, e.Body)
+}
+
+// 所有Client共用连接池，TLS配置不同时才需要单独的Transport
+var (
+	sharedTransport   = newTransport(nil)
+	insecureTransport = newTransport(&tls.Config{InsecureSkipVerify: true})
+	// *tls.Config -> *http.Transport
+	tlsTransports sync.Map
+)
+
+func tlsTransport(tlsConfig *tls.Config) http.RoundTripper {
+	if t, ok := tlsTransports.Load(tlsConfig); ok {
+		return t.(http.RoundTripper)
+	}
+	t, _ := tlsTransports.LoadOrStore(tlsConfig, newTransport(tlsConfig))
+	return t.(http.RoundTripper)
+}
+
+func newTransport(tlsConfig *tls.Config) *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   5 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSClientConfig:     tlsConfig,
+		TLSHandshakeTimeout: 5 * time.Second,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+	}
+}
+
 type Client struct {
 	// 	http://thanos-realtime.xxx.com
 	Url string `json:"url"`
+	// 单次查询的超时时间，为0时使用DefaultQueryTimeout
+	Timeout time.Duration `json:"timeout"`
+	// 认证，BearerToken优先
+	BearerToken string `json:"-"`
+	Username    string `json:"-"`
+	Password    string `json:"-"`
+	// 为空时使用共用的Transport
+	Transport http.RoundTripper `json:"-"`
 }
 
 type MatrixResult struct {
@@ -122,10 +206,21 @@ type VectorData struct {
 
 func NewClient(url string) *Client {
 	return &Client{
-		Url: url,
+		Url:       url,
+		Timeout:   DefaultQueryTimeout,
+		Transport: sharedTransport,
 	}
 }
 
+// NewClientWithTLS 相同的tlsConfig共用一个Transport，tlsConfig为nil时使用系统证书校验
+func NewClientWithTLS(url string, tlsConfig *tls.Config) *Client {
+	c := NewClient(url)
+	if tlsConfig != nil {
+		c.Transport = tlsTransport(tlsConfig)
+	}
+	return c
+}
+
 // Func 定义需要重试的函数类型
 type Func func(string, time.Time) (float64, error)
 
@@ -141,125 +236,178 @@ func Retry(fn Func, t time.Time, url ...string) (ret float64, err error) {
 }
 
 func (c *Client) GeneralQuery(t time.Time, pql ...string) (float64, error) {
+	return c.GeneralQueryContext(context.Background(), t, pql...)
+}
+
+// GeneralQueryContext 依次查询pql直到有数据，返回的错误可以通过errors.As取出APIError、HTTPError
+func (c *Client) GeneralQueryContext(ctx context.Context, t time.Time, pql ...string) (float64, error) {
 	return Retry(func(pql string, t time.Time) (float64, error) {
-		vds, err := c.Query(pql, t)
+		vds, err := c.QueryContext(ctx, pql, t)
 		if err != nil {
-			return 0, fmt.Errorf("query(%s) failed %s", pql, err)
+			return 0, fmt.Errorf("query(%s) failed %w", pql, err)
 		}
 		ret, err := parseData(vds)
 		if err != nil {
-			return 0, fmt.Errorf("parse query(%s) result, %s", pql, err)
+			return 0, fmt.Errorf("parse query(%s) result, %w", pql, err)
 		}
 		return ret, nil
 	}, t, pql...)
 }
 
-// CpuUsage 查询cpu的使用率
+// CpuUsage 按默认模板查询cpu的使用率
 func (c *Client) CpuUsage(ip string, t time.Time) (float64, error) {
-	return c.GeneralQuery(t,
-		fmt.Sprintf(tencentCpuUsagePromQL, ip),
-		fmt.Sprintf(cpuUsagePromQL, ip),
-	)
+	return c.GeneralQuery(t, expandTemplates(DefaultMetricsTemplates, "", MetricsTarget{Addr: ip},
+		func(m MetricsTemplate) []string { return m.CpuUsage })...)
 }
 
-// DiskUsage 查询磁盘的使用率
+// DiskUsage 按默认模板查询磁盘的使用率
 func (c *Client) DiskUsage(ip string, t time.Time) (float64, error) {
-	return c.GeneralQuery(t,
-		fmt.Sprintf(tencentDiskUsagePromQL, ip),
-		fmt.Sprintf(diskUsagePromQL0, ip, ip),
-		//fmt.Sprintf(diskUsagePromQL1, ip, ip),
-		fmt.Sprintf(diskUsagePromQL2, ip, ip),
-		//fmt.Sprintf(diskUsagePromQL3, ip, ip),
-		fmt.Sprintf(diskUsagePromQL4, ip, ip),
-	)
+	return c.GeneralQuery(t, expandTemplates(DefaultMetricsTemplates, "", MetricsTarget{Addr: ip},
+		func(m MetricsTemplate) []string { return m.DiskUsage })...)
 }
 
-// DiskTotal 磁盘的总大小（MB）
+// DiskTotal 按默认模板查询磁盘的总大小（MB）
 func (c *Client) DiskTotal(ip string, t time.Time) (float64, error) {
-	return c.GeneralQuery(t,
-		fmt.Sprintf(tencentDiskTotalPromQL, ip, ip),
-		fmt.Sprintf(diskTotalPromQL0, ip),
-		//fmt.Sprintf(diskTotalPromQL1, ip),
-		fmt.Sprintf(diskTotalPromQL2, ip),
-		//fmt.Sprintf(diskTotalPromQL3, ip),
-		fmt.Sprintf(diskTotalPromQL4, ip),
-	)
+	return c.GeneralQuery(t, expandTemplates(DefaultMetricsTemplates, "", MetricsTarget{Addr: ip},
+		func(m MetricsTemplate) []string { return m.DiskTotal })...)
 }
 
-// DiskUsed 磁盘的使用大小(MB)
+// DiskUsed 按默认模板查询磁盘的使用大小(MB)
 func (c *Client) DiskUsed(ip string, t time.Time) (float64, error) {
-	return c.GeneralQuery(t,
-		fmt.Sprintf(tencentDiskUsedPromQL, ip),
-		fmt.Sprintf(diskUsedPromQL0, ip, ip),
-		//fmt.Sprintf(diskUsedPromQL1, ip, ip),
-		fmt.Sprintf(diskUsedPromQL2, ip, ip),
-		//fmt.Sprintf(diskUsedPromQL3, ip, ip),
-		fmt.Sprintf(diskUsedPromQL4, ip, ip),
-	)
+	return c.GeneralQuery(t, expandTemplates(DefaultMetricsTemplates, "", MetricsTarget{Addr: ip},
+		func(m MetricsTemplate) []string { return m.DiskUsed })...)
 }
 
-// DiskFree 磁盘剩余空间大小(MB)
+// DiskFree 按默认模板查询磁盘剩余空间大小(MB)
 func (c *Client) DiskFree(ip string, t time.Time) (float64, error) {
-	return c.GeneralQuery(t,
-		fmt.Sprintf(tencentDiskFreePromQL, ip, ip, ip),
-		fmt.Sprintf(diskFreePromQL0, ip),
-		//fmt.Sprintf(diskFreePromQL1, ip),
-		fmt.Sprintf(diskFreePromQL2, ip),
-		//fmt.Sprintf(diskFreePromQL3, ip),
-		fmt.Sprintf(diskFreePromQL4, ip),
-	)
+	return c.GeneralQuery(t, expandTemplates(DefaultMetricsTemplates, "", MetricsTarget{Addr: ip},
+		func(m MetricsTemplate) []string { return m.DiskFree })...)
 }
 
 // QueryRange 查询区间向量
 func (c *Client) QueryRange(promQL string, start, end time.Time, step string) (*MatrixData, error) {
-	query := make(map[string]string, 0)
-	query["query"] = promQL
-	query["start"] = fmt.Sprintf("%d", start.Unix())
-	query["end"] = fmt.Sprintf("%d", end.Unix())
-	query["step"] = step
-	body, err := comm.HttpDo(http.MethodGet, c.Url+"/api/v1/query_range", nil, query, "")
-	if err != nil {
-		return nil, err
-	}
+	return c.QueryRangeContext(context.Background(), promQL, start, end, step)
+}
+
+// QueryRangeContext 查询区间向量，step为Prometheus的时长格式如 5m 或秒数
+func (c *Client) QueryRangeContext(ctx context.Context, promQL string, start, end time.Time, step string) (*MatrixData, error) {
+	query := url.Values{}
+	query.Set("query", promQL)
+	query.Set("start", strconv.FormatInt(start.Unix(), 10))
+	query.Set("end", strconv.FormatInt(end.Unix(), 10))
+	query.Set("step", step)
 
 	result := MatrixResult{}
-	err = json.Unmarshal(body, &result)
+	err := c.do(ctx, "/api/v1/query_range", query, &result)
 	if err != nil {
-		return nil, fmt.Errorf("unmarshal [%s] to MatrixResult failed, err: %s", string(body), err)
-	}
-
-	if result.Status != "success" {
-		return nil, fmt.Errorf(result.ErrorType, result.Error)
+		return nil, err
 	}
-
-	//fmt.Printf("%s", string(body))
-	//fmt.Printf("%+v\n", result.Data.Result[0].Values)
 	return &result.Data, nil
 }
 
 // Query 查询
 func (c *Client) Query(promQL string, time time.Time) (*VectorData, error) {
-	query := make(map[string]string, 0)
-	query["query"] = promQL
-	query["time"] = fmt.Sprintf("%d", time.Unix())
-	body, err := comm.HttpDo(http.MethodGet, c.Url+"/api/v1/query", nil, query, "")
+	return c.QueryContext(context.Background(), promQL, time)
+}
+
+// QueryContext 查询瞬时向量
+func (c *Client) QueryContext(ctx context.Context, promQL string, time time.Time) (*VectorData, error) {
+	query := url.Values{}
+	query.Set("query", promQL)
+	query.Set("time", strconv.FormatInt(time.Unix(), 10))
+
+	result := VectorResult{}
+	err := c.do(ctx, "/api/v1/query", query, &result)
 	if err != nil {
 		return nil, err
 	}
+	return &result.Data, nil
+}
 
-	result := VectorResult{}
-	err = json.Unmarshal(body, &result)
+// do 以GET请求Prometheus的HTTP API，Prometheus出错时返回APIError，响应不是Prometheus的格式时返回HTTPError
+func (c *Client) do(ctx context.Context, path string, query url.Values, result any) error {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultQueryTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(c.Url, "/")+path+"?"+query.Encode(), nil)
 	if err != nil {
-		return nil, fmt.Errorf("unmarshal [%s] to MatrixResult failed, err: %s", string(body), err)
+		return fmt.Errorf("new http request failed, %w", err)
+	}
+	switch {
+	case c.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+c.BearerToken)
+	case c.Username != "":
+		req.SetBasicAuth(c.Username, c.Password)
 	}
 
-	if result.Status != "success" {
-		return nil, fmt.Errorf(result.ErrorType, result.Error)
+	transport := c.Transport
+	if transport == nil {
+		transport = sharedTransport
+	}
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		return fmt.Errorf("do http request failed, %w", err)
 	}
+	defer resp.Body.Close()
 
-	//fmt.Printf("%s", string(body))
-	//fmt.Printf("%+v\n", result.Data.Result[0].Values)
-	return &result.Data, nil
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read http response failed, %w", err)
+	}
+
+	status := struct {
+		Status    string `json:"status"`
+		ErrorType string `json:"errorType"`
+		Error     string `json:"error"`
+	}{}
+	err = json.Unmarshal(body, &status)
+	switch {
+	case err == nil && status.Status == "error":
+		return &APIError{Type: status.ErrorType, Message: status.Error}
+	case resp.StatusCode/100 != 2 || err == nil && status.Status != "success":
+		return &HTTPError{StatusCode: resp.StatusCode, Body: string(body)}
+	case err != nil:
+		return fmt.Errorf("unmarshal [%s] failed, err: %s", string(body), err)
+	}
+
+	err = json.Unmarshal(body, result)
+	if err != nil {
+		return fmt.Errorf("unmarshal [%s] to %T failed, err: %s", string(body), result, err)
+	}
+	return nil
+}
+
+// parseMatrix 解析区间查询的第一个序列
+func parseMatrix(mds *MatrixData) ([]MetricPoint, error) {
+	if len(mds.Result) == 0 || len(mds.Result[0].Values) == 0 {
+		return nil, NoDataPointError
+	}
+
+	points := make([]MetricPoint, 0, len(mds.Result[0].Values))
+	for _, v := range mds.Result[0].Values {
+		pair, ok := v.([]interface{})
+		if !ok || len(pair) < 2 {
+			return nil, fmt.Errorf("metric.values should be [timestamp, value], value:%v", v)
+		}
+		ts, ok := pair[0].(float64)
+		if !ok {
+			return nil, fmt.Errorf("convert interface to float64 failed, value:%v", pair[0])
+		}
+		s, ok := pair[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("convert interface to string failed, value:%v", pair[1])
+		}
+		value, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("ParseFloat parse metric.value to float64 failed, err:%s", err)
+		}
+		points = append(points, MetricPoint{Time: time.Unix(0, int64(ts*float64(time.Second))), Value: value})
+	}
+	return points, nil
 }
 
 func parseData(vds *VectorData) (float64, error) {
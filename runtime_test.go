@@ -1,6 +1,8 @@
 package sqlrisk
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -193,3 +195,106 @@ func TestDiskFree(t *testing.T) {
 		t.Fatalf("DiskFree failed, got:%v, want:%v", ret, want)
 	}
 }
+
+func TestQueryEncodingAndAuth(t *testing.T) {
+	promQL := "node_filesystem_free_bytes{instance_ip='10.2.16.15',mountpoint='/data',fstype=~'ext4|xfs'}/1024/1024"
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("query"); got != promQL {
+			t.Errorf("query got %s, want %s", got, promQL)
+		}
+		if !strings.Contains(r.URL.RawQuery, "%7B") {
+			t.Errorf("query not encoded: %s", r.URL.RawQuery)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer token" {
+			t.Errorf("authorization got %s, want Bearer token", got)
+		}
+		w.Write([]byte(`{"status": "success", "data": {"resultType": "vector", "result": [{"metric": {}, "value": [1688372379, "1"]}]}}`))
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	c := NewClient(server.URL)
+	c.BearerToken = "token"
+	if _, err := c.Query(promQL, time.Now()); err != nil {
+		t.Fatalf("Query failed, got error: %s", err)
+	}
+
+	handler = func(w http.ResponseWriter, r *http.Request) {
+		user, passwd, ok := r.BasicAuth()
+		if !ok || user != "admin" || passwd != "123456" {
+			t.Errorf("basic auth got %s:%s", user, passwd)
+		}
+		w.Write([]byte(`{"status": "success", "data": {"resultType": "matrix", "result": []}}`))
+	}
+	server2 := httptest.NewServer(handler)
+	defer server2.Close()
+
+	c = NewClient(server2.URL)
+	c.Username, c.Password = "admin", "123456"
+	if _, err := c.QueryRange(promQL, time.Now().Add(-time.Hour), time.Now(), "5m"); err != nil {
+		t.Fatalf("QueryRange failed, got error: %s", err)
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		check  func(error) bool
+	}{
+		{"test001", http.StatusBadRequest, `{"status": "error", "errorType": "bad_data", "error": "parse error"}`, func(err error) bool {
+			apiErr := &APIError{}
+			return errors.As(err, &apiErr) && apiErr.Type == "bad_data" && apiErr.Message == "parse error"
+		}},
+		{"test002", http.StatusBadGateway, `<html>bad gateway</html>`, func(err error) bool {
+			httpErr := &HTTPError{}
+			return errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusBadGateway
+		}},
+		{"test003", http.StatusUnauthorized, `{"message": "unauthorized"}`, func(err error) bool {
+			httpErr := &HTTPError{}
+			return errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusUnauthorized
+		}},
+		{"test004", http.StatusOK, `{"status": "success", "data": {"resultType": "vector", "result": []}}`, func(err error) bool {
+			return errors.Is(err, NoDataPointError)
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(test.status)
+				w.Write([]byte(test.body))
+			}))
+			defer server.Close()
+
+			_, err := NewClient(server.URL).GeneralQuery(time.Now(), "up")
+			if err == nil || !test.check(err) {
+				t.Fatalf("GeneralQuery got error: %v", err)
+			}
+		})
+	}
+}
+
+func TestQueryTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	c := NewClient(server.URL)
+	c.Timeout = 50 * time.Millisecond
+	if _, err := c.Query("up", time.Now()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Query got error: %v, want deadline exceeded", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewClient(server.URL).QueryContext(ctx, "up", time.Now()); !errors.Is(err, context.Canceled) {
+		t.Fatalf("QueryContext got error: %v, want canceled", err)
+	}
+}
@@ -0,0 +1,156 @@
+package sqlrisk
+
+import (
+	"hash/crc32"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sunkaimr/sql-risk/comm"
+	"github.com/sunkaimr/sql-risk/policy"
+)
+
+// SamplingStrategy 抽样检测策略：同一工单中关键字匹配且SQL指纹相同的语句只检测一部分，
+// 其余语句标记为由已检测的语句代表，并继承其识别结果
+type SamplingStrategy struct {
+	KeyWords []policy.KeyWordType `json:"keywords"`
+	// 每个指纹检测前First条，至少检测1条
+	First int `json:"first"`
+	// 在剩余的语句中再随机检测Random条
+	Random int `json:"random"`
+}
+
+// DefaultSamplingStrategies 未配置抽样策略时只对insert按SQL指纹去重
+var DefaultSamplingStrategies = []SamplingStrategy{
+	{KeyWords: []policy.KeyWordType{policy.KeyWord.V.Insert}, First: 1},
+}
+
+// sampleGroup 同一策略下SQL指纹相同的一组语句
+type sampleGroup struct {
+	strategy SamplingStrategy
+	// 语句在工单中的下标
+	index   []int
+	sampled []int
+}
+
+func (s SamplingStrategy) match(r *SQLRisk) bool {
+	keyword, err := r.GetItemValueWithKeyWordType(policy.KeyWord.ID)
+	return err == nil && comm.EleExist(keyword, s.KeyWords)
+}
+
+// sample 返回需要检测的语句下标：前First条，再从剩余语句中随机选择Random条
+func (s SamplingStrategy) sample(index []int, rnd *rand.Rand) []int {
+	first := s.First
+	if first < 1 {
+		first = 1
+	}
+	if len(index) <= first {
+		return index
+	}
+
+	sampled := append([]int{}, index[:first]...)
+	rest := index[first:]
+	for i, j := range rnd.Perm(len(rest)) {
+		if i >= s.Random {
+			break
+		}
+		sampled = append(sampled, rest[j])
+	}
+	sort.Ints(sampled)
+	return sampled
+}
+
+func (c *WorkRisk) samplingStrategies() []SamplingStrategy {
+	if c.Config == nil || c.Config.RiskConfig.Sampling == nil {
+		return DefaultSamplingStrategies
+	}
+	return c.Config.RiskConfig.Sampling
+}
+
+// SampleStatements 按抽样策略选择需要检测的语句，未检测的语句暂时由同组的第一条语句代表，
+// 识别完成后通过InheritSampledVerdicts选择最终的代表并继承其识别结果
+func (c *WorkRisk) SampleStatements() *WorkRisk {
+	return c.sampleStatements(c.samplingStrategies())
+}
+
+// SampDetectForInsert 采样检测insert语句
+//
+// Deprecated: 使用SampleStatements，重复的insert语句不再从工单中删除，而是由已检测的语句代表
+func (c *WorkRisk) SampDetectForInsert() *WorkRisk {
+	return c.sampleStatements(DefaultSamplingStrategies)
+}
+
+func (c *WorkRisk) sampleStatements(strategies []SamplingStrategy) *WorkRisk {
+	groups := make(map[string]*sampleGroup, len(c.SQLRisks))
+	order := make([]string, 0, len(c.SQLRisks))
+	for i, r := range c.SQLRisks {
+		for j, s := range strategies {
+			if !s.match(r) {
+				continue
+			}
+			// USE切换库后相同指纹的语句可能操作不同的表
+			key := strings.Join([]string{strconv.Itoa(j), r.DataBase, r.FingerID}, "|")
+			g, ok := groups[key]
+			if !ok {
+				g = &sampleGroup{strategy: s}
+				groups[key] = g
+				order = append(order, key)
+			}
+			g.index = append(g.index, i)
+			break
+		}
+	}
+
+	c.samples = make([]*sampleGroup, 0, len(order))
+	for _, key := range order {
+		g := groups[key]
+		// 以工单和指纹作为随机种子，同一工单重复识别时抽样结果不变
+		rnd := rand.New(rand.NewSource(int64(crc32.ChecksumIEEE([]byte(c.WorkID + key)))))
+		g.sampled = g.strategy.sample(g.index, rnd)
+		if len(g.sampled) == len(g.index) {
+			continue
+		}
+
+		sampled := make(map[int]struct{}, len(g.sampled))
+		for _, i := range g.sampled {
+			sampled[i] = struct{}{}
+		}
+		for _, i := range g.index {
+			if _, ok := sampled[i]; !ok {
+				c.SQLRisks[i].RepresentedBy = g.sampled[0] + 1
+			}
+		}
+		c.samples = append(c.samples, g)
+	}
+	return c
+}
+
+// InheritSampledVerdicts 未检测的语句由同组中风险等级最高的已检测语句代表，并继承其识别结果
+func (c *WorkRisk) InheritSampledVerdicts() *WorkRisk {
+	for _, g := range c.samples {
+		best := g.sampled[0]
+		for _, i := range g.sampled[1:] {
+			if comm.LevelMap[c.SQLRisks[i].PreResult.Level] > comm.LevelMap[c.SQLRisks[best].PreResult.Level] {
+				best = i
+			}
+		}
+
+		src := c.SQLRisks[best]
+		for _, i := range g.index {
+			r := c.SQLRisks[i]
+			if r.RepresentedBy == 0 {
+				continue
+			}
+			r.RepresentedBy = best + 1
+			r.PreResult = src.PreResult
+			r.MatchedBasicPolicy = append([]policy.Policy(nil), src.MatchedBasicPolicy...)
+			r.MatchedAggPolicy = src.MatchedAggPolicy
+			r.InfoPolicy = append([]policy.Policy(nil), src.InfoPolicy...)
+			r.LowPolicy = append([]policy.Policy(nil), src.LowPolicy...)
+			r.HighPolicy = append([]policy.Policy(nil), src.HighPolicy...)
+			r.FatalPolicy = append([]policy.Policy(nil), src.FatalPolicy...)
+		}
+	}
+	return c
+}
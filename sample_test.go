@@ -0,0 +1,145 @@
+package sqlrisk
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/sunkaimr/sql-risk/comm"
+	"github.com/sunkaimr/sql-risk/policy"
+)
+
+func newSampleWorkRisk(t *testing.T, sql string, sampling []SamplingStrategy) *WorkRisk {
+	w := &WorkRisk{
+		WorkID:   "w1",
+		DataBase: "test",
+		SQLText:  sql,
+		Config:   &Config{RiskConfig: RiskConfig{Offline: true, Sampling: sampling}},
+		cache:    NewMemoryCache(0),
+	}
+	err := w.SplitStatement()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range w.SQLRisks {
+		err = r.SetSQLBasicInfo()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w
+}
+
+func TestSampleStatements(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		sampling []SamplingStrategy
+		want     []int
+	}{
+		{
+			name: "test001",
+			sql:  "insert into t1 values (1);insert into t1 values (2);update t1 set a=1 where id=1;update t1 set a=2 where id=2;",
+			want: []int{0, 1, 0, 0},
+		},
+		{
+			name:     "test002",
+			sql:      "update t1 set a=1 where id=1;delete from t1 where id=1;update t1 set a=2 where id=2;update t1 set a=3 where id=3;delete from t1 where id=2;",
+			sampling: []SamplingStrategy{{KeyWords: []policy.KeyWordType{policy.KeyWord.V.UpdateWhere, policy.KeyWord.V.DeleteWhere}, First: 1}},
+			want:     []int{0, 0, 1, 1, 2},
+		},
+		{
+			name:     "test003",
+			sql:      "insert into t1 values (1);insert into t1 values (2);",
+			sampling: []SamplingStrategy{},
+			want:     []int{0, 0},
+		},
+		{
+			// 切换库后相同指纹的SQL分别检测
+			name: "test004",
+			sql:  "insert into t1 values (1);use d1;insert into t1 values (2);insert into t1 values (3);",
+			want: []int{0, 0, 0, 3},
+		},
+	}
+
+	for _, test := range tests {
+		w := newSampleWorkRisk(t, test.sql, test.sampling)
+		w.SampleStatements()
+		got := make([]int, 0, len(w.SQLRisks))
+		for _, r := range w.SQLRisks {
+			got = append(got, r.RepresentedBy)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Fatalf("%s SampleStatements failed, got:%v, want:%v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestSampDetectForInsert(t *testing.T) {
+	// 只按insert抽样，不受配置的抽样策略影响
+	sampling := []SamplingStrategy{{KeyWords: []policy.KeyWordType{policy.KeyWord.V.UpdateWhere}, First: 1}}
+	w := newSampleWorkRisk(t, "insert into t1 values (1);insert into t1 values (2);update t1 set a=1 where id=1;update t1 set a=2 where id=2;", sampling)
+	w.SampDetectForInsert()
+	got := make([]int, 0, len(w.SQLRisks))
+	for _, r := range w.SQLRisks {
+		got = append(got, r.RepresentedBy)
+	}
+	if want := []int{0, 1, 0, 0}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("SampDetectForInsert failed, got:%v, want:%v", got, want)
+	}
+}
+
+func TestSampleStatementsRandom(t *testing.T) {
+	sql := ""
+	for i := 0; i < 20; i++ {
+		sql += fmt.Sprintf("delete from t1 where id=%d;", i)
+	}
+	sampling := []SamplingStrategy{{KeyWords: []policy.KeyWordType{policy.KeyWord.V.DeleteWhere}, First: 2, Random: 3}}
+
+	sampled := func() []int {
+		w := newSampleWorkRisk(t, sql, sampling)
+		w.SampleStatements()
+		index := make([]int, 0)
+		for i, r := range w.SQLRisks {
+			if r.RepresentedBy == 0 {
+				index = append(index, i)
+			}
+		}
+		return index
+	}
+
+	got := sampled()
+	if len(got) != 5 || got[0] != 0 || got[1] != 1 {
+		t.Fatalf("SampleStatements failed, got sampled:%v, want first 2 and random 3", got)
+	}
+	// 同一工单重复识别时抽样结果不变
+	if again := sampled(); fmt.Sprint(again) != fmt.Sprint(got) {
+		t.Fatalf("SampleStatements not stable, got:%v, then:%v", got, again)
+	}
+}
+
+func TestInheritSampledVerdicts(t *testing.T) {
+	sql := "delete from t1 where id=1;delete from t1 where id=2;delete from t1 where id=3;delete from t1 where id=4;"
+	sampling := []SamplingStrategy{{KeyWords: []policy.KeyWordType{policy.KeyWord.V.DeleteWhere}, First: 2}}
+	w := newSampleWorkRisk(t, sql, sampling)
+	w.SampleStatements()
+
+	high := policy.Policy{PolicyID: "P1", Level: comm.High}
+	w.SQLRisks[0].SetMatchPolicies(policy.Policy{PolicyID: "P0", Level: comm.Low})
+	w.SQLRisks[0].SetPreResult(comm.Low, false)
+	w.SQLRisks[1].SetMatchPolicies(high)
+	w.SQLRisks[1].SetPreResult(comm.High, true)
+	w.InheritSampledVerdicts()
+
+	// 由风险等级最高的已检测SQL代表
+	for _, r := range w.SQLRisks[2:] {
+		if r.RepresentedBy != 2 || r.PreResult.Level != comm.High || !r.PreResult.Special ||
+			len(r.HighPolicy) != 1 || r.HighPolicy[0].PolicyID != high.PolicyID {
+			t.Fatalf("InheritSampledVerdicts failed, got represented by:%d, result:%+v, high policy:%+v", r.RepresentedBy, r.PreResult, r.HighPolicy)
+		}
+	}
+
+	w.CalculateSummary()
+	if w.Summary.SQLCount != 4 {
+		t.Fatalf("CalculateSummary failed, got SQLCount:%d, want:4", w.Summary.SQLCount)
+	}
+}
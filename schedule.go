@@ -0,0 +1,220 @@
+package sqlrisk
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/sunkaimr/sql-risk/comm"
+	"github.com/sunkaimr/sql-risk/policy"
+)
+
+const (
+	// loadHistoryWindow 统计历史负载使用的数据时长和采样间隔
+	loadHistoryWindow = 7 * 24 * time.Hour
+	loadHistoryStep   = time.Hour
+	// busyLoadRatio 某时段的负载达到全天平均负载的倍数时认为是业务高峰期
+	busyLoadRatio = 1.2
+)
+
+// LoadProfile 按一天中的小时（本地时间0-23点）统计的历史负载，各指标按最大值归一化到0-1后取平均
+type LoadProfile [24]float64
+
+// ExecutionWindow 推荐的执行窗口，按历史负载选择负载最低的连续时段
+type ExecutionWindow struct {
+	// 窗口的起止时间（本地时间的小时），EndHour小于StartHour时表示跨天
+	StartHour int `json:"start_hour"`
+	EndHour   int `json:"end_hour"`
+	// 下一次进入窗口的时间，当前处于窗口内时为当前时间
+	NextStart time.Time `json:"next_start"`
+	NextEnd   time.Time `json:"next_end"`
+	// 窗口内的平均负载（0-1）
+	Load float64 `json:"load"`
+}
+
+// BuildLoadProfile 按小时统计各指标的历史负载，没有任何数据时返回NoDataPointError
+func BuildLoadProfile(series map[LoadMetric][]MetricPoint, loc *time.Location) (LoadProfile, error) {
+	var profile LoadProfile
+	var sum, count [24]float64
+	for _, points := range series {
+		max := 0.0
+		for _, p := range points {
+			max = math.Max(max, p.Value)
+		}
+		if max <= 0 {
+			continue
+		}
+
+		var metricSum, metricCount [24]float64
+		for _, p := range points {
+			h := p.Time.In(loc).Hour()
+			metricSum[h] += p.Value / max
+			metricCount[h]++
+		}
+		for h := range metricSum {
+			if metricCount[h] > 0 {
+				sum[h] += metricSum[h] / metricCount[h]
+				count[h]++
+			}
+		}
+	}
+
+	found := false
+	for h := range profile {
+		if count[h] > 0 {
+			profile[h] = sum[h] / count[h]
+			found = true
+		}
+	}
+	if !found {
+		return profile, NoDataPointError
+	}
+	return profile, nil
+}
+
+// Mean 全天的平均负载
+func (p LoadProfile) Mean() float64 {
+	sum := 0.0
+	for _, v := range p {
+		sum += v
+	}
+	return sum / float64(len(p))
+}
+
+// Busy 指定的小时是否处于业务高峰期
+func (p LoadProfile) Busy(hour int) bool {
+	mean := p.Mean()
+	return mean > 0 && p[hour%24] >= mean*busyLoadRatio
+}
+
+// RecommendWindow 选择负载之和最低的连续hours个小时，负载相同时选择离now最近的窗口
+func (p LoadProfile) RecommendWindow(now time.Time, hours int) ExecutionWindow {
+	if hours < 1 {
+		hours = 1
+	}
+	if hours > 24 {
+		hours = 24
+	}
+
+	best, bestLoad := 0, math.MaxFloat64
+	for i := 0; i < 24; i++ {
+		load := 0.0
+		for j := 0; j < hours; j++ {
+			load += p[(now.Hour()+i+j)%24]
+		}
+		// 浮点数累加存在误差，差异很小时视为相同
+		if load < bestLoad-1e-9 {
+			best, bestLoad = i, load
+		}
+	}
+
+	hour := time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), 0, 0, 0, now.Location())
+	w := ExecutionWindow{
+		StartHour: (now.Hour() + best) % 24,
+		EndHour:   (now.Hour() + best + hours) % 24,
+		NextStart: hour.Add(time.Duration(best) * time.Hour),
+		NextEnd:   hour.Add(time.Duration(best+hours) * time.Hour),
+		Load:      bestLoad / float64(hours),
+	}
+	if best == 0 {
+		w.NextStart = now
+	}
+	return w
+}
+
+// QueryLoadProfile 查询过去7天的CPU、QPS、磁盘IO统计负载，部分指标没有数据时只使用有数据的指标，
+// 同一个数据源的结果缓存在cache中，查询失败时缓存错误，监控不可用时同一工单中不再重复查询
+func QueryLoadProfile(cache *MemoryCache, config MetricsConfig, target MetricsTarget, now time.Time) (LoadProfile, error) {
+	key := strings.Join([]string{"LoadProfile", target.Addr, target.Port}, "|")
+	if v, ok := cache.Get(key); ok {
+		if err, ok := v.(error); ok {
+			return LoadProfile{}, err
+		}
+		return v.(LoadProfile), nil
+	}
+
+	profile, err := queryLoadProfile(config, target, now)
+	if err != nil {
+		cache.Set(key, err, 0)
+		return profile, err
+	}
+	cache.Set(key, profile, 0)
+	return profile, nil
+}
+
+func queryLoadProfile(config MetricsConfig, target MetricsTarget, now time.Time) (LoadProfile, error) {
+	history, ok := NewMetricsProvider(config).(LoadHistoryProvider)
+	if !ok {
+		return LoadProfile{}, NoRangeMetricsProviderError
+	}
+	series := make(map[LoadMetric][]MetricPoint, len(LoadMetrics))
+	errs := make([]string, 0, len(LoadMetrics))
+	for _, m := range LoadMetrics {
+		points, err := history.LoadRange(target, m, now.Add(-loadHistoryWindow), now, loadHistoryStep)
+		if errors.Is(err, NoRangeMetricsProviderError) {
+			return LoadProfile{}, err
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("query %s history failed, %s", m, err))
+			continue
+		}
+		series[m] = points
+	}
+	if len(series) == 0 {
+		return LoadProfile{}, errors.New(strings.Join(errs, "; "))
+	}
+	return BuildLoadProfile(series, now.Location())
+}
+
+// CollectBusyPeriod 判断DML、DDL执行时当前是否处于历史业务高峰期，未配置支持历史查询的监控时跳过，
+// 监控不可用时只记录错误，不影响其他风险项的识别
+func (c *SQLRisk) CollectBusyPeriod() error {
+	operate, err := c.GetItemValueWithOperateType(policy.Operate.ID)
+	if err != nil {
+		return err
+	}
+	if operate != policy.Operate.V.DML && operate != policy.Operate.V.DDL {
+		return nil
+	}
+
+	start := time.Now()
+	profile, err := QueryLoadProfile(c.cache, c.Config.Runtime, c.metricsTarget(), start)
+	if errors.Is(err, NoRangeMetricsProviderError) {
+		return nil
+	}
+	if err != nil {
+		c.SetItemError(policy.BusyPeriod.Name, err)
+		return nil
+	}
+	c.SetItemValue(policy.BusyPeriod.Name, policy.BusyPeriod.ID, profile.Busy(start.Hour()), int(time.Now().Sub(start).Milliseconds()))
+	return nil
+}
+
+// RecommendExecutionWindow 为high、fatal级别的工单推荐执行窗口，窗口时长按工单中耗时最长的DDL计算，至少1小时。
+// 监控不可用的错误已经记录在各SQL的BusyPeriod中，此时不推荐窗口
+func (c *WorkRisk) RecommendExecutionWindow() {
+	if c.Config == nil || c.Config.RiskConfig.Offline || comm.LevelMap[c.PreResult.Level] < comm.LevelMap[comm.High] {
+		return
+	}
+
+	duration := 0
+	for _, r := range c.SQLRisks {
+		if d, err := r.GetItemValueWithInt(policy.DDLDuration.ID); err == nil && d > duration {
+			duration = d
+		}
+	}
+
+	addr := c.Addr
+	if c.ReadWriteAddr != "" {
+		addr = c.ReadWriteAddr
+	}
+	now := time.Now()
+	profile, err := QueryLoadProfile(c.cache, c.Config.Runtime, MetricsTarget{Addr: addr, Port: c.Port, Class: c.MetricsClass}, now)
+	if err != nil {
+		return
+	}
+	w := profile.RecommendWindow(now, int(math.Ceil(float64(duration)/3600)))
+	c.RecommendedWindow = &w
+}
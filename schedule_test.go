@@ -0,0 +1,227 @@
+package sqlrisk
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/sunkaimr/sql-risk/comm"
+	"github.com/sunkaimr/sql-risk/policy"
+)
+
+// hourlyHistory 生成过去7天每小时的采样点，load返回指定小时的负载
+func hourlyHistory(now time.Time, load func(hour int) float64) []MetricPoint {
+	points := make([]MetricPoint, 0, 7*24)
+	for t := now.Add(-loadHistoryWindow); t.Before(now); t = t.Add(loadHistoryStep) {
+		points = append(points, MetricPoint{Time: t, Value: load(t.Hour())})
+	}
+	return points
+}
+
+// daytimeLoad 9-18点为业务高峰期
+func daytimeLoad(hour int) float64 {
+	if hour >= 9 && hour < 18 {
+		return 80
+	}
+	if hour >= 2 && hour < 5 {
+		return 5
+	}
+	return 20
+}
+
+func TestBuildLoadProfile(t *testing.T) {
+	now := time.Date(2023, 7, 3, 12, 0, 0, 0, time.Local)
+
+	profile, err := BuildLoadProfile(map[LoadMetric][]MetricPoint{
+		LoadMetricCpu: hourlyHistory(now, daytimeLoad),
+		// QPS全天相同，只影响平均值不影响高低
+		LoadMetricQPS: hourlyHistory(now, func(int) float64 { return 1000 }),
+		// 没有数据的指标不参与统计
+		LoadMetricDiskIO: hourlyHistory(now, func(int) float64 { return 0 }),
+	}, time.Local)
+	if err != nil {
+		t.Fatalf("BuildLoadProfile failed, got error: %s", err)
+	}
+	if math.Abs(profile[10]-1) > 1e-9 || math.Abs(profile[3]-(5.0/80+1)/2) > 1e-9 {
+		t.Fatalf("BuildLoadProfile got %v", profile)
+	}
+
+	tests := []struct {
+		hour int
+		want bool
+	}{
+		{10, true},
+		{17, true},
+		{18, false},
+		{3, false},
+	}
+	for _, test := range tests {
+		if got := profile.Busy(test.hour); got != test.want {
+			t.Fatalf("Busy(%d) got %v, want %v, profile: %v", test.hour, got, test.want, profile)
+		}
+	}
+
+	if _, err = BuildLoadProfile(map[LoadMetric][]MetricPoint{LoadMetricCpu: nil}, time.Local); err != NoDataPointError {
+		t.Fatalf("BuildLoadProfile without data got %v, want %v", err, NoDataPointError)
+	}
+}
+
+func TestRecommendWindow(t *testing.T) {
+	profile, _ := BuildLoadProfile(map[LoadMetric][]MetricPoint{
+		LoadMetricCpu: hourlyHistory(time.Now(), daytimeLoad),
+	}, time.Local)
+
+	tests := []struct {
+		name      string
+		now       time.Time
+		hours     int
+		startHour int
+		endHour   int
+		inWindow  bool
+	}{
+		{"test001", time.Date(2023, 7, 3, 12, 30, 0, 0, time.Local), 0, 2, 3, false},
+		{"test002", time.Date(2023, 7, 3, 12, 30, 0, 0, time.Local), 3, 2, 5, false},
+		// 当前已处于窗口内
+		{"test003", time.Date(2023, 7, 3, 2, 30, 0, 0, time.Local), 2, 2, 4, true},
+		// 窗口跨天
+		{"test004", time.Date(2023, 7, 3, 12, 30, 0, 0, time.Local), 8, 21, 5, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := profile.RecommendWindow(test.now, test.hours)
+			if w.StartHour != test.startHour || w.EndHour != test.endHour {
+				t.Fatalf("RecommendWindow got %d-%d, want %d-%d", w.StartHour, w.EndHour, test.startHour, test.endHour)
+			}
+			if test.inWindow != w.NextStart.Equal(test.now) || w.NextStart.Before(test.now) || w.NextEnd.Hour() != test.endHour {
+				t.Fatalf("RecommendWindow got next window %v-%v", w.NextStart, w.NextEnd)
+			}
+		})
+	}
+}
+
+func TestCollectBusyPeriod(t *testing.T) {
+	now := time.Now()
+	busy := func(hour int) float64 {
+		if hour == now.Hour() {
+			return 100
+		}
+		return 10
+	}
+
+	tests := []struct {
+		name    string
+		operate policy.OperateType
+		history map[LoadMetric][]MetricPoint
+		want    any
+		errors  int
+	}{
+		{"test001", policy.Operate.V.DML, map[LoadMetric][]MetricPoint{LoadMetricQPS: hourlyHistory(now, busy)}, true, 0},
+		{"test002", policy.Operate.V.DDL, map[LoadMetric][]MetricPoint{LoadMetricCpu: hourlyHistory(now, func(int) float64 { return 10 })}, false, 0},
+		{"test003", policy.Operate.V.DQL, map[LoadMetric][]MetricPoint{LoadMetricQPS: hourlyHistory(now, busy)}, nil, 0},
+		// 监控不可用时只记录错误
+		{"test004", policy.Operate.V.DML, nil, nil, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := &SQLRisk{
+				Config: &Config{Runtime: MetricsConfig{Static: &StaticMetricsProvider{LoadHistory: test.history}}},
+				cache:  NewMemoryCache(0),
+			}
+			c.SetItemValue(policy.Operate.Name, policy.Operate.ID, test.operate, 0)
+
+			if err := c.CollectBusyPeriod(); err != nil {
+				t.Fatalf("CollectBusyPeriod failed, got error: %s", err)
+			}
+			if got := c.GetItemValue(policy.BusyPeriod.ID); got != test.want || len(c.Errors) != test.errors {
+				t.Fatalf("BusyPeriod got %v, errors: %+v, want %v", got, c.Errors, test.want)
+			}
+		})
+	}
+
+	// 未配置监控或监控不支持历史查询时跳过，不记录错误
+	for _, config := range []MetricsConfig{{}, {CpuCores: 8}} {
+		c := &SQLRisk{Config: &Config{Runtime: config}, cache: NewMemoryCache(0)}
+		c.SetItemValue(policy.Operate.Name, policy.Operate.ID, policy.Operate.V.DML, 0)
+		if err := c.CollectBusyPeriod(); err != nil || c.GetItemValue(policy.BusyPeriod.ID) != nil || len(c.Errors) != 0 {
+			t.Fatalf("CollectBusyPeriod with %+v got %v, errors: %+v", config, err, c.Errors)
+		}
+	}
+}
+
+// unavailableLoadMetrics 负载历史查询总是失败的监控，记录查询的次数
+type unavailableLoadMetrics struct {
+	errMetrics
+	calls *int
+}
+
+func (m unavailableLoadMetrics) LoadRange(MetricsTarget, LoadMetric, time.Time, time.Time, time.Duration) ([]MetricPoint, error) {
+	*m.calls++
+	return nil, fmt.Errorf("unavailable")
+}
+
+func TestQueryLoadProfileCacheError(t *testing.T) {
+	calls := 0
+	config := &Config{Runtime: MetricsConfig{Provider: unavailableLoadMetrics{calls: &calls}}}
+	cache := NewMemoryCache(0)
+
+	// 同一工单中监控不可用时只查询一次
+	for i := 0; i < 3; i++ {
+		c := &SQLRisk{Addr: "127.0.0.1", Port: "3306", Config: config, cache: cache}
+		c.SetItemValue(policy.Operate.Name, policy.Operate.ID, policy.Operate.V.DML, 0)
+		if err := c.CollectBusyPeriod(); err != nil {
+			t.Fatalf("CollectBusyPeriod failed, got error: %s", err)
+		}
+		if len(c.Errors) != 1 {
+			t.Fatalf("CollectBusyPeriod got errors: %+v, want 1", c.Errors)
+		}
+	}
+	if calls != len(LoadMetrics) {
+		t.Fatalf("LoadRange called %d times, want %d", calls, len(LoadMetrics))
+	}
+}
+
+func TestRecommendExecutionWindow(t *testing.T) {
+	now := time.Now()
+	config := &Config{Runtime: MetricsConfig{Static: &StaticMetricsProvider{
+		LoadHistory: map[LoadMetric][]MetricPoint{LoadMetricCpu: hourlyHistory(now, daytimeLoad)},
+	}}}
+
+	tests := []struct {
+		name     string
+		level    comm.Level
+		duration any
+		hours    int
+	}{
+		{"test001", comm.High, nil, 1},
+		{"test002", comm.Fatal, 2*3600 + 1, 3},
+		{"test003", comm.Low, nil, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := &SQLRisk{}
+			if test.duration != nil {
+				r.SetItemValue(policy.DDLDuration.Name, policy.DDLDuration.ID, test.duration, 0)
+			}
+			c := &WorkRisk{Config: config, SQLRisks: []*SQLRisk{r}, cache: NewMemoryCache(0)}
+			c.SetPreResult(test.level, false)
+
+			c.RecommendExecutionWindow()
+			if test.hours == 0 {
+				if c.RecommendedWindow != nil {
+					t.Fatalf("RecommendExecutionWindow got %+v, want nil", c.RecommendedWindow)
+				}
+				return
+			}
+			if c.RecommendedWindow == nil {
+				t.Fatalf("RecommendExecutionWindow got nil")
+			}
+			if got := c.RecommendedWindow.NextEnd.Sub(c.RecommendedWindow.NextStart); got > time.Duration(test.hours)*time.Hour {
+				t.Fatalf("RecommendExecutionWindow got window %+v, want %d hours", c.RecommendedWindow, test.hours)
+			}
+			if c.RecommendedWindow.StartHour < 2 || c.RecommendedWindow.StartHour >= 5 {
+				t.Fatalf("RecommendExecutionWindow got window %+v, want during 2-5", c.RecommendedWindow)
+			}
+		})
+	}
+}
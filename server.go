@@ -0,0 +1,155 @@
+package sqlrisk
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sunkaimr/sql-risk/comm"
+	"github.com/sunkaimr/sql-risk/policy"
+)
+
+// ServerVariables 采集的全局变量，不存在的变量（如非Aurora的aurora_version）会被忽略
+var ServerVariables = []string{
+	"version_comment",
+	"aurora_version",
+	"binlog_format",
+	"binlog_row_image",
+	"read_only",
+	"super_read_only",
+	"lock_wait_timeout",
+	"innodb_lock_wait_timeout",
+	"innodb_online_alter_log_max_size",
+	"lower_case_table_names",
+	"sql_mode",
+}
+
+// ServerInfo 数据源的版本、分支和关键变量
+type ServerInfo struct {
+	Version comm.Version `json:"version"`
+	// SELECT VERSION() 的原始结果
+	VersionText string            `json:"version_text"`
+	Flavor      string            `json:"flavor"`
+	Variables   map[string]string `json:"variables"`
+}
+
+// SupportInstantDDL 是否支持INSTANT算法的DDL，MySQL 8.0.12、MariaDB 10.3开始支持，TiDB的DDL均为在线执行
+func (s *ServerInfo) SupportInstantDDL() bool {
+	switch s.Flavor {
+	case comm.FlavorTiDB:
+		return true
+	case comm.FlavorMariaDB:
+		return s.Version.AtLeast(10, 3, 0)
+	default:
+		return s.Version.AtLeast(8, 0, 12)
+	}
+}
+
+// SupportRenameColumn 是否支持 ALTER TABLE ... RENAME COLUMN，MySQL 8.0、MariaDB 10.5.2开始支持
+func (s *ServerInfo) SupportRenameColumn() bool {
+	switch s.Flavor {
+	case comm.FlavorTiDB:
+		return true
+	case comm.FlavorMariaDB:
+		return s.Version.AtLeast(10, 5, 2)
+	default:
+		return s.Version.AtLeast(8, 0, 0)
+	}
+}
+
+// ReadOnly 是否为只读实例
+func (s *ServerInfo) ReadOnly() bool {
+	return strings.EqualFold(s.Variables["read_only"], "ON") || strings.EqualFold(s.Variables["super_read_only"], "ON")
+}
+
+// ServerVariables 查询全局变量
+func (db *Connector) ServerVariables(names []string) (map[string]string, error) {
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted = append(quoted, fmt.Sprintf("'%s'", name))
+	}
+	sqlQuery := fmt.Sprintf("SHOW GLOBAL VARIABLES WHERE Variable_name IN (%s)", strings.Join(quoted, ", "))
+
+	res, err := db.Query(sqlQuery)
+	if err != nil {
+		return nil, fmt.Errorf("exec sql query failed, %s", err)
+	}
+
+	variables := make(map[string]string, len(names))
+	name, value := "", ""
+	for res.Rows.Next() {
+		err = res.Rows.Scan(&name, &value)
+		if err != nil {
+			return nil, fmt.Errorf("scan rows failed, %s", err)
+		}
+		variables[strings.ToLower(name)] = value
+	}
+	err = res.Rows.Close()
+	if err != nil {
+		return nil, fmt.Errorf("close scan rows failed, %s", err)
+	}
+	return variables, nil
+}
+
+// CollectServerInfoValues 采集数据源的版本、分支、关键变量和支持的特性，供策略按版本区分
+func (c *SQLRisk) CollectServerInfoValues() error {
+	start := time.Now()
+	info, err := c.CollectServerInfo()
+	if err != nil {
+		c.SetItemError(policy.ServerVersion.Name, err)
+		return fmt.Errorf("collect server info failed, %s", err)
+	}
+
+	cost := int(time.Now().Sub(start).Milliseconds())
+	lockWaitTimeout, _ := strconv.Atoi(info.Variables["lock_wait_timeout"])
+	c.SetItemValue(policy.ServerVersion.Name, policy.ServerVersion.ID, info.Version.Int(), cost)
+	c.SetItemValue(policy.ServerFlavor.Name, policy.ServerFlavor.ID, info.Flavor, cost)
+	c.SetItemValue(policy.BinlogFormat.Name, policy.BinlogFormat.ID, strings.ToUpper(info.Variables["binlog_format"]), cost)
+	c.SetItemValue(policy.ReadOnly.Name, policy.ReadOnly.ID, info.ReadOnly(), cost)
+	c.SetItemValue(policy.LockWaitTimeout.Name, policy.LockWaitTimeout.ID, lockWaitTimeout, cost)
+	c.SetItemValue(policy.InstantDDL.Name, policy.InstantDDL.ID, info.SupportInstantDDL(), cost)
+	c.SetItemValue(policy.RenameColumn.Name, policy.RenameColumn.ID, info.SupportRenameColumn(), cost)
+	return nil
+}
+
+// CollectServerInfo 查询数据源的版本、分支和关键变量，同一个数据源只查询一次
+func (c *SQLRisk) CollectServerInfo() (*ServerInfo, error) {
+	key := strings.Join([]string{"ServerInfo", c.Addr, c.Port}, "|")
+	if v, ok := c.cache.Get(key); ok {
+		return v.(*ServerInfo), nil
+	}
+
+	conn, err := c.connect(c.DataBase)
+	if err != nil {
+		return nil, fmt.Errorf("new mysql connect failed, %s", err)
+	}
+	defer conn.Close()
+
+	info := &ServerInfo{}
+	info.VersionText, err = conn.ServerVersion()
+	if err != nil {
+		return nil, err
+	}
+	info.Version, err = comm.ParseVersion(info.VersionText)
+	if err != nil {
+		return nil, err
+	}
+	info.Variables, err = conn.ServerVariables(ServerVariables)
+	if err != nil {
+		return nil, err
+	}
+	info.Flavor = comm.DetectFlavor(info.VersionText, info.Variables)
+
+	c.cache.Set(key, info, 0)
+	return info, nil
+}
+
+// CollectServerVersion 查询数据库版本，同一个数据源只查询一次
+func (c *SQLRisk) CollectServerVersion() (comm.Version, error) {
+	info, err := c.CollectServerInfo()
+	if err != nil {
+		return comm.Version{}, err
+	}
+	return info.Version, nil
+}
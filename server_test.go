@@ -0,0 +1,60 @@
+package sqlrisk
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sunkaimr/sql-risk/comm"
+)
+
+func TestServerVariables(t *testing.T) {
+	conn, mock, err := mockDBConn("test")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer conn.Close()
+
+	mock.ExpectExec("USE `test`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("^SHOW GLOBAL VARIABLES WHERE Variable_name IN \\('binlog_format', 'read_only'\\)").
+		WillReturnRows(mock.NewRows([]string{"Variable_name", "Value"}).AddRow("binlog_format", "ROW").AddRow("read_only", "OFF"))
+	o, err := conn.ServerVariables([]string{"binlog_format", "read_only"})
+	if err != nil {
+		t.Fatalf("ServerVariables failed, got error: %s", err)
+	}
+	want := map[string]string{"binlog_format": "ROW", "read_only": "OFF"}
+	if !reflect.DeepEqual(o, want) {
+		t.Fatalf("ServerVariables got: %v, want: %v", o, want)
+	}
+}
+
+func TestServerInfoCapability(t *testing.T) {
+	tests := []struct {
+		name         string
+		info         ServerInfo
+		instantDDL   bool
+		renameColumn bool
+		readOnly     bool
+	}{
+		{"test001", ServerInfo{Version: comm.Version{Major: 5, Minor: 7, Patch: 40}, Flavor: comm.FlavorMySQL}, false, false, false},
+		{"test002", ServerInfo{Version: comm.Version{Major: 8, Minor: 0, Patch: 11}, Flavor: comm.FlavorAurora}, false, true, false},
+		{"test003", ServerInfo{Version: comm.Version{Major: 8, Minor: 0, Patch: 32}, Flavor: comm.FlavorMySQL,
+			Variables: map[string]string{"super_read_only": "ON"}}, true, true, true},
+		{"test004", ServerInfo{Version: comm.Version{Major: 10, Minor: 4, Patch: 0}, Flavor: comm.FlavorMariaDB}, true, false, false},
+		{"test005", ServerInfo{Version: comm.Version{Major: 5, Minor: 7, Patch: 25}, Flavor: comm.FlavorTiDB}, true, true, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.info.SupportInstantDDL(); got != test.instantDDL {
+				t.Fatalf("SupportInstantDDL got %v, want %v", got, test.instantDDL)
+			}
+			if got := test.info.SupportRenameColumn(); got != test.renameColumn {
+				t.Fatalf("SupportRenameColumn got %v, want %v", got, test.renameColumn)
+			}
+			if got := test.info.ReadOnly(); got != test.readOnly {
+				t.Fatalf("ReadOnly got %v, want %v", got, test.readOnly)
+			}
+		})
+	}
+}
@@ -0,0 +1,468 @@
+package sqlrisk
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pingcap/tidb/parser/ast"
+	"github.com/pingcap/tidb/parser/format"
+	"github.com/pingcap/tidb/parser/model"
+	"github.com/sunkaimr/sql-risk/comm"
+	"github.com/sunkaimr/sql-risk/policy"
+)
+
+// SimulatedTable 工单中前面的语句执行后表的状态
+type SimulatedTable struct {
+	// 重命名得到的表对应数据库中的原表，工单中新建的表为空
+	Origin string `json:"origin"`
+	// 工单中新建的表，数据库中还不存在
+	Created bool `json:"created"`
+	// 已被删除或重命名为其他表
+	Dropped bool `json:"dropped"`
+	// 工单中新建的表是否写入过数据
+	Filled bool `json:"filled"`
+	// 工单中新建的表是否有主键，CREATE TABLE ... LIKE时为nil，与Like的表相同
+	PrimaryKey *bool `json:"primary_key,omitempty"`
+	// CREATE TABLE ... LIKE复制了结构的数据库中的表
+	Like string `json:"like,omitempty"`
+	// 工单中新建的表是否有外键，LIKE不复制外键
+	ForeignKey bool `json:"foreign_key,omitempty"`
+	// 工单中新建的表有索引的列
+	Indexes []string `json:"indexes,omitempty"`
+	// 写入的数据来自数据库中的哪些表（INSERT ... SELECT、CREATE TABLE ... SELECT），按源表估算表大小和行数
+	Sources []string `json:"sources,omitempty"`
+	// INSERT ... VALUES写入的行数
+	Rows int `json:"rows,omitempty"`
+}
+
+// TicketSchema 按工单中语句的顺序模拟的表结构，只记录工单中修改过的表，未记录的表与数据库一致
+type TicketSchema struct {
+	// 库.表（小写） -> 表的状态，只包含新建、删除、重命名过的表
+	Tables map[string]*SimulatedTable
+	// 库.表（小写） -> 工单中新增的列
+	AddedColumns map[string][]string
+}
+
+func NewTicketSchema() *TicketSchema {
+	return &TicketSchema{
+		Tables:       make(map[string]*SimulatedTable, 1),
+		AddedColumns: make(map[string][]string, 1),
+	}
+}
+
+// Snapshot 返回语句中的表在语句执行前的状态，都与数据库一致时返回nil
+func (s *TicketSchema) Snapshot(tables []string) map[string]SimulatedTable {
+	var snapshot map[string]SimulatedTable
+	for _, t := range tables {
+		st, ok := s.Tables[strings.ToLower(t)]
+		if !ok {
+			continue
+		}
+		if snapshot == nil {
+			snapshot = make(map[string]SimulatedTable, len(tables))
+		}
+		snapshot[strings.ToLower(t)] = *st
+	}
+	return snapshot
+}
+
+// Apply 按语句更新模拟的表结构，并记录语句在工单上下文中的评估项。无法解析的语句不影响表结构
+func (s *TicketSchema) Apply(r *SQLRisk) {
+	start := time.Now()
+	stmt, err := comm.TiParse(r.SQLText, "", "")
+	if err != nil || stmt == nil {
+		return
+	}
+
+	switch n := stmt.(type) {
+	case *ast.CreateTableStmt:
+		name := simulatedName(r.DataBase, n.Table)
+		// IF NOT EXISTS：工单中的表未被删除时不会新建；工单中未涉及的表查询数据库，已存在时不会新建
+		if st, ok := s.Tables[name]; n.IfNotExists && (ok && !st.Dropped || !ok && r.tableInDatabase(n.Table)) {
+			return
+		}
+		t := &SimulatedTable{Created: true, Filled: n.Select != nil}
+		if n.ReferTable != nil {
+			s.like(t, r.DataBase, n.ReferTable)
+		} else {
+			pk := false
+			pk, t.ForeignKey, t.Indexes = createTableKeys(n)
+			t.PrimaryKey = &pk
+		}
+		if n.Select != nil {
+			t.Sources, t.Rows = s.sources(r.DataBase, n.Select)
+		}
+		s.Tables[name] = t
+		delete(s.AddedColumns, name)
+	case *ast.DropTableStmt:
+		if n.IsView {
+			return
+		}
+		for _, table := range n.Tables {
+			s.drop(simulatedName(r.DataBase, table))
+		}
+	case *ast.TruncateTableStmt:
+		if t, ok := s.Tables[simulatedName(r.DataBase, n.Table)]; ok && t.Created {
+			t.Filled, t.Sources, t.Rows = false, nil, 0
+		}
+	case *ast.RenameTableStmt:
+		sw := swapResult{}
+		for _, table := range n.TableToTables {
+			sw.add(s.rename(r.DataBase, table.OldTable, table.NewTable))
+		}
+		sw.set(r, start)
+	case *ast.AlterTableStmt:
+		table, name := n.Table, simulatedName(r.DataBase, n.Table)
+		var sw *swapResult
+		for _, spec := range n.Specs {
+			switch spec.Tp {
+			case ast.AlterTableAddColumns:
+				for _, col := range spec.NewColumns {
+					s.AddedColumns[name] = append(s.AddedColumns[name], col.Name.Name.L)
+				}
+			case ast.AlterTableRenameTable:
+				if sw == nil {
+					sw = &swapResult{}
+				}
+				sw.add(s.rename(r.DataBase, table, spec.NewTable))
+				table, name = spec.NewTable, simulatedName(r.DataBase, spec.NewTable)
+			}
+		}
+		if sw != nil {
+			sw.set(r, start)
+		}
+	case *ast.InsertStmt:
+		table := singleTable(n.Table)
+		if table == nil {
+			return
+		}
+		t, ok := s.Tables[simulatedName(r.DataBase, table)]
+		if !ok || !t.Created {
+			return
+		}
+		t.Filled = true
+		if n.Select != nil {
+			sources, rows := s.sources(r.DataBase, n.Select)
+			t.Sources = append(t.Sources, sources...)
+			t.Rows += rows
+		} else if len(n.Lists) != 0 {
+			t.Rows += len(n.Lists)
+		} else {
+			// INSERT ... SET
+			t.Rows++
+		}
+	case *ast.UpdateStmt:
+		table := singleTable(n.TableRefs)
+		if table == nil {
+			return
+		}
+		backfill := false
+		added := s.AddedColumns[simulatedName(r.DataBase, table)]
+		if n.Where == nil && len(added) != 0 {
+			for _, a := range n.List {
+				if comm.EleExist(a.Column.Name.L, added) {
+					backfill = true
+					break
+				}
+			}
+		}
+		r.SetItemValue(policy.BackfillAfterAlter.Name, policy.BackfillAfterAlter.ID, backfill, int(time.Now().Sub(start).Milliseconds()))
+	}
+}
+
+// swapResult 重命名语句中是否有工单中新建的表替换了原表
+type swapResult struct {
+	filled bool
+	empty  bool
+}
+
+func (c *swapResult) add(filled, swap bool) {
+	if swap {
+		c.filled = c.filled || filled
+		c.empty = c.empty || !filled
+	}
+}
+
+func (c *swapResult) set(r *SQLRisk, start time.Time) {
+	cost := int(time.Now().Sub(start).Milliseconds())
+	r.SetItemValue(policy.SwapTable.Name, policy.SwapTable.ID, c.filled, cost)
+	r.SetItemValue(policy.SwapEmptyTable.Name, policy.SwapEmptyTable.ID, c.empty, cost)
+}
+
+func (s *TicketSchema) drop(name string) {
+	t := &SimulatedTable{Dropped: true}
+	if old, ok := s.Tables[name]; ok {
+		t.Created = old.Created
+	}
+	s.Tables[name] = t
+	delete(s.AddedColumns, name)
+}
+
+// rename 将表oldTable重命名为newTable。工单中新建的表替换了数据库中已删除或重命名的表时为切换表，返回新建的表是否写入过数据
+func (s *TicketSchema) rename(database string, oldTable, newTable *ast.TableName) (filled bool, swap bool) {
+	oldName, newName := simulatedName(database, oldTable), simulatedName(database, newTable)
+	t := &SimulatedTable{Origin: tableName(database, oldTable)}
+	if old, ok := s.Tables[oldName]; ok {
+		cp := *old
+		t = &cp
+	}
+	if prev, ok := s.Tables[newName]; ok && t.Created && prev.Dropped && !prev.Created {
+		filled, swap = t.Filled, true
+	}
+
+	columns, ok := s.AddedColumns[oldName]
+	s.drop(oldName)
+	if ok {
+		s.AddedColumns[newName] = columns
+	} else {
+		delete(s.AddedColumns, newName)
+	}
+	s.Tables[newName] = t
+	return filled, swap
+}
+
+// like CREATE TABLE ... LIKE复制表结构，复制的是工单中新建的表时继承其结构
+func (s *TicketSchema) like(t *SimulatedTable, database string, refer *ast.TableName) {
+	ref, ok := s.Tables[simulatedName(database, refer)]
+	switch {
+	case !ok:
+		t.Like = tableName(database, refer)
+	case ref.Created:
+		t.PrimaryKey, t.Indexes, t.Like = ref.PrimaryKey, ref.Indexes, ref.Like
+	case !ref.Dropped:
+		t.Like = ref.Origin
+	}
+}
+
+// sources 查询语句读取的数据库中的表，读取工单中新建的表时取其数据来源，返回数据库中的表和INSERT ... VALUES写入的行数
+func (s *TicketSchema) sources(database string, sel ast.ResultSetNode) ([]string, int) {
+	var sb strings.Builder
+	if err := sel.Restore(format.NewRestoreCtx(format.DefaultRestoreFlags, &sb)); err != nil {
+		return nil, 0
+	}
+	tables, err := comm.ExtractingRelatedTableName(sb.String(), database)
+	if err != nil {
+		return nil, 0
+	}
+
+	sources, rows := make([]string, 0, len(tables)), 0
+	for _, table := range tables {
+		t, ok := s.Tables[strings.ToLower(table)]
+		switch {
+		case !ok:
+			sources = append(sources, table)
+		case t.Created:
+			sources = append(sources, t.Sources...)
+			rows += t.Rows
+		case !t.Dropped:
+			sources = append(sources, t.Origin)
+		}
+	}
+	return comm.RemoveDuplicatesItem(sources), rows
+}
+
+// tableName 库.表，未指定库时使用当前库
+func tableName(database string, table *ast.TableName) string {
+	if table.Schema.O != "" {
+		database = table.Schema.O
+	}
+	return fmt.Sprintf("%s.%s", database, table.Name.O)
+}
+
+func simulatedName(database string, table *ast.TableName) string {
+	return strings.ToLower(tableName(database, table))
+}
+
+// singleTable 单表的INSERT、UPDATE操作的表，多表时返回nil
+func singleTable(refs *ast.TableRefsClause) *ast.TableName {
+	if refs == nil || refs.TableRefs == nil || refs.TableRefs.Right != nil {
+		return nil
+	}
+	source, ok := refs.TableRefs.Left.(*ast.TableSource)
+	if !ok {
+		return nil
+	}
+	table, _ := source.Source.(*ast.TableName)
+	return table
+}
+
+// createTableKeys 建表语句中是否有主键、外键，以及有索引的列
+func createTableKeys(n *ast.CreateTableStmt) (pk bool, fk bool, indexes []string) {
+	for _, c := range n.Constraints {
+		switch c.Tp {
+		case ast.ConstraintCheck:
+			continue
+		case ast.ConstraintPrimaryKey:
+			pk = true
+		case ast.ConstraintForeignKey:
+			fk = true
+		}
+		for _, key := range c.Keys {
+			if key.Column != nil {
+				indexes = append(indexes, key.Column.Name.L)
+			}
+		}
+	}
+	for _, col := range n.Cols {
+		for _, opt := range col.Options {
+			switch opt.Tp {
+			case ast.ColumnOptionPrimaryKey:
+				pk = true
+				indexes = append(indexes, col.Name.Name.L)
+			case ast.ColumnOptionUniqKey:
+				indexes = append(indexes, col.Name.Name.L)
+			case ast.ColumnOptionReference:
+				fk = true
+			}
+		}
+	}
+	return pk, fk, indexes
+}
+
+// tableInDatabase 数据库中是否已存在该表，离线模式或查询失败时按不存在处理，连接错误由语句自身的采集报告
+func (c *SQLRisk) tableInDatabase(table *ast.TableName) bool {
+	if c.Config != nil && c.Config.RiskConfig.Offline {
+		return false
+	}
+	db := table.Schema.O
+	if db == "" {
+		db = c.DataBase
+	}
+	conn, err := c.dialect(db, true)
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+	exist, err := conn.TableExist(db, table.Name.O)
+	return err == nil && exist
+}
+
+// AnalyzeTicket 按语句的顺序模拟工单中表结构的变化：后续语句按前面的语句新建、删除、重命名后的表采集评估项，
+// 并识别切换影子表、新增列后全表回填等跨语句的风险
+func (c *WorkRisk) AnalyzeTicket() *WorkRisk {
+	schema := NewTicketSchema()
+	for _, r := range c.SQLRisks {
+		// PostgreSQL的语句无法按MySQL语法解析
+		if r.postgres() {
+			return c
+		}
+		r.ticketTables = schema.Snapshot(append(append([]string{}, r.Tables...), r.RelevantTables...))
+		schema.Apply(r)
+	}
+	return c
+}
+
+// CollectTicketSchemaValues 语句中的表被工单中前面的语句新建、删除或重命名过，与数据库不一致：
+// 重命名得到的表将SQL改写为数据库中的原表后按原表采集；工单中新建的表不查询数据库，按模拟的表结构和数据来源估算
+func (c *SQLRisk) CollectTicketSchemaValues() error {
+	exist := !c.HasKeyWord(
+		policy.KeyWord.V.CreateTab,
+		policy.KeyWord.V.CreateTabAs,
+		policy.KeyWord.V.CreateTmpTab,
+		policy.KeyWord.V.DropTabIfExist)
+
+	snapshot := c.ticketTables
+	created := make(map[string]SimulatedTable, len(snapshot))
+	origins := make(map[string]string, len(snapshot))
+	for name, st := range snapshot {
+		switch {
+		case st.Dropped && exist:
+			err := fmt.Errorf("table %s has been dropped or renamed by previous SQL in the ticket", name)
+			c.SetItemError(policy.TabExist.Name, err)
+			return err
+		case st.Created || st.Dropped:
+			created[name] = st
+		default:
+			origins[name] = st.Origin
+		}
+	}
+
+	tables, sql, sqlID := c.Tables, c.SQLText, c.SQLID
+	if len(origins) != 0 {
+		originSQL, err := c.originSQL(origins)
+		if err != nil {
+			return fmt.Errorf("rewrite SQL with origin tables failed, %s", err)
+		}
+		c.Tables = make([]string, 0, len(tables))
+		for _, t := range tables {
+			if origin, ok := origins[strings.ToLower(t)]; ok {
+				t = origin
+			}
+			c.Tables = append(c.Tables, t)
+		}
+		c.SQLText, c.SQLID = originSQL, comm.Hash(originSQL)
+	}
+	// 只保留工单中新建的表，采集时不查询数据库
+	c.ticketTables = created
+
+	err := c.collectMySQLValues()
+	c.Tables, c.SQLText, c.SQLID, c.ticketTables = tables, sql, sqlID, snapshot
+	if err != nil {
+		return err
+	}
+
+	// 改表方案在执行时按重命名后的表执行
+	if c.OSCPlan != nil {
+		c.OSCPlan = c.GenerateOSCPlan()
+	}
+	return nil
+}
+
+// createdTable 表在工单中新建或已被删除，数据库中还不存在
+func (c *SQLRisk) createdTable(table string) (SimulatedTable, bool) {
+	st, ok := c.ticketTables[strings.ToLower(table)]
+	return st, ok && (st.Created || st.Dropped)
+}
+
+// hasCreatedTable 是否有表在工单中新建或已被删除
+func (c *SQLRisk) hasCreatedTable(tables []string) bool {
+	for _, t := range tables {
+		if _, ok := c.createdTable(t); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// originSQL 将SQL中重命名得到的表替换为数据库中的原表，原表名作为别名以保证列的引用不变
+func (c *SQLRisk) originSQL(origins map[string]string) (string, error) {
+	stmt, err := comm.TiParse(c.SQLText, "", "")
+	if err != nil {
+		return "", err
+	}
+	stmt.Accept(&originRewriter{database: c.DataBase, origins: origins})
+
+	var sb strings.Builder
+	err = stmt.Restore(format.NewRestoreCtx(format.DefaultRestoreFlags, &sb))
+	if err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+type originRewriter struct {
+	database string
+	origins  map[string]string
+}
+
+func (v *originRewriter) Enter(n ast.Node) (ast.Node, bool) {
+	switch n := n.(type) {
+	case *ast.TableSource:
+		if t, ok := n.Source.(*ast.TableName); ok && n.AsName.O == "" {
+			if _, ok = v.origins[simulatedName(v.database, t)]; ok {
+				n.AsName = t.Name
+			}
+		}
+	case *ast.TableName:
+		if origin, ok := v.origins[simulatedName(v.database, n)]; ok {
+			db, table := comm.SplitDataBaseAndTable(origin)
+			n.Schema, n.Name = model.NewCIStr(db), model.NewCIStr(table)
+		}
+	}
+	return n, false
+}
+
+func (v *originRewriter) Leave(n ast.Node) (ast.Node, bool) {
+	return n, true
+}
@@ -0,0 +1,220 @@
+package sqlrisk
+
+import (
+	"testing"
+
+	"github.com/sunkaimr/sql-risk/policy"
+)
+
+// newTicketWorkRisk 离线解析工单中的语句，不连接数据库
+func newTicketWorkRisk(t *testing.T, sql string) *WorkRisk {
+	w := &WorkRisk{
+		WorkID:   "w1",
+		DataBase: "test",
+		SQLText:  sql,
+		Config:   &Config{RiskConfig: RiskConfig{Offline: true}},
+		cache:    NewMemoryCache(0),
+	}
+	err := w.SplitStatement()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range w.SQLRisks {
+		err = r.SetSQLBasicInfo()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w
+}
+
+func TestAnalyzeTicket(t *testing.T) {
+	tests := []struct {
+		name  string
+		sql   string
+		index int
+		want  map[string]bool
+	}{
+		{
+			name:  "test001",
+			sql:   "create table t_new (id int primary key);insert into t_new select * from t;rename table t to t_bak, t_new to t;",
+			index: 2,
+			want:  map[string]bool{policy.SwapTable.ID: true, policy.SwapEmptyTable.ID: false},
+		},
+		{
+			name:  "test002",
+			sql:   "create table t_new like t;rename table t to t_bak, t_new to t;",
+			index: 1,
+			want:  map[string]bool{policy.SwapTable.ID: false, policy.SwapEmptyTable.ID: true},
+		},
+		{
+			name:  "test003",
+			sql:   "create table t_new (id int);insert into t_new values (1);drop table t;alter table t_new rename to t;",
+			index: 3,
+			want:  map[string]bool{policy.SwapTable.ID: true, policy.SwapEmptyTable.ID: false},
+		},
+		{
+			// 重命名为不存在的表不是切换表
+			name:  "test004",
+			sql:   "create table t_new (id int);rename table t_new to t_final;",
+			index: 1,
+			want:  map[string]bool{policy.SwapTable.ID: false, policy.SwapEmptyTable.ID: false},
+		},
+		{
+			name:  "test005",
+			sql:   "alter table t add column c int, add column d int;update t set d=1;",
+			index: 1,
+			want:  map[string]bool{policy.BackfillAfterAlter.ID: true},
+		},
+		{
+			name:  "test006",
+			sql:   "alter table t add column c int;update t set c=1 where id=1;update t set a=1;update test.t2 set c=1;",
+			index: 1,
+			want:  map[string]bool{policy.BackfillAfterAlter.ID: false},
+		},
+		{
+			// 重命名后新增的列跟随表
+			name:  "test007",
+			sql:   "alter table t add column c int;rename table t to t2;update t2 set c=1;",
+			index: 2,
+			want:  map[string]bool{policy.BackfillAfterAlter.ID: true},
+		},
+	}
+
+	for _, test := range tests {
+		w := newTicketWorkRisk(t, test.sql)
+		w.AnalyzeTicket()
+		r := w.SQLRisks[test.index]
+		for id, want := range test.want {
+			got, err := r.GetItemValueWithBool(id)
+			if err != nil || got != want {
+				t.Fatalf("%s AnalyzeTicket failed, got %s:%v %v, want:%v", test.name, id, got, err, want)
+			}
+		}
+	}
+
+	// CREATE TABLE IF NOT EXISTS新建的表，后续语句按工单中新建的表采集，不查询数据库
+	w := newTicketWorkRisk(t, "create table if not exists t_new (id int primary key);insert into t_new values (1);")
+	w.AnalyzeTicket()
+	if st, ok := w.SQLRisks[1].ticketTables["test.t_new"]; !ok || !st.Created {
+		t.Fatalf("insert got snapshot:%+v, want created table t_new", w.SQLRisks[1].ticketTables)
+	}
+	if exist, err := w.SQLRisks[1].tableExist(w.SQLRisks[1].Tables); err != nil || !exist {
+		t.Fatalf("tableExist got %v, %v, want created table t_new exist", exist, err)
+	}
+
+	// 工单中已新建的表不会被IF NOT EXISTS覆盖
+	w = newTicketWorkRisk(t, "create table t5 (id int primary key);create table if not exists t5 (id int);insert into t5 values (1);")
+	w.AnalyzeTicket()
+	if st := w.SQLRisks[2].ticketTables["test.t5"]; !st.Created || st.PrimaryKey == nil || !*st.PrimaryKey {
+		t.Fatalf("insert got snapshot:%+v, want t5 with primary key", w.SQLRisks[2].ticketTables)
+	}
+}
+
+func TestTicketSchemaSnapshot(t *testing.T) {
+	w := newTicketWorkRisk(t, "create table t_new (id int primary key);insert into t_new select * from t;"+
+		"rename table t to t_bak, t_new to t;delete from t_bak;create table if not exists t3 (id int);insert into t3 values (1);"+
+		"create table t4 like T_Bak;insert into t4 values (1), (2);insert into t4 select * from t where id > 10;")
+	w.AnalyzeTicket()
+
+	if w.SQLRisks[0].ticketTables != nil {
+		t.Fatalf("create table got snapshot:%+v, want nil", w.SQLRisks[0].ticketTables)
+	}
+	if st, ok := w.SQLRisks[1].ticketTables["test.t_new"]; !ok || !st.Created || st.Filled || st.PrimaryKey == nil || !*st.PrimaryKey {
+		t.Fatalf("insert got snapshot:%+v, want created table with primary key", w.SQLRisks[1].ticketTables)
+	}
+	// 重命名得到的表查询数据库中的原表
+	if st := w.SQLRisks[3].ticketTables["test.t_bak"]; st.Created || st.Origin != "test.t" {
+		t.Fatalf("delete got snapshot:%+v, want origin test.t", w.SQLRisks[3].ticketTables)
+	}
+	// 离线时数据库中不存在的表按IF NOT EXISTS新建
+	if st, ok := w.SQLRisks[5].ticketTables["test.t3"]; !ok || !st.Created || st.PrimaryKey == nil || *st.PrimaryKey {
+		t.Fatalf("insert got snapshot:%+v, want created table t3", w.SQLRisks[5].ticketTables)
+	}
+	// 按原表的结构新建，写入的数据来自数据库中的原表
+	if st := w.SQLRisks[7].ticketTables["test.t4"]; !st.Created || st.Like != "test.t" || st.PrimaryKey != nil {
+		t.Fatalf("insert got snapshot:%+v, want like test.t", w.SQLRisks[7].ticketTables)
+	}
+	st := w.SQLRisks[8].ticketTables["test.t4"]
+	if !st.Filled || st.Rows != 2 || len(st.Sources) != 0 {
+		t.Fatalf("insert got snapshot:%+v, want 2 rows", w.SQLRisks[8].ticketTables)
+	}
+	// 读取的t为工单中新建的表t_new，数据来源为数据库中的原表
+	if st := w.SQLRisks[8].ticketTables["test.t"]; !st.Created || len(st.Sources) != 1 || st.Sources[0] != "test.t" {
+		t.Fatalf("insert got snapshot:%+v, want t sourced from test.t", w.SQLRisks[8].ticketTables)
+	}
+}
+
+func TestOriginSQL(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{"test001", "delete from t_bak where t_bak.id = 1", "DELETE FROM `test`.`t` AS `t_bak` WHERE `t_bak`.`id`=1"},
+		{"test002", "update test.t_bak b set a = 1 where id in (select id from t2)", "UPDATE `test`.`t` AS `b` SET `a`=1 WHERE `id` IN (SELECT `id` FROM `t2`)"},
+		{"test003", "alter table t_bak add column c int", "ALTER TABLE `test`.`t` ADD COLUMN `c` INT"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := &SQLRisk{DataBase: "test", SQLText: test.sql}
+			got, err := c.originSQL(map[string]string{"test.t_bak": "test.t"})
+			if err != nil || got != test.want {
+				t.Fatalf("originSQL got %s, %v, want %s", got, err, test.want)
+			}
+		})
+	}
+}
+
+func TestCollectTicketSchemaValues(t *testing.T) {
+	w := newTicketWorkRisk(t, "drop table t;delete from t where id=1;drop table if exists t;")
+	w.AnalyzeTicket()
+
+	err := w.SQLRisks[1].CollectTicketSchemaValues()
+	if err == nil {
+		t.Fatalf("CollectTicketSchemaValues got nil error, want table dropped")
+	}
+	if len(w.SQLRisks[1].Errors) != 1 || w.SQLRisks[1].Errors[0].Type != policy.TabExist.Name {
+		t.Fatalf("CollectTicketSchemaValues got errors:%+v", w.SQLRisks[1].Errors)
+	}
+}
+
+func TestCollectCreatedTableValues(t *testing.T) {
+	pk := true
+	c := &SQLRisk{
+		DataBase: "test",
+		SQLText:  "update t_new set a = 1 where id = 1",
+		Tables:   []string{"test.t_new"},
+		Config:   &Config{},
+		cache:    NewMemoryCache(0),
+		ticketTables: map[string]SimulatedTable{
+			"test.t_new": {Created: true, Filled: true, PrimaryKey: &pk, ForeignKey: true, Indexes: []string{"id"}, Rows: 3},
+		},
+	}
+	c.SetItemValue(policy.Operate.Name, policy.Operate.ID, policy.Operate.V.DML, 0)
+	c.SetItemValue(policy.Action.Name, policy.Action.ID, policy.Action.V.Update, 0)
+	c.SetItemValue(policy.KeyWord.Name, policy.KeyWord.ID, policy.KeyWord.V.UpdateWhere, 0)
+
+	// 工单中新建的表不查询数据库
+	tests := []struct {
+		method string
+		want   any
+	}{
+		{"CollectTableExist", true},
+		{"CollectTableSize", 0},
+		{"CollectTableRows", 3},
+		{"CollectPrimaryKeyExist", true},
+		{"CollectForeignKeyExist", true},
+		{"CollectTriggerExist", false},
+		{"CollectIndexExistInWhere", true},
+		{"CollectAffectRows", 3},
+	}
+	for _, test := range tests {
+		t.Run(test.method, func(t *testing.T) {
+			err := c.CollectValueWithCache(test.method, test.method, c.Tables, test.method, true)
+			if got := c.GetItemValue(test.method); err != nil || got != test.want {
+				t.Fatalf("%s got %v, %v, want %v", test.method, got, err, test.want)
+			}
+		})
+	}
+}
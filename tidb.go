@@ -0,0 +1,313 @@
+package sqlrisk
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sunkaimr/sql-risk/policy"
+)
+
+// TiDB默认的 performance.txn-total-size-limit，单位MB
+const tidbDefaultTxnSizeLimit = 100
+
+// TiDB默认的副本数，用于由TiKV总可用空间估算可写入的数据量
+const tidbDefaultReplicas = 3
+
+// TiDBDDLJob ADMIN SHOW DDL JOBS 的结果
+type TiDBDDLJob struct {
+	ID          string `json:"id"`
+	DB          string `json:"db"`
+	Table       string `json:"table"`
+	Type        string `json:"type"`
+	SchemaState string `json:"schema_state"`
+	State       string `json:"state"`
+	RowCount    int    `json:"row_count"`
+	StartTime   string `json:"start_time"`
+}
+
+// Finished DDL任务是否已经结束
+func (j TiDBDDLJob) Finished() bool {
+	switch strings.ToLower(j.State) {
+	case "synced", "done", "cancelled", "rollback done":
+		return true
+	}
+	return false
+}
+
+type tidbBackend struct{}
+
+func (tidbBackend) TableSize(conn *Connector, database, table string) (int, error) {
+	return conn.TiDBTableSize(database, table)
+}
+
+func (tidbBackend) TableRows(conn *Connector, database, table string) (int, error) {
+	return conn.TiDBTableRows(database, table)
+}
+
+func (tidbBackend) Transactions(conn *Connector) ([]TrxResult, error) {
+	return conn.TiDBTransactions()
+}
+
+// TiDBTableSize 从 TABLE_STORAGE_STATS 查询表大小，单位MB
+func (db *Connector) TiDBTableSize(d, table string) (int, error) {
+	sqlQuery := fmt.Sprintf("SELECT coalesce(SUM(TABLE_SIZE), 0) FROM information_schema.TABLE_STORAGE_STATS "+
+		"WHERE TABLE_SCHEMA = '%s' AND TABLE_NAME = '%s'", d, table)
+
+	res, err := db.Query(sqlQuery)
+	if err != nil {
+		return 0, fmt.Errorf("exec sql query failed, %s", err)
+	}
+
+	size := 0.0
+	for res.Rows.Next() {
+		err = res.Rows.Scan(&size)
+		if err != nil {
+			return 0, fmt.Errorf("scan rows failed, %s", err)
+		}
+	}
+	err = res.Rows.Close()
+	if err != nil {
+		return 0, fmt.Errorf("close scan rows failed, %s", err)
+	}
+	return int(size + 0.5), nil
+}
+
+// TiDBTableRows 从 TIKV_REGION_STATUS 汇总表数据（不含索引）Region的key数量作为表行数
+func (db *Connector) TiDBTableRows(d, table string) (int, error) {
+	sqlQuery := fmt.Sprintf("SELECT coalesce(SUM(APPROXIMATE_KEYS), 0) FROM information_schema.TIKV_REGION_STATUS "+
+		"WHERE DB_NAME = '%s' AND TABLE_NAME = '%s' AND IS_INDEX = 0", d, table)
+
+	res, err := db.Query(sqlQuery)
+	if err != nil {
+		return 0, fmt.Errorf("exec sql query failed, %s", err)
+	}
+
+	rows := 0
+	for res.Rows.Next() {
+		err = res.Rows.Scan(&rows)
+		if err != nil {
+			return 0, fmt.Errorf("scan rows failed, %s", err)
+		}
+	}
+	err = res.Rows.Close()
+	if err != nil {
+		return 0, fmt.Errorf("close scan rows failed, %s", err)
+	}
+	return rows, nil
+}
+
+// TiDBTransactions 从 CLUSTER_TIDB_TRX 查询集群中所有TiDB节点正在运行的事务，RowsModified为事务缓存中的key数量
+func (db *Connector) TiDBTransactions() ([]TrxResult, error) {
+	sqlQuery := "SELECT " +
+		"ID, STATE, DATE_FORMAT(START_TIME, '%Y-%m-%d %H:%i:%s'), coalesce(SESSION_ID, 0), " +
+		"coalesce(CURRENT_SQL_DIGEST_TEXT, ''), MEM_BUFFER_KEYS " +
+		"FROM information_schema.CLUSTER_TIDB_TRX"
+
+	var trxs []TrxResult
+	res, err := db.Query(sqlQuery)
+	if err != nil {
+		return trxs, fmt.Errorf("exec sql query failed, %s", err)
+	}
+
+	for res.Rows.Next() {
+		t := TrxResult{}
+		err = res.Rows.Scan(&t.ID, &t.State, &t.Started, &t.MysqlThreadID, &t.Query, &t.RowsModified)
+		if err != nil {
+			return trxs, fmt.Errorf("scan rows failed, %s", err)
+		}
+		trxs = append(trxs, t)
+	}
+	err = res.Rows.Close()
+	if err != nil {
+		return trxs, fmt.Errorf("close scan rows failed, %s", err)
+	}
+	return trxs, nil
+}
+
+// TiDBDDLJobs 查询最近的DDL任务，不同版本的列不同，按列名读取
+func (db *Connector) TiDBDDLJobs() ([]TiDBDDLJob, error) {
+	res, err := db.Query("ADMIN SHOW DDL JOBS")
+	if err != nil {
+		return nil, fmt.Errorf("exec sql query failed, %s", err)
+	}
+	rows, err := scanRowMaps(res.Rows)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]TiDBDDLJob, 0, len(rows))
+	for _, row := range rows {
+		count, _ := strconv.Atoi(row["ROW_COUNT"])
+		jobs = append(jobs, TiDBDDLJob{
+			ID:          row["JOB_ID"],
+			DB:          row["DB_NAME"],
+			Table:       row["TABLE_NAME"],
+			Type:        row["JOB_TYPE"],
+			SchemaState: row["SCHEMA_STATE"],
+			State:       row["STATE"],
+			RowCount:    count,
+			StartTime:   row["START_TIME"],
+		})
+	}
+	return jobs, nil
+}
+
+// TiDBTxnSizeLimit 查询TiDB节点配置的 performance.txn-total-size-limit，多个节点取最小值，单位MB
+func (db *Connector) TiDBTxnSizeLimit() (int, error) {
+	res, err := db.Query("SHOW CONFIG WHERE type = 'tidb' AND name = 'performance.txn-total-size-limit'")
+	if err != nil {
+		return 0, fmt.Errorf("exec sql query failed, %s", err)
+	}
+	rows, err := scanRowMaps(res.Rows)
+	if err != nil {
+		return 0, err
+	}
+
+	limit := 0
+	for _, row := range rows {
+		bytes, err := strconv.ParseInt(row["Value"], 10, 64)
+		if err != nil || bytes <= 0 {
+			continue
+		}
+		mb := int(bytes / 1024 / 1024)
+		if limit == 0 || mb < limit {
+			limit = mb
+		}
+	}
+	if limit == 0 {
+		limit = tidbDefaultTxnSizeLimit
+	}
+	return limit, nil
+}
+
+// TiDBStoreAvailable 查询所有TiKV节点的可用空间之和，单位MB，不包括TiFlash节点
+func (db *Connector) TiDBStoreAvailable() (int, error) {
+	res, err := db.Query("SELECT AVAILABLE FROM information_schema.TIKV_STORE_STATUS " +
+		"WHERE STORE_STATE_NAME = 'Up' AND coalesce(LABEL, '') NOT LIKE '%tiflash%'")
+	if err != nil {
+		return 0, fmt.Errorf("exec sql query failed, %s", err)
+	}
+
+	total, available := 0.0, ""
+	for res.Rows.Next() {
+		err = res.Rows.Scan(&available)
+		if err != nil {
+			return 0, fmt.Errorf("scan rows failed, %s", err)
+		}
+		mb, err := parseTiDBSize(available)
+		if err != nil {
+			return 0, err
+		}
+		total += mb
+	}
+	err = res.Rows.Close()
+	if err != nil {
+		return 0, fmt.Errorf("close scan rows failed, %s", err)
+	}
+	return int(total), nil
+}
+
+// parseTiDBSize 解析TIKV_STORE_STATUS中的容量，如 401.5GiB、1TiB、0B，返回MB
+func parseTiDBSize(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	units := []struct {
+		suffix string
+		mb     float64
+	}{
+		{"PiB", 1024 * 1024 * 1024}, {"TiB", 1024 * 1024}, {"GiB", 1024}, {"MiB", 1}, {"KiB", 1.0 / 1024}, {"B", 1.0 / 1024 / 1024},
+	}
+	for _, u := range units {
+		if strings.HasSuffix(s, u.suffix) {
+			n, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(s, u.suffix)), 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid size: %q", s)
+			}
+			return n * u.mb, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid size: %q", s)
+}
+
+// CollectTiDBFreeDisk TiDB的剩余空间为所有TiKV节点的可用空间之和除以副本数
+func (c *SQLRisk) CollectTiDBFreeDisk() (int, error) {
+	conn, err := c.connect(c.DataBase)
+	if err != nil {
+		return 0, fmt.Errorf("new mysql connect failed, %s", err)
+	}
+	defer conn.Close()
+
+	available, err := conn.TiDBStoreAvailable()
+	if err != nil {
+		return 0, err
+	}
+	return available / tidbDefaultReplicas, nil
+}
+
+// CollectTiDBValues 采集TiDB特有的风险项：DML的事务大小是否超过txn-total-size-limit，DDL是否需要等待未完成的DDL任务
+func (c *SQLRisk) CollectTiDBValues() error {
+	operate, err := c.GetItemValueWithOperateType(policy.Operate.ID)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	switch operate {
+	case policy.Operate.V.DML:
+		// 事务大小按 影响行数 × 平均行长度 估算，与binlog大小的估算方式相同
+		size, err := c.GetItemValueWithInt(policy.EstimatedBinlogMB.ID)
+		if err != nil {
+			return fmt.Errorf("attempt to query EstimatedBinlogMB for collecting TiDBTxnSizeMB failed, %s", err)
+		}
+		limit, err := c.tidbTxnSizeLimit()
+		if err != nil {
+			c.SetItemError(policy.TiDBTxnOverLimit.Name, err)
+			return fmt.Errorf("collect txn size limit failed, %s", err)
+		}
+		cost := int(time.Now().Sub(start).Milliseconds())
+		c.SetItemValue(policy.TiDBTxnSizeMB.Name, policy.TiDBTxnSizeMB.ID, size, cost)
+		c.SetItemValue(policy.TiDBTxnOverLimit.Name, policy.TiDBTxnOverLimit.ID, size > limit, cost)
+	case policy.Operate.V.DDL:
+		conn, err := c.connect(c.DataBase)
+		if err != nil {
+			return fmt.Errorf("new mysql connect failed, %s", err)
+		}
+		jobs, err := conn.TiDBDDLJobs()
+		_ = conn.Close()
+		if err != nil {
+			c.SetItemError(policy.TiDBPendingDDLJobs.Name, err)
+			return fmt.Errorf("collect ddl jobs failed, %s", err)
+		}
+
+		pending := 0
+		for _, job := range jobs {
+			if !job.Finished() {
+				pending++
+			}
+		}
+		c.SetItemValue(policy.TiDBPendingDDLJobs.Name, policy.TiDBPendingDDLJobs.ID, pending, int(time.Now().Sub(start).Milliseconds()))
+	}
+	return nil
+}
+
+// tidbTxnSizeLimit 同一个数据源只查询一次
+func (c *SQLRisk) tidbTxnSizeLimit() (int, error) {
+	key := strings.Join([]string{"TiDBTxnSizeLimit", c.Addr, c.Port}, "|")
+	if v, ok := c.cache.Get(key); ok {
+		return v.(int), nil
+	}
+
+	conn, err := c.connect(c.DataBase)
+	if err != nil {
+		return 0, fmt.Errorf("new mysql connect failed, %s", err)
+	}
+	defer conn.Close()
+
+	limit, err := conn.TiDBTxnSizeLimit()
+	if err != nil {
+		return 0, err
+	}
+	c.cache.Set(key, limit, 0)
+	return limit, nil
+}
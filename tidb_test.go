@@ -0,0 +1,149 @@
+package sqlrisk
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sunkaimr/sql-risk/comm"
+)
+
+func TestTiDBTableSizeAndRows(t *testing.T) {
+	conn, mock, err := mockDBConn("test")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer conn.Close()
+
+	mock.ExpectExec("USE `test`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM information_schema.TABLE_STORAGE_STATS WHERE TABLE_SCHEMA = 'test' AND TABLE_NAME = 'student'").
+		WillReturnRows(mock.NewRows([]string{"size"}).AddRow(1023.6))
+	size, err := GetBackend(TiDBBackend).TableSize(conn, "test", "student")
+	if err != nil || size != 1024 {
+		t.Fatalf("TableSize got: %d, %v, want: 1024", size, err)
+	}
+
+	mock.ExpectExec("USE `test`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM information_schema.TIKV_REGION_STATUS WHERE DB_NAME = 'test' AND TABLE_NAME = 'student' AND IS_INDEX = 0").
+		WillReturnRows(mock.NewRows([]string{"rows"}).AddRow(250000))
+	rows, err := GetBackend(TiDBBackend).TableRows(conn, "test", "student")
+	if err != nil || rows != 250000 {
+		t.Fatalf("TableRows got: %d, %v, want: 250000", rows, err)
+	}
+}
+
+func TestTiDBTransactions(t *testing.T) {
+	conn, mock, err := mockDBConn("test")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer conn.Close()
+
+	mock.ExpectExec("USE `test`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM information_schema.CLUSTER_TIDB_TRX").
+		WillReturnRows(mock.NewRows([]string{"ID", "STATE", "START_TIME", "SESSION_ID", "SQL", "MEM_BUFFER_KEYS"}).
+			AddRow("426789", "Idle", "2023-05-01 10:00:00", 7, "update `student` set `a` = ?", 12))
+	trxs, err := conn.TiDBTransactions()
+	if err != nil {
+		t.Fatalf("TiDBTransactions failed, got error: %s", err)
+	}
+	want := []TrxResult{{ID: "426789", State: "Idle", Started: "2023-05-01 10:00:00", MysqlThreadID: 7,
+		Query: "update `student` set `a` = ?", RowsModified: 12}}
+	if !reflect.DeepEqual(trxs, want) {
+		t.Fatalf("TiDBTransactions got: %+v, want: %+v", trxs, want)
+	}
+}
+
+func TestTiDBDDLJobs(t *testing.T) {
+	conn, mock, err := mockDBConn("test")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer conn.Close()
+
+	columns := []string{"JOB_ID", "DB_NAME", "TABLE_NAME", "JOB_TYPE", "SCHEMA_STATE", "SCHEMA_ID", "TABLE_ID", "ROW_COUNT", "START_TIME", "STATE"}
+	mock.ExpectExec("USE `test`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("^ADMIN SHOW DDL JOBS").WillReturnRows(mock.NewRows(columns).
+		AddRow("101", "test", "student", "add index", "write reorganization", "2", "88", "5000", "2023-05-01 10:00:00", "running").
+		AddRow("100", "test", "teacher", "add column", "public", "2", "89", "0", "2023-05-01 09:00:00", "synced"))
+	jobs, err := conn.TiDBDDLJobs()
+	if err != nil {
+		t.Fatalf("TiDBDDLJobs failed, got error: %s", err)
+	}
+	if len(jobs) != 2 || jobs[0].Table != "student" || jobs[0].RowCount != 5000 || jobs[0].Finished() || !jobs[1].Finished() {
+		t.Fatalf("TiDBDDLJobs got: %+v", jobs)
+	}
+}
+
+func TestTiDBTxnSizeLimit(t *testing.T) {
+	conn, mock, err := mockDBConn("test")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer conn.Close()
+
+	columns := []string{"Type", "Instance", "Name", "Value"}
+	mock.ExpectExec("USE `test`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("^SHOW CONFIG WHERE type = 'tidb'").WillReturnRows(mock.NewRows(columns).
+		AddRow("tidb", "10.0.0.1:4000", "performance.txn-total-size-limit", "1073741824").
+		AddRow("tidb", "10.0.0.2:4000", "performance.txn-total-size-limit", "536870912"))
+	limit, err := conn.TiDBTxnSizeLimit()
+	if err != nil || limit != 512 {
+		t.Fatalf("TiDBTxnSizeLimit got: %d, %v, want: 512", limit, err)
+	}
+
+	// 未查询到配置时使用默认值
+	mock.ExpectExec("USE `test`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("^SHOW CONFIG WHERE type = 'tidb'").WillReturnRows(mock.NewRows(columns))
+	limit, err = conn.TiDBTxnSizeLimit()
+	if err != nil || limit != tidbDefaultTxnSizeLimit {
+		t.Fatalf("TiDBTxnSizeLimit got: %d, %v, want: %d", limit, err, tidbDefaultTxnSizeLimit)
+	}
+}
+
+func TestParseTiDBSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    float64
+		wantErr bool
+	}{
+		{"test001", "401.5GiB", 401.5 * 1024, false},
+		{"test002", "1TiB", 1024 * 1024, false},
+		{"test003", "512MiB", 512, false},
+		{"test004", "0B", 0, false},
+		{"test005", "1.5G", 0, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseTiDBSize(test.s)
+			if (err != nil) != test.wantErr || got != test.want {
+				t.Fatalf("parseTiDBSize(%s) got %v, %v, want %v", test.s, got, err, test.want)
+			}
+		})
+	}
+}
+
+func TestBackendType(t *testing.T) {
+	cache := NewMemoryCache(0)
+	cache.Set("ServerInfo|1.2.3.4|4000", &ServerInfo{Flavor: comm.FlavorTiDB}, 0)
+
+	tests := []struct {
+		name string
+		risk SQLRisk
+		want BackendType
+	}{
+		{"test001", SQLRisk{Backend: TiDBBackend}, TiDBBackend},
+		{"test002", SQLRisk{Config: &Config{RiskConfig: RiskConfig{Offline: true}}}, MySQLBackend},
+		{"test003", SQLRisk{Addr: "1.2.3.4", Port: "4000", cache: cache}, TiDBBackend},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.risk.backendType(); got != test.want {
+				t.Fatalf("backendType got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
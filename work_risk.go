@@ -20,33 +20,43 @@ const (
 )
 
 type WorkRisk struct {
-	ID            uint            `gorm:"primary_key;AUTO_INCREMENT;" json:"id"`
-	WorkID        string          `gorm:"type:varchar(64);index:work_id_idx;column:work_id;comment:工单ID" json:"work_id"`
-	Addr          string          `gorm:"type:varchar(64);not null;column:addr;comment:数据源地址" json:"addr"`                        // 此地址对应是集群的vip，自建集群无法根据vip查询到监控信息，所以需要配置读写库的地址
-	ReadWriteAddr string          `gorm:"type:varchar(64);not null;column:read_write_addr;comment:读写库的地址" json:"read_write_addr"` // 此地址对应是集群读写库的地址，主要用来查询监控信息
-	Port          string          `gorm:"type:varchar(64);not null;column:port;comment:数据源端口" json:"port"`
-	User          string          `gorm:"type:varchar(64);not null;column:user;comment:用户名" json:"user"`
-	Passwd        string          `gorm:"-" json:"-"`
-	DataBase      string          `gorm:"type:varchar(1024);not null;column:data_base;comment:数据库名称" json:"database"`
-	Table         string          `gorm:"type:varchar(1024);column:addr;comment:表名" json:"table"`
-	SQLText       string          `gorm:"type:longtext;column:sql_text;comment:SQL" json:"sql_text"`
-	Summary       Summary         `gorm:"type:json;column:summary;comment:工单概要信息" json:"summary"`
-	SQLRisks      []*SQLRisk      `gorm:"-;comment:各个SQL风险" json:"sql_risks"`
-	InfoPolicy    []policy.Policy `gorm:"type:json;column:info_policy;comment:最终生效的info级别的策略" json:"info_policy"`
-	LowPolicy     []policy.Policy `gorm:"type:json;column:low_policy;comment:最终生效的low级别的策略" json:"low_policy"`
-	HighPolicy    []policy.Policy `gorm:"type:json;column:high_policy;comment:最终生效的high级别的策略" json:"high_policy"`
-	FatalPolicy   []policy.Policy `gorm:"type:json;column:fatal_policy;comment:最终生效的fatal级别的策略" json:"fatal_policy"`
-	PreResult     PreResult       `gorm:"type:json;column:pre_result;comment:前置风险识别结果" json:"pre_result"`
-	PostResult    PostResult      `gorm:"type:json;column:post_result;comment:后置风险识别结果" json:"post_result"`
-	Errors        []ErrorResult   `gorm:"type:json;column:errors;comment:错误信息" json:"errors"`
-	Config        *Config         `gorm:"type:json;column:config;comment:相关配置信息" json:"config"`
-	Cost          int             `gorm:"type:int;column:cost;comment:识别工单风险花费时间" json:"cost"`
-	cache         map[string]any
+	ID                uint               `gorm:"primary_key;AUTO_INCREMENT;" json:"id"`
+	WorkID            string             `gorm:"type:varchar(64);index:work_id_idx;column:work_id;comment:工单ID" json:"work_id"`
+	DataSourceID      string             `gorm:"type:varchar(64);column:data_source_id;comment:数据源ID" json:"data_source_id"`
+	Backend           BackendType        `gorm:"type:varchar(16);column:backend;comment:数据库类型" json:"backend"`                           // 为空时根据数据库版本识别
+	MetricsClass      string             `gorm:"type:varchar(32);column:metrics_class;comment:监控类别" json:"metrics_class"`                // 为空时依次尝试所有监控模板
+	Addr              string             `gorm:"type:varchar(64);not null;column:addr;comment:数据源地址" json:"addr"`                        // 此地址对应是集群的vip，自建集群无法根据vip查询到监控信息，所以需要配置读写库的地址
+	ReadWriteAddr     string             `gorm:"type:varchar(64);not null;column:read_write_addr;comment:读写库的地址" json:"read_write_addr"` // 此地址对应是集群读写库的地址，主要用来查询监控信息
+	Port              string             `gorm:"type:varchar(64);not null;column:port;comment:数据源端口" json:"port"`
+	User              string             `gorm:"type:varchar(64);not null;column:user;comment:用户名" json:"user"`
+	Passwd            string             `gorm:"-" json:"-"`
+	Credential        CredentialProvider `gorm:"-" json:"-"` // 未配置时使用Passwd
+	Replicas          []Endpoint         `gorm:"-" json:"-"` // 只读库，表元数据、COUNT(*)等查询优先发往只读库
+	DataBase          string             `gorm:"type:varchar(1024);not null;column:data_base;comment:数据库名称" json:"database"`
+	Table             string             `gorm:"type:varchar(1024);column:table_name;comment:表名" json:"table"`
+	SQLText           string             `gorm:"type:longtext;column:sql_text;comment:SQL" json:"sql_text"`
+	Summary           Summary            `gorm:"type:json;serializer:json;column:summary;comment:工单概要信息" json:"summary"`
+	SQLRisks          []*SQLRisk         `gorm:"foreignKey:WorkRiskID" json:"sql_risks"`
+	InfoPolicy        []policy.Policy    `gorm:"type:json;serializer:json;column:info_policy;comment:最终生效的info级别的策略" json:"info_policy"`
+	LowPolicy         []policy.Policy    `gorm:"type:json;serializer:json;column:low_policy;comment:最终生效的low级别的策略" json:"low_policy"`
+	HighPolicy        []policy.Policy    `gorm:"type:json;serializer:json;column:high_policy;comment:最终生效的high级别的策略" json:"high_policy"`
+	FatalPolicy       []policy.Policy    `gorm:"type:json;serializer:json;column:fatal_policy;comment:最终生效的fatal级别的策略" json:"fatal_policy"`
+	PreResult         PreResult          `gorm:"type:json;serializer:json;column:pre_result;comment:前置风险识别结果" json:"pre_result"`
+	PostResult        PostResult         `gorm:"type:json;serializer:json;column:post_result;comment:后置风险识别结果" json:"post_result"`
+	RecommendedWindow *ExecutionWindow   `gorm:"type:json;serializer:json;column:recommended_window;comment:推荐的执行窗口" json:"recommended_window,omitempty"` // 只有high、fatal级别的工单推荐
+	Errors            []ErrorResult      `gorm:"type:json;serializer:json;column:errors;comment:错误信息" json:"errors"`
+	Config            *Config            `gorm:"type:json;serializer:json;column:config;comment:相关配置信息" json:"config"`
+	Cost              int                `gorm:"type:int;column:cost;comment:识别工单风险花费时间" json:"cost"`
+	CreatedAt         time.Time          `gorm:"index:created_at_idx;column:created_at;comment:创建时间" json:"created_at"`
+	cache             *MemoryCache
+	samples           []*sampleGroup
 }
 
 type Config struct {
-	Runtime    Client     `json:"runtime"`
-	RiskConfig RiskConfig `json:"risk_config"`
+	Runtime    MetricsConfig `json:"runtime"`
+	RiskConfig RiskConfig    `json:"risk_config"`
+	// 跨工单的评估项缓存，多个工单使用同一个Config时共享，为nil时只在工单内缓存
+	VerdictCache *VerdictCache `json:"-"`
 }
 
 type Summary struct {
@@ -60,31 +70,46 @@ type Summary struct {
 	InfoCount   int `json:"info_count"`
 }
 
-func NewWorkRisk(workID, addr, rwAddr, port, user, passwd, database, sql string, config *Config) *WorkRisk {
+// NewWorkRisk 根据注册的数据源创建工单，dataSourceID为空时不关联数据源，只能离线识别
+func NewWorkRisk(workID, dataSourceID, database, sql string, config *Config) (*WorkRisk, error) {
 	if config == nil {
 		config = newDefaultConfig()
 	}
-	return &WorkRisk{
-		WorkID:        workID,
-		Addr:          addr,
-		ReadWriteAddr: rwAddr,
-		Port:          port,
-		User:          user,
-		Passwd:        passwd,
-		DataBase:      database,
-		SQLText:       sql,
-		Config:        config,
-		cache:         make(map[string]any, 1),
+	w := &WorkRisk{
+		WorkID:       workID,
+		DataSourceID: dataSourceID,
+		DataBase:     database,
+		SQLText:      sql,
+		Config:       config,
+		cache:        NewMemoryCache(0),
 	}
+	if dataSourceID == "" {
+		return w, nil
+	}
+
+	ds, err := GetDataSource(dataSourceID)
+	if err != nil {
+		return nil, err
+	}
+	w.Addr = ds.Primary.Addr
+	w.Port = ds.Primary.Port
+	w.User = ds.Primary.User
+	w.Passwd = ds.Primary.Passwd
+	w.Credential = ds.Primary.Credential
+	w.ReadWriteAddr = ds.ReadWriteAddr
+	w.Replicas = ds.Replicas
+	w.Backend = ds.Backend
+	w.MetricsClass = ds.MetricsClass
+	return w, nil
 }
 
 func newDefaultConfig() *Config {
 	return &Config{
-		Runtime: Client{Url: string([]byte{104, 116, 116, 112, 58, 47, 47, 116, 104, 97, 110, 111, 115, 45, 114, 101, 97, 108, 116, 105, 109, 101, 46, 99, 111, 119, 101, 108, 108, 116, 101, 99, 104, 46, 99, 111, 109})},
 		RiskConfig: RiskConfig{
-			TxDuration:       10,
-			TabRowsThreshold: 100000,
-			TabSizeThreshold: 2048,
+			TxDuration:        10,
+			TabRowsThreshold:  100000,
+			TabSizeThreshold:  2048,
+			DiskForecastHours: 24,
 		},
 	}
 }
@@ -130,22 +155,29 @@ func (c *WorkRisk) IdentifyWorkRiskPreRisk() error {
 		}
 	}
 
+	// 按语句顺序模拟表结构的变化，识别跨语句的风险
+	c.AnalyzeTicket()
+
 	// 统计信息
 	c.CalculateSummary()
 
-	// 抽样检测
-	// insert：按SQL指纹进行采样检测
-	c.SampDetectForInsert()
+	// 抽样检测：按关键字和SQL指纹只检测部分语句，其余语句继承检测结果
+	c.SampleStatements()
 
 	matchedPolicies := make([]policy.Policy, 0, len(c.SQLRisks))
 	// 遍历SQL进行前置风险识别
 	for i := range c.SQLRisks {
+		if c.SQLRisks[i].RepresentedBy > 0 {
+			continue
+		}
 		err = c.SQLRisks[i].IdentifyPreRisk()
 		if err != nil {
 			err = fmt.Errorf("identify SQL risk failed, %s", err)
 			c.SQLRisks[i].SetPreResult(comm.Fatal, false)
 			c.SQLRisks[i].SetItemError(IdentifyRisk, err)
 			c.SetPreResult(comm.Fatal, false)
+			// 已检测的语句仍需要将结果传递给其代表的语句
+			c.InheritSampledVerdicts()
 			return err
 		}
 
@@ -154,6 +186,7 @@ func (c *WorkRisk) IdentifyWorkRiskPreRisk() error {
 		matchedPolicies = append(matchedPolicies, c.SQLRisks[i].HighPolicy...)
 		matchedPolicies = append(matchedPolicies, c.SQLRisks[i].FatalPolicy...)
 	}
+	c.InheritSampledVerdicts()
 
 	// 一个工单只能包含一种操作类型
 	//operate := make(map[string]struct{})
@@ -184,11 +217,15 @@ func (c *WorkRisk) IdentifyWorkRiskPreRisk() error {
 		c.SetItemError(Authority, err)
 		return err
 	}
+
+	c.RecommendExecutionWindow()
 	return nil
 }
 
 // SplitStatement 将多个SQL语句进行拆分
 func (c *WorkRisk) SplitStatement() error {
+	// 位置信息基于用户提交的原始文本，中文空格替换会改变字节偏移
+	text := c.SQLText
 	idx := strings.Index(c.SQLText, " ")
 	if idx != -1 {
 		c.SQLText = regexp.MustCompile(` `).ReplaceAllString(c.SQLText, " ")
@@ -200,28 +237,45 @@ func (c *WorkRisk) SplitStatement() error {
 		//	comm.Info))
 	}
 
-	sqlList := comm.SplitStatement(c.SQLText)
-	for _, sql := range sqlList {
+	// USE语句会切换后续语句的默认库
+	database := c.DataBase
+	split := comm.SplitStatementWithPosition
+	if c.Backend == PostgresBackend {
+		split = comm.SplitPostgresStatementWithPosition
+	}
+	stmts := split(text)
+	for _, stmt := range stmts {
 		sqlRisk := &SQLRisk{
 			WorkID:        c.WorkID,
+			DataSourceID:  c.DataSourceID,
+			Backend:       c.Backend,
+			MetricsClass:  c.MetricsClass,
 			Addr:          c.Addr,
 			ReadWriteAddr: c.ReadWriteAddr,
 			Port:          c.Port,
 			User:          c.User,
 			Passwd:        c.Passwd,
-			DataBase:      c.DataBase,
-			SQLText:       sql,
+			Credential:    c.Credential,
+			Replicas:      c.Replicas,
+			DataBase:      database,
+			SQLText:       strings.ReplaceAll(stmt.SQL, " ", " "),
+			Position:      stmt.Start,
+			EndPosition:   stmt.End,
 			Errors:        nil,
 			Config:        c.Config,
 			cache:         c.cache,
 		}
 		c.SQLRisks = append(c.SQLRisks, sqlRisk)
+
+		if db, ok := comm.ParseUseDataBase(sqlRisk.SQLText); ok {
+			database = db
+		}
 	}
 
 	return nil
 }
 
-// ExceedingPermissions 校验是否对库进行越权操作
+// ExceedingPermissions 校验是否对库进行越权操作，USE切换库后SQL的默认库可能改变，以工单选择的库为准
 func (c *WorkRisk) ExceedingPermissions() error {
 	for _, sqlRisk := range c.SQLRisks {
 		var database []string
@@ -231,8 +285,8 @@ func (c *WorkRisk) ExceedingPermissions() error {
 			database = append(database, d)
 		}
 
-		if len(database) != 0 && !comm.EleExist(sqlRisk.DataBase, database) {
-			return fmt.Errorf("越权操作, 你操作的库%v必须包含选择的库[%s]", database, sqlRisk.DataBase)
+		if len(database) != 0 && !comm.EleExist(c.DataBase, database) {
+			return fmt.Errorf("越权操作, 你操作的库%v必须包含选择的库[%s]", database, c.DataBase)
 		}
 	}
 
@@ -283,29 +337,6 @@ func (c *WorkRisk) String() string {
 	return buf.String()
 }
 
-// SampDetectForInsert 采样检测insert语句
-func (c *WorkRisk) SampDetectForInsert() *WorkRisk {
-	m := make(map[string]struct{}, len(c.SQLRisks))
-	deleteItem := make([]int, 0, len(c.SQLRisks))
-	for i, r := range c.SQLRisks {
-		if !r.JudgeItemValue(policy.KeyWord.ID, policy.KeyWord.V.Insert) {
-			continue
-		}
-
-		if _, ok := m[r.FingerID]; !ok {
-			m[r.FingerID] = struct{}{}
-		} else {
-			deleteItem = append(deleteItem, i)
-		}
-	}
-
-	for i := len(deleteItem) - 1; i >= 0; i-- {
-		index := deleteItem[i]
-		c.SQLRisks = append(c.SQLRisks[:index], c.SQLRisks[index+1:]...)
-	}
-	return c
-}
-
 func (c *WorkRisk) CalculateSummary() *WorkRisk {
 	if c.Summary.DataBaseNum == 0 || c.Summary.TableNum == 0 || c.Summary.SQLCount == 0 {
 		database := make(map[string]struct{}, len(c.SQLRisks))
@@ -58,6 +58,55 @@ func TestStatementSQL(t *testing.T) {
 	}
 }
 
+func TestStatementPosition(t *testing.T) {
+	wr := WorkRisk{
+		DataBase: "test",
+		SQLText:  "delete from student where\u00a0id=10;\n-- 重复执行\ndelete from student where id=10;",
+	}
+	err := wr.SplitStatement()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	want := []struct {
+		sql   string
+		start comm.Position
+		end   comm.Position
+	}{
+		{"delete from student where id=10", comm.Position{Offset: 0, Line: 1, Column: 1}, comm.Position{Offset: 32, Line: 1, Column: 32}},
+		{"delete from student where id=10", comm.Position{Offset: 50, Line: 3, Column: 1}, comm.Position{Offset: 81, Line: 3, Column: 32}},
+	}
+	if len(wr.SQLRisks) != len(want) {
+		t.Fatalf("SplitStatement failed, got %d statements, want %d", len(wr.SQLRisks), len(want))
+	}
+	for i, w := range want {
+		r := wr.SQLRisks[i]
+		if r.SQLText != w.sql || r.Position != w.start || r.EndPosition != w.end {
+			t.Fatalf("SplitStatement failed, got:%q %+v %+v, want:%q %+v %+v", r.SQLText, r.Position, r.EndPosition, w.sql, w.start, w.end)
+		}
+	}
+}
+
+func TestStatementUseDataBase(t *testing.T) {
+	wr := WorkRisk{
+		DataBase: "test",
+		SQLText:  "delete from student where id=1;USE `d1`;delete from student where id=2;",
+	}
+	err := wr.SplitStatement()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	want := []string{"test", "test", "d1"}
+	got := make([]string, 0, len(wr.SQLRisks))
+	for _, r := range wr.SQLRisks {
+		got = append(got, r.DataBase)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("SplitStatement failed, got database:%v, want:%v", got, want)
+	}
+}
+
 func TestIdentifyWorkRiskPreRisk(t *testing.T) {
 	store := policy.GetStore(policy.FileStoreType, ".policy.yaml")
 	err := store.Init()
@@ -79,7 +128,14 @@ CREATE VIEW view1 AS SELECT * FROM isql_test.alter_table1;
 	passwd := "yearning_dml"
 	j := json.Config{EscapeHTML: false, IndentionStep: 2}.Froze()
 
-	w := NewWorkRisk("111", addr, "", port, user, passwd, database, sql, nil)
+	err = RegisterDataSource(&DataSource{ID: "111", Primary: Endpoint{Addr: addr, Port: port, User: user, Passwd: passwd}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewWorkRisk("111", "111", database, sql, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
 	err = w.IdentifyWorkRiskPreRisk()
 	b, _ := j.Marshal(w)
 	fmt.Println(string(b))